@@ -4,8 +4,10 @@ package main
 import (
 	"context"
 	"fmt"
+	"github.com/jessevdk/go-flags"
 	"github.com/ruslanDantsov/osmetrics-server/internal/agent/app"
 	"github.com/ruslanDantsov/osmetrics-server/internal/agent/config"
+	"github.com/ruslanDantsov/osmetrics-server/internal/agent/identity"
 	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/logger"
 	"io"
 	"os"
@@ -37,7 +39,10 @@ func printBuildInfo(w io.Writer) {
 func main() {
 	printBuildInfo(os.Stdout)
 
-	agentConfig := config.NewAgentConfig(os.Args[1:])
+	agentConfig, err := config.NewAgentConfig(os.Args[1:])
+	if err != nil {
+		exitOnConfigError(err)
+	}
 
 	if err := logger.Initialized(agentConfig.LogLevel); err != nil {
 		logger.Log.Fatal(fmt.Sprintf("Logger initialized failed: %v", err.Error()))
@@ -47,7 +52,11 @@ func main() {
 
 	logger.Log.Info("Starting agent...")
 
-	agentApp := app.NewAgentApp(agentConfig, logger.Log)
+	agentApp := app.NewAgentApp(agentConfig, logger.Log, identity.Build{
+		Version: buildVersion,
+		Commit:  buildCommit,
+		Date:    buildDate,
+	})
 
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
@@ -56,3 +65,14 @@ func main() {
 		logger.Log.Fatal(fmt.Sprintf("Agent start failed: %v", err.Error()))
 	}
 }
+
+// exitOnConfigError завершает процесс при ошибке конфигурации.
+// Запрос справки (-h) не считается ошибкой: справка выводится в stdout, код возврата 0.
+func exitOnConfigError(err error) {
+	if flags.WroteHelp(err) {
+		fmt.Println(err)
+		os.Exit(0)
+	}
+	fmt.Fprintf(os.Stderr, "Config initialized failed: %v\n", err)
+	os.Exit(1)
+}
@@ -2,13 +2,17 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"github.com/jessevdk/go-flags"
 	"github.com/ruslanDantsov/osmetrics-server/internal/logger"
 	"github.com/ruslanDantsov/osmetrics-server/internal/server/app"
 	"github.com/ruslanDantsov/osmetrics-server/internal/server/config"
 	"go.uber.org/zap"
 	"io"
 	"os"
+	"os/signal"
+	"syscall"
 )
 
 var (
@@ -36,9 +40,8 @@ func main() {
 	printBuildInfo(os.Stdout)
 
 	serverConfig, err := config.NewServerConfig(os.Args[1:])
-
 	if err != nil {
-		logger.Log.Fatal("Config initialized failed: %v", zap.Error(err))
+		exitOnConfigError(err)
 	}
 
 	if err := logger.Initialized(serverConfig.LogLevel); err != nil {
@@ -59,10 +62,26 @@ func main() {
 		logger.Log.Fatal("Unable to config Server", zap.Error(err))
 	}
 
-	if err := serverApp.Run(); err != nil {
-		logger.Log.Fatal("Server start failed: %v", zap.Error(err))
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	runErr := serverApp.Run(ctx)
+	serverApp.Close()
+
+	if runErr != nil {
+		logger.Log.Fatal("Server start failed: %v", zap.Error(runErr))
 	}
 
-	defer serverApp.Close()
+	logger.Log.Info("Server stopped")
+}
 
+// exitOnConfigError завершает процесс при ошибке конфигурации.
+// Запрос справки (-h) не считается ошибкой: справка выводится в stdout, код возврата 0.
+func exitOnConfigError(err error) {
+	if flags.WroteHelp(err) {
+		fmt.Println(err)
+		os.Exit(0)
+	}
+	fmt.Fprintf(os.Stderr, "Config initialized failed: %v\n", err)
+	os.Exit(1)
 }
@@ -3,16 +3,19 @@ package app
 import (
 	"context"
 	"crypto/rsa"
-	"fmt"
 	"github.com/go-resty/resty/v2"
+	"github.com/ruslanDantsov/osmetrics-server/internal/agent/collector"
 	"github.com/ruslanDantsov/osmetrics-server/internal/agent/config"
 	"github.com/ruslanDantsov/osmetrics-server/internal/agent/constants"
+	"github.com/ruslanDantsov/osmetrics-server/internal/agent/identity"
 	middleware2 "github.com/ruslanDantsov/osmetrics-server/internal/agent/middleware"
+	"github.com/ruslanDantsov/osmetrics-server/internal/agent/retry"
 	"github.com/ruslanDantsov/osmetrics-server/internal/agent/service"
+	"github.com/ruslanDantsov/osmetrics-server/internal/agent/spool"
+	"github.com/ruslanDantsov/osmetrics-server/internal/agent/transport"
 	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/crypto"
 	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/model"
 	"go.uber.org/zap"
-	"net/http"
 	"sync"
 	"time"
 )
@@ -22,14 +25,71 @@ import (
 type AgentApp struct {
 	config        *config.AgentConfig
 	logger        *zap.Logger
-	client        resty.Client
+	transport     AgentTransport
 	metricService *service.MetricService
+	spool         *spool.Spool
+	collectors    []collector.Scheduled
+	build         identity.Build
+}
+
+// AgentTransport определяет транспорт доставки метрик, которым владеет приложение.
+type AgentTransport interface {
+	service.Transport
+	Close() error
 }
 
 // NewAgentApp создает новый экземпляр AgentApp с заданной конфигурацией и логгером.
-func NewAgentApp(cfg *config.AgentConfig, log *zap.Logger) *AgentApp {
+// Сведения о сборке build передаются серверу при регистрации агента.
+func NewAgentApp(cfg *config.AgentConfig, log *zap.Logger, build identity.Build) *AgentApp {
+
+	agentTransport := newTransport(cfg, log)
+
+	var metricSpool *spool.Spool
+	var spooler service.Spooler
+	if len(cfg.SpoolDir) > 0 {
+		s, err := spool.New(cfg.SpoolDir, cfg.SpoolMaxSizeBytes, cfg.SpoolMaxAge, log)
+		if err != nil {
+			log.Fatal("failed to open metrics spool", zap.Error(err))
+		}
+		metricSpool = s
+		spooler = s
+	}
+
+	collectors, err := collector.Default().Build(cfg)
+	if err != nil {
+		log.Fatal("failed to create metric collectors", zap.Error(err))
+	}
+
+	metricService := service.NewMetricService(log, agentTransport, cfg, spooler)
+
+	return &AgentApp{
+		config:        cfg,
+		logger:        log,
+		transport:     agentTransport,
+		metricService: metricService,
+		spool:         metricSpool,
+		collectors:    collectors,
+		build:         build,
+	}
+}
+
+// newTransport создаёт транспорт доставки метрик, выбранный в конфигурации.
+func newTransport(cfg *config.AgentConfig, log *zap.Logger) AgentTransport {
+	if cfg.Transport == constants.TransportGRPC {
+		opts := append(transport.WithRealIP(outboundIP(cfg.GRPCAddress, log)), transport.WithAgentID(cfg.AgentID)...)
+		opts = append(opts, transport.WithHashKey(cfg.HashKey)...)
+		t, err := transport.NewGRPCTransport(cfg.GRPCAddress, opts...)
+		if err != nil {
+			log.Fatal("failed to create gRPC transport", zap.Error(err))
+		}
+		return t
+	}
 
 	client := resty.New()
+	client.SetHeader(constants.AgentIDHeaderName, cfg.AgentID)
+	if ip := outboundIP(cfg.Address, log); ip != "" {
+		client.SetHeader(constants.RealIPHeaderName, ip)
+	}
 	client.OnBeforeRequest(middleware2.GzipRestyMiddleware())
 	if len(cfg.HashKey) > 0 {
 		client.OnBeforeRequest(middleware2.HashBodyRestyMiddleware(cfg.HashKey))
@@ -44,14 +104,18 @@ func NewAgentApp(cfg *config.AgentConfig, log *zap.Logger) *AgentApp {
 		pubKey = k
 	}
 
-	metricService := service.NewMetricService(log, client, cfg, pubKey)
+	return transport.NewHTTPTransport(client, cfg.Address, pubKey)
+}
 
-	return &AgentApp{
-		config:        cfg,
-		logger:        log,
-		client:        *client,
-		metricService: metricService,
+// outboundIP возвращает адрес интерфейса, через который агент обращается к серверу,
+// или пустую строку, если определить его не удалось.
+func outboundIP(address string, log *zap.Logger) string {
+	ip, err := transport.OutboundIP(address)
+	if err != nil {
+		log.Warn("Failed to detect outbound IP address", zap.String("address", address), zap.Error(err))
+		return ""
 	}
+	return ip.String()
 }
 
 // Run запускает агент: собирает метрики, отправляет их и управляет жизненным циклом воркеров.
@@ -59,85 +123,133 @@ func NewAgentApp(cfg *config.AgentConfig, log *zap.Logger) *AgentApp {
 // Ожидает готовности сервера перед запуском сбора метрик.
 // Использует контекст для управления завершением работы.
 func (app *AgentApp) Run(ctx context.Context) error {
-	if err := app.waitForServer(); err != nil {
+	if err := app.waitForServer(ctx); err != nil {
+		if ctx.Err() != nil {
+			app.logger.Info("Agent stopped before server became ready")
+			return nil
+		}
 		return err
 	}
 
-	metricChan := make(chan model.Metrics, constants.MetricChannelSize)
+	app.register(ctx)
 
 	var wg sync.WaitGroup
-	wg.Add(1)
 
-	go func() {
-		defer wg.Done()
-		ticker := time.NewTicker(app.config.PollInterval)
-		defer ticker.Stop()
+	if app.config.ReportMode == constants.ReportModeSingle {
+		app.runSingleMode(ctx, &wg)
+	} else {
+		app.runBatchMode(ctx, &wg)
+	}
 
-		for {
-			select {
-			case <-ticker.C:
-				app.metricService.CollectMetrics(metricChan)
-			case <-ctx.Done():
-				app.logger.Info("Collector received shutdown signal")
-				return
-			}
+	wg.Wait()
+
+	if app.spool != nil {
+		app.metricService.FlushPending()
+		app.spool.Close()
+	}
+
+	if err := app.transport.Close(); err != nil {
+		app.logger.Error("Failed to close transport", zap.Error(err))
+	}
+
+	return nil
+}
+
+// runBatchMode запускает сбор метрик во внутреннее состояние сервиса
+// и их пакетную отправку раз в ReportInterval.
+func (app *AgentApp) runBatchMode(ctx context.Context, wg *sync.WaitGroup) {
+	for _, scheduled := range app.collectors {
+		c := scheduled.Collector
+		app.runPeriodically(ctx, wg, "Collector "+c.Name(), scheduled.Interval, func() {
+			app.metricService.Poll(ctx, c)
+		})
+	}
+	app.runPeriodically(ctx, wg, "Reporter", app.config.ReportInterval, func() {
+		if err := app.metricService.SendAllMetrics(ctx); err != nil {
+			app.logger.Error("Failed to send batch of metrics", zap.Error(err))
 		}
-	}()
+	})
+}
+
+// runSingleMode запускает сбор метрик в канал и воркеры,
+// отправляющие каждую метрику отдельным запросом.
+func (app *AgentApp) runSingleMode(ctx context.Context, wg *sync.WaitGroup) {
+	metricChan := make(chan model.Metrics, constants.MetricChannelSize)
 
+	for _, scheduled := range app.collectors {
+		c := scheduled.Collector
+		app.runPeriodically(ctx, wg, "Collector "+c.Name(), scheduled.Interval, func() {
+			app.metricService.Collect(ctx, c, metricChan)
+		})
+	}
+
+	if app.spool != nil {
+		app.runPeriodically(ctx, wg, "Spool replayer", app.config.ReportInterval, func() {
+			if err := app.metricService.ReplaySpool(ctx); err != nil {
+				app.logger.Warn("Failed to replay spooled metrics", zap.Error(err))
+			}
+		})
+	}
+
+	for i := 0; i < app.config.RateLimit; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			app.metricService.Worker(ctx, metricChan)
+		}()
+	}
+}
+
+// runPeriodically запускает горутину, вызывающую task с заданным интервалом до отмены контекста.
+func (app *AgentApp) runPeriodically(ctx context.Context, wg *sync.WaitGroup, name string, interval time.Duration, task func()) {
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		ticker := time.NewTicker(app.config.PollInterval)
+		ticker := time.NewTicker(interval)
 		defer ticker.Stop()
 
 		for {
 			select {
 			case <-ticker.C:
-				app.metricService.CollectAdditionalMetrics(metricChan)
+				task()
 			case <-ctx.Done():
-				app.logger.Info("Additional collector received shutdown signal")
+				app.logger.Info(name + " received shutdown signal")
 				return
 			}
 		}
 	}()
+}
 
-	for i := 0; i < app.config.RateLimit; i++ {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			app.metricService.Worker(ctx, metricChan)
-		}()
+// register регистрирует агент на сервере, повторяя запрос по политике повторных попыток.
+// Ошибка регистрации не останавливает агент: сервер учтёт его по первым принятым метрикам.
+func (app *AgentApp) register(ctx context.Context) {
+	info := identity.Info(ctx, app.config.AgentID, app.build)
+
+	err := service.NewRetryPolicy(app.config).Do(ctx, func(ctx context.Context) error {
+		return app.transport.Register(ctx, info)
+	})
+	if err != nil {
+		app.logger.Warn("Failed to register agent", zap.String("agent_id", info.ID), zap.Error(err))
+		return
 	}
 
-	wg.Wait()
-	close(metricChan)
-
-	return nil
+	app.logger.Info("Agent registered", zap.String("agent_id", info.ID))
 }
 
-func (app *AgentApp) waitForServer() error {
-	url := fmt.Sprintf(constants.ServerHealthCheckURL, app.config.Address)
-
-	sleepDelay := 1 * time.Second
-	attemps := 0
-	for {
-		resp, err := app.client.R().Get(url)
-
-		if err == nil && resp.StatusCode() == http.StatusOK {
-			app.logger.Info("Server is ready!")
-			return nil
-		}
-
-		app.logger.Info("Server not ready, waiting...")
-		time.Sleep(sleepDelay)
-
-		sleepDelay += constants.IncreaseDelayForWaitingServer
-		attemps++
-
-		if sleepDelay > constants.MaxDelayForWaitingServer {
-			break
+// waitForServer ожидает, пока сервер начнёт отвечать на проверку состояния, повторяя её
+// по политике повторных попыток без ограничения числа попыток.
+// Возвращает ошибку только при отмене контекста.
+func (app *AgentApp) waitForServer(ctx context.Context) error {
+	policy := service.NewRetryPolicy(app.config)
+	policy.MaxAttempts = 0
+
+	return policy.Do(ctx, func(ctx context.Context) error {
+		if err := app.transport.HealthCheck(ctx); err != nil {
+			app.logger.Info("Server not ready, waiting...", zap.Error(err))
+			return retry.Retryable(err, 0)
 		}
 
-	}
-	return fmt.Errorf("server didn't become ready after %v attempts", attemps)
+		app.logger.Info("Server is ready!")
+		return nil
+	})
 }
@@ -0,0 +1,148 @@
+// Package collector определяет подключаемые источники метрик агента и реестр, из которого они включаются.
+//
+// Сборщик регистрирует свою фабрику под уникальным именем в init, поэтому новый сборщик добавляется
+// одним файлом в этом пакете и включается в конфигурации агента по этому имени.
+package collector
+
+import (
+	"context"
+	"fmt"
+	"github.com/ruslanDantsov/osmetrics-server/internal/agent/config"
+	"github.com/ruslanDantsov/osmetrics-server/internal/agent/constants"
+	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/model"
+	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/model/enum"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Collector — источник метрик агента.
+type Collector interface {
+	// Name возвращает имя коллектора, под которым он включается в конфигурации.
+	Name() string
+
+	// Collect собирает текущие значения метрик. Если часть показателей прочитать не удалось,
+	// возвращает собранные метрики вместе с ошибкой.
+	Collect(ctx context.Context) ([]model.Metrics, error)
+}
+
+// Factory создаёт коллектор по конфигурации агента.
+type Factory func(cfg *config.AgentConfig) (Collector, error)
+
+// Scheduled — включённый коллектор и интервал его опроса.
+type Scheduled struct {
+	Collector Collector
+	Interval  time.Duration
+}
+
+// Registry хранит фабрики коллекторов по именам.
+type Registry struct {
+	mu        sync.RWMutex
+	factories map[string]Factory
+}
+
+// NewRegistry создаёт пустой реестр коллекторов.
+func NewRegistry() *Registry {
+	return &Registry{factories: make(map[string]Factory)}
+}
+
+// Register добавляет фабрику коллектора name. Повторная регистрация имени считается ошибкой.
+func (r *Registry) Register(name string, factory Factory) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.factories[name]; exists {
+		return fmt.Errorf("collector %s is already registered", name)
+	}
+	r.factories[name] = factory
+	return nil
+}
+
+// Names возвращает отсортированные имена зарегистрированных коллекторов.
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.namesLocked()
+}
+
+// Build создаёт коллекторы, включённые в cfg.Collectors. Интервал опроса берётся
+// из cfg.CollectorIntervals, а если он не задан — равен cfg.PollInterval.
+func (r *Registry) Build(cfg *config.AgentConfig) ([]Scheduled, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	scheduled := make([]Scheduled, 0, len(cfg.Collectors))
+	for _, name := range cfg.Collectors {
+		factory, ok := r.factories[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown collector %s, available: %v", name, r.namesLocked())
+		}
+
+		c, err := factory(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create collector %s: %w", name, err)
+		}
+
+		interval, ok := cfg.CollectorIntervals[name]
+		if !ok {
+			interval = cfg.PollInterval
+		}
+		scheduled = append(scheduled, Scheduled{Collector: c, Interval: interval})
+	}
+
+	for name := range cfg.CollectorIntervals {
+		if _, ok := r.factories[name]; !ok {
+			return nil, fmt.Errorf("interval is set for unknown collector %s", name)
+		}
+	}
+
+	return scheduled, nil
+}
+
+func (r *Registry) namesLocked() []string {
+	names := make([]string, 0, len(r.factories))
+	for name := range r.factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+var defaultRegistry = NewRegistry()
+
+// Register добавляет фабрику коллектора в реестр по умолчанию. Вызывается из init;
+// при повторной регистрации имени паникует.
+func Register(name string, factory Factory) {
+	if err := defaultRegistry.Register(name, factory); err != nil {
+		panic(err)
+	}
+}
+
+// Default возвращает реестр по умолчанию со всеми встроенными коллекторами.
+func Default() *Registry {
+	return defaultRegistry
+}
+
+func gauge(id enum.MetricID, value float64) model.Metrics {
+	return model.Metrics{ID: id, MType: constants.GaugeMetricType, Value: &value}
+}
+
+func counter(id enum.MetricID, delta int64) model.Metrics {
+	return model.Metrics{ID: id, MType: constants.CounterMetricType, Delta: &delta}
+}
+
+// withLabels возвращает метрику metric с метками labels.
+func withLabels(metric model.Metrics, labels map[string]string) model.Metrics {
+	metric.Labels = labels
+	return metric
+}
+
+// cumulativeDelta возвращает прирост монотонного системного счётчика с prev до cur.
+// Уменьшение значения означает сброс счётчика (перезагрузку, переподключение устройства),
+// и тогда приростом считается всё значение cur, чтобы дельта не стала отрицательной.
+func cumulativeDelta(prev, cur uint64) int64 {
+	if cur < prev {
+		return int64(cur)
+	}
+	return int64(cur - prev)
+}
@@ -0,0 +1,103 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"github.com/ruslanDantsov/osmetrics-server/internal/agent/config"
+	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/model"
+	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/model/enum"
+	"github.com/shirou/gopsutil/v4/mem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func gaugeValues(metrics []model.Metrics) map[enum.MetricID]float64 {
+	values := make(map[enum.MetricID]float64, len(metrics))
+	for _, metric := range metrics {
+		if metric.Value != nil {
+			values[metric.ID] = *metric.Value
+		}
+	}
+	return values
+}
+
+func TestRegistry_Build(t *testing.T) {
+	registry := NewRegistry()
+	require.NoError(t, registry.Register(RuntimeCollectorName, func(*config.AgentConfig) (Collector, error) {
+		return NewRuntimeCollector(), nil
+	}))
+	require.NoError(t, registry.Register(CPUCollectorName, func(*config.AgentConfig) (Collector, error) {
+		return NewCPUCollector(), nil
+	}))
+	assert.Error(t, registry.Register(CPUCollectorName, nil))
+	assert.Equal(t, []string{CPUCollectorName, RuntimeCollectorName}, registry.Names())
+
+	scheduled, err := registry.Build(&config.AgentConfig{
+		Collectors:         []string{RuntimeCollectorName, CPUCollectorName},
+		CollectorIntervals: map[string]time.Duration{CPUCollectorName: 5 * time.Second},
+		PollInterval:       2 * time.Second,
+	})
+	require.NoError(t, err)
+	require.Len(t, scheduled, 2)
+	assert.Equal(t, RuntimeCollectorName, scheduled[0].Collector.Name())
+	assert.Equal(t, 2*time.Second, scheduled[0].Interval)
+	assert.Equal(t, CPUCollectorName, scheduled[1].Collector.Name())
+	assert.Equal(t, 5*time.Second, scheduled[1].Interval)
+
+	_, err = registry.Build(&config.AgentConfig{Collectors: []string{"gpu"}})
+	assert.Error(t, err)
+
+	_, err = registry.Build(&config.AgentConfig{
+		Collectors:         []string{RuntimeCollectorName},
+		CollectorIntervals: map[string]time.Duration{"gpu": time.Second},
+	})
+	assert.Error(t, err)
+}
+
+func TestDefault_HasBuiltinCollectors(t *testing.T) {
+	assert.Subset(t, Default().Names(), []string{RuntimeCollectorName, MemoryCollectorName, CPUCollectorName})
+}
+
+func TestRuntimeCollector_Collect(t *testing.T) {
+	metrics, err := NewRuntimeCollector().Collect(context.Background())
+	require.NoError(t, err)
+
+	assert.Contains(t, gaugeValues(metrics), enum.Alloc)
+	assert.Contains(t, metrics, counter(enum.PollCount, 1))
+}
+
+func TestMemoryCollector_Collect(t *testing.T) {
+	c := NewMemoryCollector()
+	c.virtualMemory = func() (*mem.VirtualMemoryStat, error) {
+		return &mem.VirtualMemoryStat{Total: 1024, Free: 256}, nil
+	}
+
+	metrics, err := c.Collect(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, map[enum.MetricID]float64{enum.TotalMemory: 1024, enum.FreeMemory: 256}, gaugeValues(metrics))
+
+	c.virtualMemory = func() (*mem.VirtualMemoryStat, error) {
+		return nil, errors.New("no /proc/meminfo")
+	}
+	metrics, err = c.Collect(context.Background())
+	assert.Error(t, err)
+	assert.Empty(t, metrics)
+}
+
+func TestCPUCollector_ReportsUtilizationPerCore(t *testing.T) {
+	c := NewCPUCollector()
+	c.cpuPercent = func(interval time.Duration, perCPU bool) ([]float64, error) {
+		assert.True(t, perCPU)
+		return []float64{12.5, 50, 100}, nil
+	}
+
+	metrics, err := c.Collect(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, map[enum.MetricID]float64{
+		enum.CPUutilization(1): 12.5,
+		enum.CPUutilization(2): 50,
+		enum.CPUutilization(3): 100,
+	}, gaugeValues(metrics))
+}
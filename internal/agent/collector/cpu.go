@@ -0,0 +1,50 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"github.com/ruslanDantsov/osmetrics-server/internal/agent/config"
+	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/model"
+	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/model/enum"
+	"github.com/shirou/gopsutil/v4/cpu"
+	"time"
+)
+
+// CPUCollectorName — имя коллектора загрузки CPU.
+const CPUCollectorName = "cpu"
+
+func init() {
+	Register(CPUCollectorName, func(*config.AgentConfig) (Collector, error) {
+		return NewCPUCollector(), nil
+	})
+}
+
+// CPUCollector собирает загрузку каждого ядра CPU в процентах в виде CPUutilization1..CPUutilizationN.
+type CPUCollector struct {
+	cpuPercent func(interval time.Duration, perCPU bool) ([]float64, error)
+}
+
+// NewCPUCollector создаёт CPUCollector.
+func NewCPUCollector() *CPUCollector {
+	return &CPUCollector{cpuPercent: cpu.Percent}
+}
+
+// Name возвращает имя коллектора.
+func (c *CPUCollector) Name() string {
+	return CPUCollectorName
+}
+
+// Collect собирает загрузку ядер. Загрузка считается с момента предыдущего вызова,
+// то есть за интервал опроса коллектора.
+func (c *CPUCollector) Collect(ctx context.Context) ([]model.Metrics, error) {
+	percents, err := c.cpuPercent(0, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CPU utilization: %w", err)
+	}
+
+	metrics := make([]model.Metrics, 0, len(percents))
+	for core, percent := range percents {
+		metrics = append(metrics, gauge(enum.CPUutilization(core+1), percent))
+	}
+	return metrics, nil
+}
@@ -0,0 +1,134 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/model"
+	"github.com/shirou/gopsutil/v4/disk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+// seriesValues возвращает значения метрик, сгруппированные по ключу "имя{метка}".
+func seriesValues(metrics []model.Metrics, label string) map[string]float64 {
+	values := make(map[string]float64, len(metrics))
+	for _, metric := range metrics {
+		key := string(metric.ID) + "{" + metric.Labels[label] + "}"
+		if metric.Value != nil {
+			values[key] = *metric.Value
+		}
+		if metric.Delta != nil {
+			values[key] = float64(*metric.Delta)
+		}
+	}
+	return values
+}
+
+func TestFilter(t *testing.T) {
+	f, err := newFilter([]string{"/", "/data/*"}, []string{"/data/tmp"})
+	require.NoError(t, err)
+
+	assert.True(t, f.matches("/"))
+	assert.True(t, f.matches("/data/db"))
+	assert.False(t, f.matches("/data/tmp"))
+	assert.False(t, f.matches("/boot"))
+
+	f, err = newFilter(nil, []string{"loop*"})
+	require.NoError(t, err)
+	assert.True(t, f.matches("sda"))
+	assert.False(t, f.matches("loop0"))
+
+	_, err = newFilter([]string{"[sd"}, nil)
+	assert.Error(t, err)
+}
+
+func TestFilesystemCollector_Collect(t *testing.T) {
+	c, err := NewFilesystemCollector(nil, []string{"/snap/*"})
+	require.NoError(t, err)
+	c.partitions = func(ctx context.Context, all bool) ([]disk.PartitionStat, error) {
+		assert.False(t, all)
+		return []disk.PartitionStat{
+			{Device: "/dev/sda1", Mountpoint: "/", Fstype: "ext4"},
+			{Device: "/dev/sda1", Mountpoint: "/", Fstype: "ext4"},
+			{Device: "/dev/loop0", Mountpoint: "/snap/core/1", Fstype: "squashfs"},
+			{Device: "/dev/sdb1", Mountpoint: "/data", Fstype: "xfs"},
+			{Device: "server:/export", Mountpoint: "/mnt/nfs", Fstype: "nfs"},
+		}, nil
+	}
+	c.usage = func(ctx context.Context, path string) (*disk.UsageStat, error) {
+		switch path {
+		case "/":
+			return &disk.UsageStat{Total: 100, Free: 40, Used: 60, InodesTotal: 10, InodesFree: 7, InodesUsed: 3}, nil
+		case "/data":
+			return &disk.UsageStat{Total: 1000, Free: 1, Used: 999}, nil
+		}
+		return nil, errors.New("stale file handle")
+	}
+
+	metrics, err := c.Collect(context.Background())
+	assert.ErrorContains(t, err, "/mnt/nfs")
+	assert.Len(t, metrics, 12)
+
+	values := seriesValues(metrics, MountpointLabelName)
+	assert.Equal(t, 100.0, values["DiskTotal{/}"])
+	assert.Equal(t, 60.0, values["DiskUsed{/}"])
+	assert.Equal(t, 7.0, values["DiskInodesFree{/}"])
+	assert.Equal(t, 1.0, values["DiskFree{/data}"])
+	assert.NotContains(t, values, "DiskTotal{/snap/core/1}")
+
+	for _, metric := range metrics {
+		if metric.Labels[MountpointLabelName] == "/data" {
+			assert.Equal(t, map[string]string{MountpointLabelName: "/data", FstypeLabelName: "xfs", DeviceLabelName: "/dev/sdb1"}, metric.Labels)
+		}
+	}
+}
+
+func TestDiskIOCollector_EmitsDeltas(t *testing.T) {
+	c, err := NewDiskIOCollector(nil, []string{"loop*"})
+	require.NoError(t, err)
+
+	counters := map[string]disk.IOCountersStat{
+		"sda":   {ReadBytes: 1000, WriteBytes: 500, ReadCount: 10, WriteCount: 5},
+		"loop0": {ReadBytes: 1},
+	}
+	c.ioCounters = func(context.Context, ...string) (map[string]disk.IOCountersStat, error) {
+		return counters, nil
+	}
+
+	metrics, err := c.Collect(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, metrics, "first poll only records a baseline")
+
+	counters = map[string]disk.IOCountersStat{
+		"sda":   {ReadBytes: 1500, WriteBytes: 700, ReadCount: 12, WriteCount: 9},
+		"loop0": {ReadBytes: 5},
+		"sdb":   {ReadBytes: 42},
+	}
+	metrics, err = c.Collect(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]float64{
+		"DiskReadBytes{sda}":  500,
+		"DiskWriteBytes{sda}": 200,
+		"DiskReadOps{sda}":    2,
+		"DiskWriteOps{sda}":   4,
+	}, seriesValues(metrics, DeviceLabelName))
+	for _, metric := range metrics {
+		assert.Equal(t, "counter", metric.MType)
+	}
+
+	// Счётчики sda сброшены, например после переподключения устройства.
+	counters = map[string]disk.IOCountersStat{
+		"sda": {ReadBytes: 100, WriteBytes: 700, ReadCount: 1, WriteCount: 9},
+		"sdb": {ReadBytes: 50},
+	}
+	metrics, err = c.Collect(context.Background())
+	require.NoError(t, err)
+	values := seriesValues(metrics, DeviceLabelName)
+	assert.Equal(t, 100.0, values["DiskReadBytes{sda}"])
+	assert.Equal(t, 0.0, values["DiskWriteBytes{sda}"])
+	assert.Equal(t, 8.0, values["DiskReadBytes{sdb}"])
+	for _, value := range values {
+		assert.GreaterOrEqual(t, value, 0.0)
+	}
+}
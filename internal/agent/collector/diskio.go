@@ -0,0 +1,85 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"github.com/ruslanDantsov/osmetrics-server/internal/agent/config"
+	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/model"
+	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/model/enum"
+	"github.com/shirou/gopsutil/v4/disk"
+)
+
+// DiskIOCollectorName — имя коллектора дискового ввода-вывода.
+const DiskIOCollectorName = "diskio"
+
+func init() {
+	Register(DiskIOCollectorName, func(cfg *config.AgentConfig) (Collector, error) {
+		return NewDiskIOCollector(cfg.DiskDevicesInclude, cfg.DiskDevicesExclude)
+	})
+}
+
+// DiskIOCollector собирает для каждого блочного устройства прочитанные и записанные байты
+// (DiskReadBytes, DiskWriteBytes) и число операций (DiskReadOps, DiskWriteOps) с меткой device.
+//
+// Значения отправляются как counter с приростом с предыдущего опроса, поэтому первый опрос
+// только запоминает текущие значения счётчиков и метрик не возвращает.
+type DiskIOCollector struct {
+	devices *filter
+
+	// previous — значения счётчиков устройств на предыдущем опросе.
+	// Collect вызывается из одной горутины, поэтому доступ не синхронизируется.
+	previous map[string]disk.IOCountersStat
+
+	ioCounters func(ctx context.Context, names ...string) (map[string]disk.IOCountersStat, error)
+}
+
+// NewDiskIOCollector создаёт DiskIOCollector, отбирающий устройства по шаблонам include и exclude.
+func NewDiskIOCollector(include, exclude []string) (*DiskIOCollector, error) {
+	devices, err := newFilter(include, exclude)
+	if err != nil {
+		return nil, fmt.Errorf("device filter: %w", err)
+	}
+
+	return &DiskIOCollector{
+		devices:    devices,
+		ioCounters: disk.IOCountersWithContext,
+	}, nil
+}
+
+// Name возвращает имя коллектора.
+func (c *DiskIOCollector) Name() string {
+	return DiskIOCollectorName
+}
+
+// Collect собирает прирост счётчиков ввода-вывода устройств с предыдущего опроса.
+func (c *DiskIOCollector) Collect(ctx context.Context) ([]model.Metrics, error) {
+	counters, err := c.ioCounters(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read disk I/O counters: %w", err)
+	}
+
+	current := make(map[string]disk.IOCountersStat, len(counters))
+	var metrics []model.Metrics
+	for device, stat := range counters {
+		if !c.devices.matches(device) {
+			continue
+		}
+		current[device] = stat
+
+		prev, ok := c.previous[device]
+		if !ok {
+			continue
+		}
+
+		labels := map[string]string{DeviceLabelName: device}
+		metrics = append(metrics,
+			withLabels(counter(enum.DiskReadBytes, cumulativeDelta(prev.ReadBytes, stat.ReadBytes)), labels),
+			withLabels(counter(enum.DiskWriteBytes, cumulativeDelta(prev.WriteBytes, stat.WriteBytes)), labels),
+			withLabels(counter(enum.DiskReadOps, cumulativeDelta(prev.ReadCount, stat.ReadCount)), labels),
+			withLabels(counter(enum.DiskWriteOps, cumulativeDelta(prev.WriteCount, stat.WriteCount)), labels),
+		)
+	}
+	c.previous = current
+
+	return metrics, nil
+}
@@ -0,0 +1,97 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ruslanDantsov/osmetrics-server/internal/agent/config"
+	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/model"
+	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/model/enum"
+	"github.com/shirou/gopsutil/v4/disk"
+)
+
+// FilesystemCollectorName — имя коллектора заполненности файловых систем.
+const FilesystemCollectorName = "filesystem"
+
+// Метки метрик файловых систем и дисков.
+const (
+	MountpointLabelName = "mountpoint"
+	FstypeLabelName     = "fstype"
+	DeviceLabelName     = "device"
+)
+
+func init() {
+	Register(FilesystemCollectorName, func(cfg *config.AgentConfig) (Collector, error) {
+		return NewFilesystemCollector(cfg.DiskMountpointsInclude, cfg.DiskMountpointsExclude)
+	})
+}
+
+// FilesystemCollector собирает для каждой точки монтирования объём (DiskTotal, DiskFree, DiskUsed)
+// и число inode (DiskInodesTotal, DiskInodesFree, DiskInodesUsed) с метками mountpoint, fstype и device.
+type FilesystemCollector struct {
+	mountpoints *filter
+
+	partitions func(ctx context.Context, all bool) ([]disk.PartitionStat, error)
+	usage      func(ctx context.Context, path string) (*disk.UsageStat, error)
+}
+
+// NewFilesystemCollector создаёт FilesystemCollector, отбирающий точки монтирования
+// по шаблонам include и exclude.
+func NewFilesystemCollector(include, exclude []string) (*FilesystemCollector, error) {
+	mountpoints, err := newFilter(include, exclude)
+	if err != nil {
+		return nil, fmt.Errorf("mountpoint filter: %w", err)
+	}
+
+	return &FilesystemCollector{
+		mountpoints: mountpoints,
+		partitions:  disk.PartitionsWithContext,
+		usage:       disk.UsageWithContext,
+	}, nil
+}
+
+// Name возвращает имя коллектора.
+func (c *FilesystemCollector) Name() string {
+	return FilesystemCollectorName
+}
+
+// Collect собирает заполненность физических файловых систем. Точки монтирования,
+// для которых не удалось прочитать статистику, пропускаются, а ошибки возвращаются вместе с метриками.
+func (c *FilesystemCollector) Collect(ctx context.Context) ([]model.Metrics, error) {
+	partitions, err := c.partitions(ctx, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list partitions: %w", err)
+	}
+
+	var metrics []model.Metrics
+	var errs []error
+	seen := make(map[string]struct{}, len(partitions))
+	for _, partition := range partitions {
+		if _, ok := seen[partition.Mountpoint]; ok || !c.mountpoints.matches(partition.Mountpoint) {
+			continue
+		}
+		seen[partition.Mountpoint] = struct{}{}
+
+		usage, err := c.usage(ctx, partition.Mountpoint)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to read usage of %s: %w", partition.Mountpoint, err))
+			continue
+		}
+
+		labels := map[string]string{
+			MountpointLabelName: partition.Mountpoint,
+			FstypeLabelName:     partition.Fstype,
+			DeviceLabelName:     partition.Device,
+		}
+		metrics = append(metrics,
+			withLabels(gauge(enum.DiskTotal, float64(usage.Total)), labels),
+			withLabels(gauge(enum.DiskFree, float64(usage.Free)), labels),
+			withLabels(gauge(enum.DiskUsed, float64(usage.Used)), labels),
+			withLabels(gauge(enum.DiskInodesTotal, float64(usage.InodesTotal)), labels),
+			withLabels(gauge(enum.DiskInodesFree, float64(usage.InodesFree)), labels),
+			withLabels(gauge(enum.DiskInodesUsed, float64(usage.InodesUsed)), labels),
+		)
+	}
+
+	return metrics, errors.Join(errs...)
+}
@@ -0,0 +1,43 @@
+package collector
+
+import (
+	"fmt"
+	"path"
+)
+
+// filter отбирает объекты (точки монтирования, устройства, интерфейсы) по именам
+// с помощью шаблонов path.Match, например "/mnt/*" или "loop*".
+//
+// Имя проходит фильтр, если оно соответствует хотя бы одному шаблону include
+// (или include пуст) и не соответствует ни одному шаблону exclude.
+type filter struct {
+	include []string
+	exclude []string
+}
+
+// newFilter создаёт фильтр и проверяет синтаксис шаблонов.
+func newFilter(include, exclude []string) (*filter, error) {
+	for _, pattern := range append(append([]string{}, include...), exclude...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+	return &filter{include: include, exclude: exclude}, nil
+}
+
+// matches сообщает, проходит ли name фильтр.
+func (f *filter) matches(name string) bool {
+	if len(f.include) > 0 && !matchAny(f.include, name) {
+		return false
+	}
+	return !matchAny(f.exclude, name)
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
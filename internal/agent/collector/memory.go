@@ -0,0 +1,47 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"github.com/ruslanDantsov/osmetrics-server/internal/agent/config"
+	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/model"
+	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/model/enum"
+	"github.com/shirou/gopsutil/v4/mem"
+)
+
+// MemoryCollectorName — имя коллектора системной памяти.
+const MemoryCollectorName = "memory"
+
+func init() {
+	Register(MemoryCollectorName, func(*config.AgentConfig) (Collector, error) {
+		return NewMemoryCollector(), nil
+	})
+}
+
+// MemoryCollector собирает объём свободной и общей памяти системы.
+type MemoryCollector struct {
+	virtualMemory func() (*mem.VirtualMemoryStat, error)
+}
+
+// NewMemoryCollector создаёт MemoryCollector.
+func NewMemoryCollector() *MemoryCollector {
+	return &MemoryCollector{virtualMemory: mem.VirtualMemory}
+}
+
+// Name возвращает имя коллектора.
+func (c *MemoryCollector) Name() string {
+	return MemoryCollectorName
+}
+
+// Collect собирает FreeMemory и TotalMemory.
+func (c *MemoryCollector) Collect(ctx context.Context) ([]model.Metrics, error) {
+	memInfo, err := c.virtualMemory()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read memory stats: %w", err)
+	}
+
+	return []model.Metrics{
+		gauge(enum.FreeMemory, float64(memInfo.Free)),
+		gauge(enum.TotalMemory, float64(memInfo.Total)),
+	}, nil
+}
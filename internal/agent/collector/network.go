@@ -0,0 +1,95 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"github.com/ruslanDantsov/osmetrics-server/internal/agent/config"
+	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/model"
+	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/model/enum"
+	"github.com/shirou/gopsutil/v4/net"
+)
+
+// NetworkCollectorName — имя коллектора трафика сетевых интерфейсов.
+const NetworkCollectorName = "network"
+
+// InterfaceLabelName — метка с именем сетевого интерфейса.
+const InterfaceLabelName = "interface"
+
+func init() {
+	Register(NetworkCollectorName, func(cfg *config.AgentConfig) (Collector, error) {
+		return NewNetworkCollector(cfg.NetInterfacesInclude, cfg.NetInterfacesExclude)
+	})
+}
+
+// NetworkCollector собирает для каждого сетевого интерфейса отправленные и полученные байты,
+// пакеты, ошибки и отброшенные пакеты (NetBytesSent, NetBytesRecv, NetPacketsSent, NetPacketsRecv,
+// NetErrorsIn, NetErrorsOut, NetDropsIn, NetDropsOut) с меткой interface.
+//
+// Значения отправляются как counter с приростом с предыдущего опроса, поэтому первый опрос
+// и первый опрос после появления интерфейса только запоминают текущие значения счётчиков.
+type NetworkCollector struct {
+	interfaces *filter
+
+	// previous — значения счётчиков интерфейсов на предыдущем опросе.
+	// Collect вызывается из одной горутины, поэтому доступ не синхронизируется.
+	previous map[string]net.IOCountersStat
+
+	ioCounters func(ctx context.Context, pernic bool) ([]net.IOCountersStat, error)
+}
+
+// NewNetworkCollector создаёт NetworkCollector, отбирающий интерфейсы по шаблонам include и exclude.
+func NewNetworkCollector(include, exclude []string) (*NetworkCollector, error) {
+	interfaces, err := newFilter(include, exclude)
+	if err != nil {
+		return nil, fmt.Errorf("interface filter: %w", err)
+	}
+
+	return &NetworkCollector{
+		interfaces: interfaces,
+		ioCounters: net.IOCountersWithContext,
+	}, nil
+}
+
+// Name возвращает имя коллектора.
+func (c *NetworkCollector) Name() string {
+	return NetworkCollectorName
+}
+
+// Collect собирает прирост счётчиков интерфейсов с предыдущего опроса.
+// Если счётчик интерфейса уменьшился (например, после перезапуска интерфейса),
+// приростом считается его текущее значение.
+func (c *NetworkCollector) Collect(ctx context.Context) ([]model.Metrics, error) {
+	counters, err := c.ioCounters(ctx, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read network I/O counters: %w", err)
+	}
+
+	current := make(map[string]net.IOCountersStat, len(counters))
+	var metrics []model.Metrics
+	for _, stat := range counters {
+		if !c.interfaces.matches(stat.Name) {
+			continue
+		}
+		current[stat.Name] = stat
+
+		prev, ok := c.previous[stat.Name]
+		if !ok {
+			continue
+		}
+
+		labels := map[string]string{InterfaceLabelName: stat.Name}
+		metrics = append(metrics,
+			withLabels(counter(enum.NetBytesSent, cumulativeDelta(prev.BytesSent, stat.BytesSent)), labels),
+			withLabels(counter(enum.NetBytesRecv, cumulativeDelta(prev.BytesRecv, stat.BytesRecv)), labels),
+			withLabels(counter(enum.NetPacketsSent, cumulativeDelta(prev.PacketsSent, stat.PacketsSent)), labels),
+			withLabels(counter(enum.NetPacketsRecv, cumulativeDelta(prev.PacketsRecv, stat.PacketsRecv)), labels),
+			withLabels(counter(enum.NetErrorsIn, cumulativeDelta(prev.Errin, stat.Errin)), labels),
+			withLabels(counter(enum.NetErrorsOut, cumulativeDelta(prev.Errout, stat.Errout)), labels),
+			withLabels(counter(enum.NetDropsIn, cumulativeDelta(prev.Dropin, stat.Dropin)), labels),
+			withLabels(counter(enum.NetDropsOut, cumulativeDelta(prev.Dropout, stat.Dropout)), labels),
+		)
+	}
+	c.previous = current
+
+	return metrics, nil
+}
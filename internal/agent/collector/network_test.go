@@ -0,0 +1,82 @@
+package collector
+
+import (
+	"context"
+	"github.com/shirou/gopsutil/v4/net"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestNetworkCollector_EmitsDeltasAndHandlesResets(t *testing.T) {
+	c, err := NewNetworkCollector(nil, []string{"lo", "veth*"})
+	require.NoError(t, err)
+
+	counters := []net.IOCountersStat{
+		{Name: "lo", BytesSent: 100},
+		{Name: "eth0", BytesSent: 1000, BytesRecv: 2000, PacketsSent: 10, PacketsRecv: 20, Errin: 1, Dropout: 2},
+		{Name: "veth1a2b", BytesRecv: 7},
+	}
+	c.ioCounters = func(ctx context.Context, pernic bool) ([]net.IOCountersStat, error) {
+		assert.True(t, pernic)
+		return counters, nil
+	}
+
+	metrics, err := c.Collect(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, metrics, "first poll only records a baseline")
+
+	counters = []net.IOCountersStat{
+		{Name: "lo", BytesSent: 500},
+		{Name: "eth0", BytesSent: 1500, BytesRecv: 2100, PacketsSent: 15, PacketsRecv: 21, Errin: 1, Dropout: 5},
+		{Name: "veth1a2b", BytesRecv: 70},
+	}
+	metrics, err = c.Collect(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]float64{
+		"NetBytesSent{eth0}":   500,
+		"NetBytesRecv{eth0}":   100,
+		"NetPacketsSent{eth0}": 5,
+		"NetPacketsRecv{eth0}": 1,
+		"NetErrorsIn{eth0}":    0,
+		"NetErrorsOut{eth0}":   0,
+		"NetDropsIn{eth0}":     0,
+		"NetDropsOut{eth0}":    3,
+	}, seriesValues(metrics, InterfaceLabelName))
+
+	// eth0 перезапущен: счётчики начались заново.
+	counters = []net.IOCountersStat{
+		{Name: "eth0", BytesSent: 40, BytesRecv: 2200, PacketsSent: 1, PacketsRecv: 22},
+	}
+	metrics, err = c.Collect(context.Background())
+	require.NoError(t, err)
+	values := seriesValues(metrics, InterfaceLabelName)
+	assert.Equal(t, 40.0, values["NetBytesSent{eth0}"])
+	assert.Equal(t, 100.0, values["NetBytesRecv{eth0}"])
+	for _, value := range values {
+		assert.GreaterOrEqual(t, value, 0.0)
+	}
+}
+
+func TestTCPCollector_CountsConnectionsByState(t *testing.T) {
+	c := NewTCPCollector()
+	c.connections = func(ctx context.Context, kind string) ([]net.ConnectionStat, error) {
+		assert.Equal(t, "tcp", kind)
+		return []net.ConnectionStat{
+			{Status: "ESTABLISHED"},
+			{Status: "ESTABLISHED"},
+			{Status: "LISTEN"},
+			{Status: "TIME_WAIT"},
+		}, nil
+	}
+
+	metrics, err := c.Collect(context.Background())
+	require.NoError(t, err)
+
+	values := seriesValues(metrics, StateLabelName)
+	assert.Len(t, values, len(tcpStates))
+	assert.Equal(t, 2.0, values["TCPConnections{ESTABLISHED}"])
+	assert.Equal(t, 1.0, values["TCPConnections{LISTEN}"])
+	assert.Equal(t, 1.0, values["TCPConnections{TIME_WAIT}"])
+	assert.Equal(t, 0.0, values["TCPConnections{CLOSE_WAIT}"])
+}
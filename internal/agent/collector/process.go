@@ -0,0 +1,289 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ruslanDantsov/osmetrics-server/internal/agent/config"
+	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/model"
+	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/model/enum"
+	"github.com/shirou/gopsutil/v4/process"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ProcessCollectorName — имя коллектора метрик наблюдаемых процессов.
+const ProcessCollectorName = "process"
+
+// ProcessLabelName — метка с именем группы наблюдаемых процессов.
+const ProcessLabelName = "process"
+
+// cgroupRoot — точка монтирования файловой системы cgroup.
+const cgroupRoot = "/sys/fs/cgroup"
+
+func init() {
+	Register(ProcessCollectorName, func(cfg *config.AgentConfig) (Collector, error) {
+		return NewProcessCollector(cfg.ProcessWatches), nil
+	})
+}
+
+// processHandle — процесс, показатели которого читает коллектор; реализуется *process.Process.
+type processHandle interface {
+	NameWithContext(ctx context.Context) (string, error)
+	CreateTimeWithContext(ctx context.Context) (int64, error)
+	IsRunningWithContext(ctx context.Context) (bool, error)
+	PercentWithContext(ctx context.Context, interval time.Duration) (float64, error)
+	MemoryInfoWithContext(ctx context.Context) (*process.MemoryInfoStat, error)
+	NumFDsWithContext(ctx context.Context) (int32, error)
+	NumThreadsWithContext(ctx context.Context) (int32, error)
+}
+
+// ProcessCollector собирает метрики групп процессов, заданных в конфигурации агента.
+//
+// Для каждой группы отправляются gauge с меткой process: process_up (1, если запущен хотя бы один
+// процесс группы, иначе 0), process_count и, если процессы есть, суммарные по группе
+// process_cpu_percent, process_rss_bytes, process_open_fds, process_threads, а также
+// process_uptime_seconds самого старого процесса группы.
+//
+// Загрузка CPU считается с предыдущего опроса, поэтому для нового процесса на первом опросе она равна 0.
+type ProcessCollector struct {
+	watches    []config.ProcessWatch
+	cgroupRoot string
+
+	// handles — процессы, прочитанные на предыдущем опросе, по PID. Хранятся между опросами,
+	// чтобы считать загрузку CPU с предыдущего опроса. Collect вызывается из одной горутины.
+	handles map[int32]processHandle
+
+	pids       func(ctx context.Context) ([]int32, error)
+	newProcess func(ctx context.Context, pid int32) (processHandle, error)
+	now        func() time.Time
+}
+
+// NewProcessCollector создаёт ProcessCollector для групп процессов watches.
+func NewProcessCollector(watches []config.ProcessWatch) *ProcessCollector {
+	return &ProcessCollector{
+		watches:    watches,
+		cgroupRoot: cgroupRoot,
+		handles:    make(map[int32]processHandle),
+		pids:       process.PidsWithContext,
+		newProcess: func(ctx context.Context, pid int32) (processHandle, error) {
+			p, err := process.NewProcessWithContext(ctx, pid)
+			if err != nil {
+				return nil, err
+			}
+			return p, nil
+		},
+		now: time.Now,
+	}
+}
+
+// Name возвращает имя коллектора.
+func (c *ProcessCollector) Name() string {
+	return ProcessCollectorName
+}
+
+// processGroupStats — суммарные показатели процессов группы.
+type processGroupStats struct {
+	count      int
+	cpuPercent float64
+	rss        uint64
+	fds        int64
+	threads    int64
+	oldest     time.Time
+}
+
+// Collect собирает метрики всех групп процессов. Показатели, которые не удалось прочитать,
+// не учитываются в сумме, а ошибки возвращаются вместе с метриками.
+func (c *ProcessCollector) Collect(ctx context.Context) ([]model.Metrics, error) {
+	if len(c.watches) == 0 {
+		return nil, nil
+	}
+
+	handles := make(map[int32]processHandle, len(c.handles))
+	var allPids []int32
+	var metrics []model.Metrics
+	var errs []error
+
+	for _, watch := range c.watches {
+		var pids []int32
+		var err error
+		switch watch.Selector {
+		case config.ProcessSelectorName:
+			if allPids == nil {
+				if allPids, err = c.pids(ctx); err != nil {
+					errs = append(errs, fmt.Errorf("failed to list processes: %w", err))
+				}
+			}
+			pids = c.matchName(ctx, handles, allPids, watch.Target)
+		case config.ProcessSelectorPIDFile:
+			pids, err = c.readPIDFile(watch.Target)
+		case config.ProcessSelectorCgroup:
+			pids, err = c.readCgroup(watch.Target)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("process %s: %w", watch.Name, err))
+		}
+
+		stats, err := c.groupStats(ctx, handles, pids)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("process %s: %w", watch.Name, err))
+		}
+		metrics = append(metrics, c.groupMetrics(watch.Name, stats)...)
+	}
+
+	c.handles = handles
+	return metrics, errors.Join(errs...)
+}
+
+// handle возвращает процесс pid, сохранённый с предыдущего опроса, или открывает его заново.
+// Возвращает nil, если процесса не существует.
+func (c *ProcessCollector) handle(ctx context.Context, handles map[int32]processHandle, pid int32) processHandle {
+	if h, ok := handles[pid]; ok {
+		return h
+	}
+	h, ok := c.handles[pid]
+	if !ok {
+		var err error
+		if h, err = c.newProcess(ctx, pid); err != nil {
+			return nil
+		}
+	}
+	handles[pid] = h
+	return h
+}
+
+func (c *ProcessCollector) matchName(ctx context.Context, handles map[int32]processHandle, pids []int32, pattern string) []int32 {
+	var matched []int32
+	for _, pid := range pids {
+		h := c.handle(ctx, handles, pid)
+		if h == nil {
+			continue
+		}
+		name, err := h.NameWithContext(ctx)
+		if err != nil {
+			continue
+		}
+		if ok, _ := path.Match(pattern, name); ok {
+			matched = append(matched, pid)
+		}
+	}
+	return matched
+}
+
+// readPIDFile читает PID из pidfile. Отсутствие файла означает, что процесс не запущен.
+func (c *ProcessCollector) readPIDFile(name string) ([]int32, error) {
+	data, err := os.ReadFile(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pidfile: %w", err)
+	}
+
+	pid, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 32)
+	if err != nil || pid <= 0 {
+		return nil, fmt.Errorf("invalid pidfile %s: %q", name, strings.TrimSpace(string(data)))
+	}
+	return []int32{int32(pid)}, nil
+}
+
+// readCgroup читает PID процессов cgroup из cgroup.procs. Отсутствие cgroup означает,
+// что процессы не запущены.
+func (c *ProcessCollector) readCgroup(cgroup string) ([]int32, error) {
+	data, err := os.ReadFile(filepath.Join(c.cgroupRoot, filepath.Clean("/"+cgroup), "cgroup.procs"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cgroup: %w", err)
+	}
+
+	var pids []int32
+	for _, line := range strings.Fields(string(data)) {
+		pid, err := strconv.ParseInt(line, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid PID %q in cgroup %s", line, cgroup)
+		}
+		pids = append(pids, int32(pid))
+	}
+	return pids, nil
+}
+
+// groupStats суммирует показатели процессов pids. Процессы, завершившиеся во время опроса, пропускаются.
+func (c *ProcessCollector) groupStats(ctx context.Context, handles map[int32]processHandle, pids []int32) (processGroupStats, error) {
+	var stats processGroupStats
+	var errs []error
+
+	for _, pid := range pids {
+		h := c.handle(ctx, handles, pid)
+		if h == nil {
+			continue
+		}
+
+		createTime, err := h.CreateTimeWithContext(ctx)
+		if err != nil {
+			if running, _ := h.IsRunningWithContext(ctx); !running {
+				continue
+			}
+			errs = append(errs, fmt.Errorf("pid %d: %w", pid, err))
+			continue
+		}
+
+		stats.count++
+		if created := time.UnixMilli(createTime); stats.oldest.IsZero() || created.Before(stats.oldest) {
+			stats.oldest = created
+		}
+
+		if percent, err := h.PercentWithContext(ctx, 0); err == nil {
+			stats.cpuPercent += percent
+		} else {
+			errs = append(errs, fmt.Errorf("pid %d: failed to read CPU usage: %w", pid, err))
+		}
+		if memInfo, err := h.MemoryInfoWithContext(ctx); err == nil {
+			stats.rss += memInfo.RSS
+		} else {
+			errs = append(errs, fmt.Errorf("pid %d: failed to read memory usage: %w", pid, err))
+		}
+		if fds, err := h.NumFDsWithContext(ctx); err == nil {
+			stats.fds += int64(fds)
+		} else {
+			errs = append(errs, fmt.Errorf("pid %d: failed to read open files: %w", pid, err))
+		}
+		if threads, err := h.NumThreadsWithContext(ctx); err == nil {
+			stats.threads += int64(threads)
+		} else {
+			errs = append(errs, fmt.Errorf("pid %d: failed to read threads: %w", pid, err))
+		}
+	}
+
+	return stats, errors.Join(errs...)
+}
+
+func (c *ProcessCollector) groupMetrics(name string, stats processGroupStats) []model.Metrics {
+	labels := map[string]string{ProcessLabelName: name}
+
+	up := 0.0
+	if stats.count > 0 {
+		up = 1
+	}
+	metrics := []model.Metrics{
+		withLabels(gauge(enum.ProcessUp, up), labels),
+		withLabels(gauge(enum.ProcessCount, float64(stats.count)), labels),
+	}
+	if stats.count == 0 {
+		return metrics
+	}
+
+	return append(metrics,
+		withLabels(gauge(enum.ProcessCPUPercent, stats.cpuPercent), labels),
+		withLabels(gauge(enum.ProcessRSSBytes, float64(stats.rss)), labels),
+		withLabels(gauge(enum.ProcessOpenFDs, float64(stats.fds)), labels),
+		withLabels(gauge(enum.ProcessThreads, float64(stats.threads)), labels),
+		withLabels(gauge(enum.ProcessUptimeSeconds, c.now().Sub(stats.oldest).Seconds()), labels),
+	)
+}
@@ -0,0 +1,167 @@
+package collector
+
+import (
+	"context"
+	"github.com/ruslanDantsov/osmetrics-server/internal/agent/config"
+	"github.com/shirou/gopsutil/v4/process"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type fakeProcess struct {
+	name    string
+	created time.Time
+	cpu     float64
+	rss     uint64
+	fds     int32
+	threads int32
+	gone    bool
+}
+
+func (p *fakeProcess) NameWithContext(context.Context) (string, error) {
+	if p.gone {
+		return "", process.ErrorProcessNotRunning
+	}
+	return p.name, nil
+}
+
+func (p *fakeProcess) CreateTimeWithContext(context.Context) (int64, error) {
+	if p.gone {
+		return 0, process.ErrorProcessNotRunning
+	}
+	return p.created.UnixMilli(), nil
+}
+
+func (p *fakeProcess) IsRunningWithContext(context.Context) (bool, error) {
+	return !p.gone, nil
+}
+
+func (p *fakeProcess) PercentWithContext(context.Context, time.Duration) (float64, error) {
+	return p.cpu, nil
+}
+
+func (p *fakeProcess) MemoryInfoWithContext(context.Context) (*process.MemoryInfoStat, error) {
+	return &process.MemoryInfoStat{RSS: p.rss}, nil
+}
+
+func (p *fakeProcess) NumFDsWithContext(context.Context) (int32, error) {
+	if p.fds < 0 {
+		return 0, process.ErrorNotPermitted
+	}
+	return p.fds, nil
+}
+
+func (p *fakeProcess) NumThreadsWithContext(context.Context) (int32, error) {
+	return p.threads, nil
+}
+
+func newTestProcessCollector(t *testing.T, watches []config.ProcessWatch, processes map[int32]*fakeProcess) *ProcessCollector {
+	now := time.Date(2025, 9, 1, 12, 0, 0, 0, time.UTC)
+
+	c := NewProcessCollector(watches)
+	c.cgroupRoot = t.TempDir()
+	c.now = func() time.Time { return now }
+	c.pids = func(context.Context) ([]int32, error) {
+		pids := make([]int32, 0, len(processes))
+		for pid, p := range processes {
+			if !p.gone {
+				pids = append(pids, pid)
+			}
+		}
+		return pids, nil
+	}
+	c.newProcess = func(_ context.Context, pid int32) (processHandle, error) {
+		p, ok := processes[pid]
+		if !ok || p.gone {
+			return nil, process.ErrorProcessNotRunning
+		}
+		return p, nil
+	}
+	return c
+}
+
+func TestProcessCollector_AggregatesGroupByName(t *testing.T) {
+	now := time.Date(2025, 9, 1, 12, 0, 0, 0, time.UTC)
+	processes := map[int32]*fakeProcess{
+		10: {name: "nginx", created: now.Add(-time.Hour), cpu: 1.5, rss: 100, fds: 10, threads: 1},
+		11: {name: "nginx", created: now.Add(-time.Minute), cpu: 2.5, rss: 200, fds: 20, threads: 4},
+		12: {name: "postgres", created: now.Add(-time.Minute), cpu: 50, rss: 1 << 20, fds: 5, threads: 2},
+	}
+	c := newTestProcessCollector(t, []config.ProcessWatch{
+		{Name: "web", Selector: config.ProcessSelectorName, Target: "nginx*"},
+	}, processes)
+
+	metrics, err := c.Collect(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]float64{
+		"process_up{web}":             1,
+		"process_count{web}":          2,
+		"process_cpu_percent{web}":    4,
+		"process_rss_bytes{web}":      300,
+		"process_open_fds{web}":       30,
+		"process_threads{web}":        5,
+		"process_uptime_seconds{web}": 3600,
+	}, seriesValues(metrics, ProcessLabelName))
+}
+
+func TestProcessCollector_ReportsDownWhenProcessDisappears(t *testing.T) {
+	now := time.Date(2025, 9, 1, 12, 0, 0, 0, time.UTC)
+	processes := map[int32]*fakeProcess{
+		42: {name: "postgres", created: now.Add(-time.Minute), rss: 10, fds: 1, threads: 1},
+	}
+	c := newTestProcessCollector(t, nil, processes)
+
+	pidfile := filepath.Join(t.TempDir(), "postgresql.pid")
+	require.NoError(t, os.WriteFile(pidfile, []byte("42\n"), 0600))
+	c.watches = []config.ProcessWatch{{Name: "db", Selector: config.ProcessSelectorPIDFile, Target: pidfile}}
+
+	metrics, err := c.Collect(context.Background())
+	require.NoError(t, err)
+	values := seriesValues(metrics, ProcessLabelName)
+	assert.Equal(t, 1.0, values["process_up{db}"])
+	assert.Equal(t, 60.0, values["process_uptime_seconds{db}"])
+
+	processes[42].gone = true
+	metrics, err = c.Collect(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]float64{"process_up{db}": 0, "process_count{db}": 0}, seriesValues(metrics, ProcessLabelName))
+
+	require.NoError(t, os.Remove(pidfile))
+	metrics, err = c.Collect(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0.0, seriesValues(metrics, ProcessLabelName)["process_up{db}"])
+
+	require.NoError(t, os.WriteFile(pidfile, []byte("not a pid"), 0600))
+	metrics, err = c.Collect(context.Background())
+	assert.Error(t, err)
+	assert.Equal(t, 0.0, seriesValues(metrics, ProcessLabelName)["process_up{db}"])
+}
+
+func TestProcessCollector_SelectsByCgroup(t *testing.T) {
+	now := time.Date(2025, 9, 1, 12, 0, 0, 0, time.UTC)
+	processes := map[int32]*fakeProcess{
+		7: {name: "app", created: now.Add(-time.Second), threads: 3, fds: -1},
+		8: {name: "worker", created: now.Add(-time.Second), threads: 2, fds: 4},
+	}
+	c := newTestProcessCollector(t, []config.ProcessWatch{
+		{Name: "app", Selector: config.ProcessSelectorCgroup, Target: "system.slice/app.service"},
+		{Name: "missing", Selector: config.ProcessSelectorCgroup, Target: "system.slice/missing.service"},
+	}, processes)
+
+	dir := filepath.Join(c.cgroupRoot, "system.slice", "app.service")
+	require.NoError(t, os.MkdirAll(dir, 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "cgroup.procs"), []byte("7\n8\n"), 0600))
+
+	metrics, err := c.Collect(context.Background())
+	assert.ErrorIs(t, err, process.ErrorNotPermitted)
+
+	values := seriesValues(metrics, ProcessLabelName)
+	assert.Equal(t, 2.0, values["process_count{app}"])
+	assert.Equal(t, 5.0, values["process_threads{app}"])
+	assert.Equal(t, 4.0, values["process_open_fds{app}"])
+	assert.Equal(t, 0.0, values["process_up{missing}"])
+}
@@ -0,0 +1,71 @@
+package collector
+
+import (
+	"context"
+	"github.com/ruslanDantsov/osmetrics-server/internal/agent/config"
+	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/model"
+	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/model/enum"
+	"math/rand"
+	"runtime"
+)
+
+// RuntimeCollectorName — имя коллектора статистики Go-рантайма агента.
+const RuntimeCollectorName = "runtime"
+
+func init() {
+	Register(RuntimeCollectorName, func(*config.AgentConfig) (Collector, error) {
+		return NewRuntimeCollector(), nil
+	})
+}
+
+// RuntimeCollector собирает runtime.MemStats агента, случайное значение RandomValue
+// и счётчик опросов PollCount.
+type RuntimeCollector struct{}
+
+// NewRuntimeCollector создаёт RuntimeCollector.
+func NewRuntimeCollector() *RuntimeCollector {
+	return &RuntimeCollector{}
+}
+
+// Name возвращает имя коллектора.
+func (c *RuntimeCollector) Name() string {
+	return RuntimeCollectorName
+}
+
+// Collect собирает статистику рантайма.
+func (c *RuntimeCollector) Collect(ctx context.Context) ([]model.Metrics, error) {
+	var memStats runtime.MemStats
+	runtime.ReadMemStats(&memStats)
+
+	return []model.Metrics{
+		gauge(enum.Alloc, float64(memStats.Alloc)),
+		gauge(enum.BuckHashSys, float64(memStats.BuckHashSys)),
+		gauge(enum.Frees, float64(memStats.Frees)),
+		gauge(enum.GCCPUFraction, memStats.GCCPUFraction),
+		gauge(enum.GCSys, float64(memStats.GCSys)),
+		gauge(enum.HeapAlloc, float64(memStats.HeapAlloc)),
+		gauge(enum.HeapIdle, float64(memStats.HeapIdle)),
+		gauge(enum.HeapInuse, float64(memStats.HeapInuse)),
+		gauge(enum.HeapObjects, float64(memStats.HeapObjects)),
+		gauge(enum.HeapReleased, float64(memStats.HeapReleased)),
+		gauge(enum.HeapSys, float64(memStats.HeapSys)),
+		gauge(enum.LastGC, float64(memStats.LastGC)),
+		gauge(enum.Lookups, float64(memStats.Lookups)),
+		gauge(enum.MCacheInuse, float64(memStats.MCacheInuse)),
+		gauge(enum.MCacheSys, float64(memStats.MCacheSys)),
+		gauge(enum.MSpanInuse, float64(memStats.MSpanInuse)),
+		gauge(enum.MSpanSys, float64(memStats.MSpanSys)),
+		gauge(enum.Mallocs, float64(memStats.Mallocs)),
+		gauge(enum.NextGC, float64(memStats.NextGC)),
+		gauge(enum.OtherSys, float64(memStats.OtherSys)),
+		gauge(enum.PauseTotalNs, float64(memStats.PauseTotalNs)),
+		gauge(enum.StackInuse, float64(memStats.StackInuse)),
+		gauge(enum.StackSys, float64(memStats.StackSys)),
+		gauge(enum.Sys, float64(memStats.Sys)),
+		gauge(enum.TotalAlloc, float64(memStats.TotalAlloc)),
+		gauge(enum.NumForcedGC, float64(memStats.NumForcedGC)),
+		gauge(enum.NumGC, float64(memStats.NumGC)),
+		gauge(enum.RandomValue, rand.Float64()),
+		counter(enum.PollCount, 1),
+	}, nil
+}
@@ -0,0 +1,69 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"github.com/ruslanDantsov/osmetrics-server/internal/agent/config"
+	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/model"
+	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/model/enum"
+	"github.com/shirou/gopsutil/v4/net"
+)
+
+// TCPCollectorName — имя коллектора состояний TCP-соединений.
+const TCPCollectorName = "tcp"
+
+// StateLabelName — метка с состоянием TCP-соединения.
+const StateLabelName = "state"
+
+// tcpStates — состояния TCP-соединений, для которых число соединений отправляется всегда,
+// в том числе нулевое, чтобы на сервере не оставались устаревшие значения.
+var tcpStates = []string{
+	"ESTABLISHED", "SYN_SENT", "SYN_RECV", "FIN_WAIT1", "FIN_WAIT2", "TIME_WAIT",
+	"CLOSE", "CLOSE_WAIT", "LAST_ACK", "LISTEN", "CLOSING",
+}
+
+func init() {
+	Register(TCPCollectorName, func(*config.AgentConfig) (Collector, error) {
+		return NewTCPCollector(), nil
+	})
+}
+
+// TCPCollector собирает число TCP-соединений (IPv4 и IPv6) в каждом состоянии
+// в виде gauge TCPConnections с меткой state.
+type TCPCollector struct {
+	connections func(ctx context.Context, kind string) ([]net.ConnectionStat, error)
+}
+
+// NewTCPCollector создаёт TCPCollector.
+func NewTCPCollector() *TCPCollector {
+	return &TCPCollector{connections: net.ConnectionsWithoutUidsWithContext}
+}
+
+// Name возвращает имя коллектора.
+func (c *TCPCollector) Name() string {
+	return TCPCollectorName
+}
+
+// Collect подсчитывает TCP-соединения по состояниям.
+func (c *TCPCollector) Collect(ctx context.Context) ([]model.Metrics, error) {
+	connections, err := c.connections(ctx, "tcp")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read TCP connections: %w", err)
+	}
+
+	counts := make(map[string]int, len(tcpStates))
+	for _, state := range tcpStates {
+		counts[state] = 0
+	}
+	for _, connection := range connections {
+		if connection.Status != "" {
+			counts[connection.Status]++
+		}
+	}
+
+	metrics := make([]model.Metrics, 0, len(counts))
+	for state, count := range counts {
+		metrics = append(metrics, withLabels(gauge(enum.TCPConnections, float64(count)), map[string]string{StateLabelName: state}))
+	}
+	return metrics, nil
+}
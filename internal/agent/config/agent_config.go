@@ -3,15 +3,33 @@ package config
 import (
 	"fmt"
 	"github.com/jessevdk/go-flags"
+	"github.com/ruslanDantsov/osmetrics-server/internal/agent/constants"
+	"github.com/ruslanDantsov/osmetrics-server/internal/agent/identity"
+	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/configfile"
 	"os"
+	"slices"
+	"sort"
+	"strings"
 	"time"
 )
 
 // AgentConfig содержит параметры конфигурации агента
+//
+// Значения берутся в порядке приоритета: флаги командной строки, переменные окружения,
+// JSON-файл конфигурации (см. fileConfig), значения по умолчанию.
 type AgentConfig struct {
+	// ConfigPath — путь к JSON-файлу конфигурации.
+	ConfigPath string `short:"c" long:"config" env:"CONFIG" description:"Path to JSON config file"`
+
 	// Address — адрес HTTP-сервера, к которому агент будет отправлять метрики.
 	Address string `long:"address" short:"a" env:"ADDRESS" default:"localhost:8080" description:"Address of the HTTP server"`
 
+	// Transport — способ доставки метрик на сервер: http или grpc.
+	Transport string `long:"transport" env:"TRANSPORT" default:"http" choice:"http" choice:"grpc" description:"Transport for sending metrics to the server"`
+
+	// GRPCAddress — адрес gRPC-сервера, используемый при Transport=grpc.
+	GRPCAddress string `long:"grpc-address" env:"GRPC_ADDRESS" default:"localhost:3200" description:"Address of the gRPC server"`
+
 	// ReportIntervalInSeconds — частота (в секундах), с которой агент отправляет отчёты на сервер.
 	ReportIntervalInSeconds int `long:"report" short:"r" env:"REPORT_INTERVAL" default:"10" description:"Frequency (in seconds) for sending reports to the server"`
 
@@ -33,24 +51,344 @@ type AgentConfig struct {
 	// RateLimit — количество рабочих потоков, отправляющих метрики на сервер.
 	RateLimit int `long:"rate" short:"l" env:"RATE_LIMIT" default:"2" description:"Count of workers for sending metrics to the server"`
 
-	CryptoPubKeyPath string `short:"c" long:"crypto-key" env:"CRYPTO_KEY" description:"path to public key"`
+	// ReportMode — режим отправки метрик: batch (пакетом раз в ReportInterval) или single (каждая метрика отдельно).
+	ReportMode string `long:"mode" short:"m" env:"REPORT_MODE" default:"batch" choice:"batch" choice:"single" description:"Metrics reporting mode"`
+
+	// CryptoPubKeyPath — путь к открытому ключу для шифрования отправляемых данных.
+	// Шифрование поддерживается только HTTP-транспортом.
+	CryptoPubKeyPath string `long:"crypto-key" env:"CRYPTO_KEY" description:"path to public key (HTTP transport only)"`
+
+	// AgentID — стабильный идентификатор агента. Если не задан, используется идентификатор машины
+	// из /etc/machine-id, а при его отсутствии — имя хоста.
+	AgentID string `long:"agent-id" env:"AGENT_ID" description:"Stable agent ID; defaults to the machine ID or hostname"`
+
+	// LabelPairs — статические метки, добавляемые ко всем метрикам, в виде "имя=значение".
+	LabelPairs []string `long:"label" env:"LABELS" env-delim:"," description:"Static label name=value attached to every metric (repeatable)"`
+
+	// Labels — метки, вычисляемые на основании LabelPairs, с автоматически добавленными метками host и agent.
+	Labels map[string]string `ignored:"true"`
+
+	// RetryMaxAttempts — максимальное число попыток отправки запроса на сервер, включая первую.
+	RetryMaxAttempts int `long:"retry-attempts" env:"RETRY_MAX_ATTEMPTS" default:"4" description:"Maximum number of attempts for each request to the server"`
+
+	// RetryBaseDelayInMilliseconds — задержка (в миллисекундах) перед первой повторной попыткой.
+	RetryBaseDelayInMilliseconds int `long:"retry-base-delay" env:"RETRY_BASE_DELAY" default:"1000" description:"Delay in milliseconds before the first retry"`
+
+	// RetryMaxDelayInMilliseconds — верхняя граница задержки (в миллисекундах) между попытками.
+	RetryMaxDelayInMilliseconds int `long:"retry-max-delay" env:"RETRY_MAX_DELAY" default:"30000" description:"Maximum delay in milliseconds between retries"`
+
+	// RetryBaseDelay — производное значение из RetryBaseDelayInMilliseconds в формате time.Duration.
+	RetryBaseDelay time.Duration `no:"-" description:"Derived duration from RetryBaseDelayInMilliseconds"`
+
+	// RetryMaxDelay — производное значение из RetryMaxDelayInMilliseconds в формате time.Duration.
+	RetryMaxDelay time.Duration `no:"-" description:"Derived duration from RetryMaxDelayInMilliseconds"`
+
+	// SpoolDir — каталог дисковой очереди неотправленных метрик. Пустое значение отключает очередь.
+	SpoolDir string `long:"spool-dir" env:"SPOOL_DIR" description:"Directory for the on-disk queue of unsent metrics"`
+
+	// SpoolMaxSizeBytes — максимальный объём дисковой очереди в байтах.
+	SpoolMaxSizeBytes int64 `long:"spool-max-size" env:"SPOOL_MAX_SIZE" default:"67108864" description:"Maximum size in bytes of the on-disk queue"`
+
+	// SpoolMaxAgeInSeconds — максимальный возраст (в секундах) данных в дисковой очереди.
+	SpoolMaxAgeInSeconds int `long:"spool-max-age" env:"SPOOL_MAX_AGE" default:"86400" description:"Maximum age in seconds of data in the on-disk queue"`
+
+	// SpoolMaxAge — производное значение из SpoolMaxAgeInSeconds в формате time.Duration.
+	SpoolMaxAge time.Duration `no:"-" description:"Derived duration from SpoolMaxAgeInSeconds"`
+
+	// Collectors — имена включённых коллекторов метрик.
+	Collectors []string `long:"collector" env:"COLLECTORS" env-delim:"," default:"runtime" default:"memory" default:"cpu" default:"filesystem" default:"diskio" default:"network" default:"tcp" default:"process" description:"Name of an enabled metrics collector (repeatable)"`
+
+	// CollectorIntervalPairs — интервалы опроса отдельных коллекторов в виде "имя=длительность", например "cpu=5s".
+	CollectorIntervalPairs []string `long:"collector-interval" env:"COLLECTOR_INTERVALS" env-delim:"," description:"Poll interval of a collector as name=duration, overrides --poll (repeatable)"`
+
+	// CollectorIntervals — интервалы опроса коллекторов, вычисляемые на основании CollectorIntervalPairs.
+	// Коллекторы без своего интервала опрашиваются раз в PollInterval.
+	CollectorIntervals map[string]time.Duration `ignored:"true"`
+
+	// DiskMountpointsInclude — шаблоны точек монтирования, по которым собирается заполненность.
+	// Пустой список означает все физические файловые системы.
+	DiskMountpointsInclude []string `long:"disk-mountpoint-include" env:"DISK_MOUNTPOINTS_INCLUDE" env-delim:"," description:"Mountpoint pattern to report filesystem usage for, e.g. /data/* (repeatable)"`
+
+	// DiskMountpointsExclude — шаблоны точек монтирования, исключаемые из сбора.
+	DiskMountpointsExclude []string `long:"disk-mountpoint-exclude" env:"DISK_MOUNTPOINTS_EXCLUDE" env-delim:"," description:"Mountpoint pattern to skip, e.g. /snap/* (repeatable)"`
+
+	// DiskDevicesInclude — шаблоны блочных устройств, по которым собирается ввод-вывод.
+	// Пустой список означает все устройства.
+	DiskDevicesInclude []string `long:"disk-device-include" env:"DISK_DEVICES_INCLUDE" env-delim:"," description:"Block device pattern to report I/O for, e.g. sd* (repeatable)"`
+
+	// DiskDevicesExclude — шаблоны блочных устройств, исключаемые из сбора.
+	DiskDevicesExclude []string `long:"disk-device-exclude" env:"DISK_DEVICES_EXCLUDE" env-delim:"," default:"loop*" default:"ram*" description:"Block device pattern to skip (repeatable)"`
+
+	// NetInterfacesInclude — шаблоны сетевых интерфейсов, по которым собирается трафик.
+	// Пустой список означает все интерфейсы, кроме исключённых.
+	NetInterfacesInclude []string `long:"net-interface-include" env:"NET_INTERFACES_INCLUDE" env-delim:"," description:"Network interface pattern to report traffic for, e.g. eth* (repeatable)"`
+
+	// NetInterfacesExclude — шаблоны сетевых интерфейсов, исключаемые из сбора.
+	// По умолчанию исключаются loopback и виртуальные интерфейсы контейнеров и мостов.
+	NetInterfacesExclude []string `long:"net-interface-exclude" env:"NET_INTERFACES_EXCLUDE" env-delim:"," default:"lo" default:"docker*" default:"veth*" default:"br-*" default:"virbr*" default:"vnet*" default:"cni*" default:"flannel*" default:"cali*" description:"Network interface pattern to skip (repeatable)"`
+
+	// ProcessPairs — наблюдаемые группы процессов в виде "имя=способ:значение",
+	// например "nginx=name:nginx*", "db=pidfile:/run/postgresql.pid" или "app=cgroup:system.slice/app.service".
+	ProcessPairs []string `long:"process" env:"PROCESSES" env-delim:"," description:"Watched processes as name=name:pattern, name=pidfile:path or name=cgroup:path (repeatable)"`
+
+	// ProcessWatches — группы процессов, вычисляемые на основании ProcessPairs.
+	ProcessWatches []ProcessWatch `ignored:"true"`
 }
 
-// NewAgentConfig создаёт и инициализирует конфигурацию агента,
-// используя переданные аргументы командной строки и/или переменных окружения.
-func NewAgentConfig(cliArgs []string) *AgentConfig {
-	config := &AgentConfig{}
-	parser := flags.NewParser(config, flags.Default)
+// parserOptions — опции парсера go-flags. Ошибки не печатаются, а возвращаются вызывающему коду.
+const parserOptions = flags.HelpFlag | flags.PassDoubleDash
+
+// fileConfig описывает JSON-файл конфигурации агента. Интервалы задаются строками, например "10s".
+type fileConfig struct {
+	AgentID          *string              `json:"agent_id"`
+	Address          *string              `json:"address"`
+	Transport        *string              `json:"transport"`
+	GRPCAddress      *string              `json:"grpc_address"`
+	ReportInterval   *configfile.Duration `json:"report_interval"`
+	PollInterval     *configfile.Duration `json:"poll_interval"`
+	LogLevel         *string              `json:"log_level"`
+	Key              *string              `json:"key"`
+	RateLimit        *int64               `json:"rate_limit"`
+	ReportMode       *string              `json:"report_mode"`
+	CryptoKey        *string              `json:"crypto_key"`
+	RetryMaxAttempts *int64               `json:"retry_max_attempts"`
+	RetryBaseDelay   *configfile.Duration `json:"retry_base_delay"`
+	RetryMaxDelay    *configfile.Duration `json:"retry_max_delay"`
+	SpoolDir         *string              `json:"spool_dir"`
+	SpoolMaxSize     *int64               `json:"spool_max_size"`
+	SpoolMaxAge      *configfile.Duration `json:"spool_max_age"`
+	Labels           map[string]string    `json:"labels"`
+
+	DiskMountpointsInclude []string `json:"disk_mountpoints_include"`
+	DiskMountpointsExclude []string `json:"disk_mountpoints_exclude"`
+	DiskDevicesInclude     []string `json:"disk_devices_include"`
+	DiskDevicesExclude     []string `json:"disk_devices_exclude"`
+	NetInterfacesInclude   []string `json:"net_interfaces_include"`
+	NetInterfacesExclude   []string `json:"net_interfaces_exclude"`
+
+	// Collectors включает и выключает коллекторы по имени относительно набора по умолчанию
+	// и задаёт интервалы их опроса.
+	Collectors map[string]collectorFileConfig `json:"collectors"`
+
+	// Processes задаёт наблюдаемые группы процессов по имени группы.
+	Processes map[string]processFileConfig `json:"processes"`
+}
+
+// collectorFileConfig описывает настройки коллектора в JSON-файле конфигурации.
+type collectorFileConfig struct {
+	Enabled  *bool                `json:"enabled"`
+	Interval *configfile.Duration `json:"interval"`
+}
+
+// values сопоставляет поля файла длинным именам флагов AgentConfig.
+func (f *fileConfig) values() (configfile.Values, error) {
+	values := configfile.Values{}
+	values.String("agent-id", f.AgentID)
+	values.String("address", f.Address)
+	values.String("transport", f.Transport)
+	values.String("grpc-address", f.GRPCAddress)
+	values.String("log", f.LogLevel)
+	values.String("key", f.Key)
+	values.Int("rate", f.RateLimit)
+	values.String("mode", f.ReportMode)
+	values.String("crypto-key", f.CryptoKey)
+	values.Int("retry-attempts", f.RetryMaxAttempts)
+	values.String("spool-dir", f.SpoolDir)
+	values.Int("spool-max-size", f.SpoolMaxSize)
+	values.Map("label", f.Labels)
+	values.Strings("disk-mountpoint-include", f.DiskMountpointsInclude)
+	values.Strings("disk-mountpoint-exclude", f.DiskMountpointsExclude)
+	values.Strings("disk-device-include", f.DiskDevicesInclude)
+	values.Strings("disk-device-exclude", f.DiskDevicesExclude)
+	values.Strings("net-interface-include", f.NetInterfacesInclude)
+	values.Strings("net-interface-exclude", f.NetInterfacesExclude)
+
+	durations := []struct {
+		longName string
+		value    *configfile.Duration
+		unit     time.Duration
+	}{
+		{"report", f.ReportInterval, time.Second},
+		{"poll", f.PollInterval, time.Second},
+		{"retry-base-delay", f.RetryBaseDelay, time.Millisecond},
+		{"retry-max-delay", f.RetryMaxDelay, time.Millisecond},
+		{"spool-max-age", f.SpoolMaxAge, time.Second},
+	}
+	for _, d := range durations {
+		if err := values.Duration(d.longName, d.value, d.unit); err != nil {
+			return nil, err
+		}
+	}
+
+	if f.Processes != nil {
+		processes, err := processPairs(f.Processes)
+		if err != nil {
+			return nil, err
+		}
+		values["process"] = processes
+	}
+
+	var intervals []string
+	for name, collector := range f.Collectors {
+		if collector.Interval != nil {
+			intervals = append(intervals, name+"="+time.Duration(*collector.Interval).String())
+		}
+	}
+	if intervals != nil {
+		sort.Strings(intervals)
+		values["collector-interval"] = intervals
+	}
+
+	return values, nil
+}
 
-	_, err := parser.ParseArgs(cliArgs)
+// enabledCollectors возвращает набор коллекторов defaults, в котором включены и выключены
+// коллекторы согласно файлу. Порядок коллекторов по умолчанию сохраняется,
+// дополнительно включённые добавляются в конце по алфавиту.
+func (f *fileConfig) enabledCollectors(defaults []string) []string {
+	enabled := make([]string, 0, len(defaults)+len(f.Collectors))
+	for _, name := range defaults {
+		if collector, ok := f.Collectors[name]; ok && collector.Enabled != nil && !*collector.Enabled {
+			continue
+		}
+		enabled = append(enabled, name)
+	}
+
+	var added []string
+	for name, collector := range f.Collectors {
+		if collector.Enabled != nil && *collector.Enabled && !slices.Contains(defaults, name) {
+			added = append(added, name)
+		}
+	}
+	sort.Strings(added)
+
+	return append(enabled, added...)
+}
+
+// NewAgentConfig создаёт и инициализирует конфигурацию агента,
+// используя переданные аргументы командной строки, переменные окружения и файл конфигурации.
+func NewAgentConfig(cliArgs []string) (*AgentConfig, error) {
+	config, err := parseAgentConfig(cliArgs)
 	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+		return nil, err
+	}
+
+	// Транспорт gRPC не шифрует данные, поэтому отправлять метрики открытым текстом
+	// при настроенном шифровании нельзя.
+	if config.Transport == constants.TransportGRPC && config.CryptoPubKeyPath != "" {
+		return nil, fmt.Errorf("--crypto-key is not supported with --transport=%s", constants.TransportGRPC)
 	}
 
 	// Convert seconds to durations
 	config.ReportInterval = time.Duration(config.ReportIntervalInSeconds) * time.Second
 	config.PollInterval = time.Duration(config.PollIntervalInSeconds) * time.Second
+	config.RetryBaseDelay = time.Duration(config.RetryBaseDelayInMilliseconds) * time.Millisecond
+	config.RetryMaxDelay = time.Duration(config.RetryMaxDelayInMilliseconds) * time.Millisecond
+	config.SpoolMaxAge = time.Duration(config.SpoolMaxAgeInSeconds) * time.Second
+
+	agentID, err := identity.ResolveID(config.AgentID)
+	if err != nil {
+		return nil, err
+	}
+	config.AgentID = agentID
+
+	labels, err := parseLabels(config.LabelPairs, agentID)
+	if err != nil {
+		return nil, err
+	}
+	config.Labels = labels
+
+	intervals, err := parseCollectorIntervals(config.CollectorIntervalPairs)
+	if err != nil {
+		return nil, err
+	}
+	config.CollectorIntervals = intervals
+
+	watches, err := parseProcessWatches(config.ProcessPairs)
+	if err != nil {
+		return nil, err
+	}
+	config.ProcessWatches = watches
+
+	return config, nil
+}
+
+// parseCollectorIntervals разбирает интервалы опроса коллекторов вида "имя=длительность".
+func parseCollectorIntervals(pairs []string) (map[string]time.Duration, error) {
+	intervals := make(map[string]time.Duration, len(pairs))
+	for _, pair := range pairs {
+		name, raw, found := strings.Cut(pair, "=")
+		name = strings.TrimSpace(name)
+		if !found || name == "" {
+			return nil, fmt.Errorf("invalid value for --collector-interval: %q, expected name=duration", pair)
+		}
+		interval, err := time.ParseDuration(strings.TrimSpace(raw))
+		if err != nil || interval <= 0 {
+			return nil, fmt.Errorf("invalid value for --collector-interval: %q, expected a positive duration like 5s", pair)
+		}
+		intervals[name] = interval
+	}
+	return intervals, nil
+}
+
+// parseLabels разбирает статические метки и добавляет метку agent с идентификатором агента
+// и метку host с именем хоста, если они не заданы явно и имя хоста удалось определить.
+func parseLabels(pairs []string, agentID string) (map[string]string, error) {
+	labels := make(map[string]string, len(pairs)+2)
+	for _, pair := range pairs {
+		name, value, found := strings.Cut(pair, "=")
+		name = strings.TrimSpace(name)
+		if !found || name == "" {
+			return nil, fmt.Errorf("invalid value for --label: %q, expected name=value", pair)
+		}
+		labels[name] = value
+	}
+
+	if _, ok := labels[constants.AgentLabelName]; !ok {
+		labels[constants.AgentLabelName] = agentID
+	}
+
+	if _, ok := labels[constants.HostLabelName]; !ok {
+		if hostname, err := os.Hostname(); err == nil && hostname != "" {
+			labels[constants.HostLabelName] = hostname
+		}
+	}
+
+	return labels, nil
+}
+
+// parseAgentConfig разбирает аргументы и переменные окружения. Если в них указан файл конфигурации,
+// разбор повторяется с подставленными из файла значениями по умолчанию.
+func parseAgentConfig(cliArgs []string) (*AgentConfig, error) {
+	config := &AgentConfig{}
+	if _, err := flags.NewParser(config, parserOptions).ParseArgs(cliArgs); err != nil {
+		return nil, err
+	}
+
+	if config.ConfigPath == "" {
+		return config, nil
+	}
+
+	var file fileConfig
+	if err := configfile.Load(config.ConfigPath, &file); err != nil {
+		return nil, err
+	}
+	values, err := file.values()
+	if err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", config.ConfigPath, err)
+	}
+
+	config = &AgentConfig{}
+	parser := flags.NewParser(config, parserOptions)
+	if file.Collectors != nil {
+		values["collector"] = file.enabledCollectors(parser.FindOptionByLongName("collector").Default)
+	}
+	if err := configfile.ApplyDefaults(parser, values); err != nil {
+		return nil, err
+	}
+	if _, err := parser.ParseArgs(cliArgs); err != nil {
+		return nil, err
+	}
 
-	return config
+	return config, nil
 }
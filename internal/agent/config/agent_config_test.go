@@ -2,6 +2,9 @@ package config
 
 import (
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 )
@@ -11,7 +14,7 @@ func TestAgentConfig_FromEnv(t *testing.T) {
 
 	t.Setenv("ADDRESS", expectedAddress)
 
-	config := NewAgentConfig([]string{})
+	config, _ := NewAgentConfig([]string{})
 
 	assert.Equal(t, expectedAddress, config.Address)
 
@@ -20,7 +23,7 @@ func TestAgentConfig_FromEnv(t *testing.T) {
 func TestAgentConfig_FromDefaultValue(t *testing.T) {
 	expectedAddress := "localhost:8080"
 
-	config := NewAgentConfig([]string{})
+	config, _ := NewAgentConfig([]string{})
 
 	assert.Equal(t, expectedAddress, config.Address)
 
@@ -29,8 +32,175 @@ func TestAgentConfig_FromDefaultValue(t *testing.T) {
 func TestAgentConfig_FromCommandLineArg(t *testing.T) {
 	expectedReportInterval := 20 * time.Second
 
-	config := NewAgentConfig([]string{"-r", "20"})
+	config, _ := NewAgentConfig([]string{"-r", "20"})
 
 	assert.Equal(t, expectedReportInterval, config.ReportInterval)
 
 }
+
+func TestAgentConfig_FromConfigFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agent.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{
+		"address": "localhost:9090",
+		"report_interval": "15s",
+		"poll_interval": "3s",
+		"retry_base_delay": "250ms",
+		"crypto_key": "/tmp/public.pem"
+	}`), 0600))
+
+	t.Setenv("POLL_INTERVAL", "5")
+
+	config, err := NewAgentConfig([]string{"-c", path, "-r", "30"})
+	require.NoError(t, err)
+
+	assert.Equal(t, "localhost:9090", config.Address)
+	assert.Equal(t, 30*time.Second, config.ReportInterval)
+	assert.Equal(t, 5*time.Second, config.PollInterval)
+	assert.Equal(t, 250*time.Millisecond, config.RetryBaseDelay)
+	assert.Equal(t, "/tmp/public.pem", config.CryptoPubKeyPath)
+}
+
+func TestAgentConfig_InvalidConfigFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agent.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"report_interval": "1500ms"}`), 0600))
+
+	_, err := NewAgentConfig([]string{"--config", path})
+	assert.Error(t, err)
+
+	_, err = NewAgentConfig([]string{"--config", filepath.Join(t.TempDir(), "missing.json")})
+	assert.Error(t, err)
+}
+
+func TestAgentConfig_CryptoKeyWithGRPCTransport(t *testing.T) {
+	_, err := NewAgentConfig([]string{"--transport", "grpc", "--crypto-key", "/tmp/public.pem"})
+	assert.Error(t, err)
+
+	config, err := NewAgentConfig([]string{"--transport", "http", "--crypto-key", "/tmp/public.pem"})
+	require.NoError(t, err)
+	assert.Equal(t, "/tmp/public.pem", config.CryptoPubKeyPath)
+}
+
+func TestAgentConfig_Labels(t *testing.T) {
+	config, err := NewAgentConfig([]string{"--label", "env=prod", "--label", "host=web-1", "--agent-id", "agent-7"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"env": "prod", "host": "web-1", "agent": "agent-7"}, config.Labels)
+
+	config, err = NewAgentConfig([]string{"--label", "env=prod"})
+	require.NoError(t, err)
+	hostname, _ := os.Hostname()
+	assert.Equal(t, hostname, config.Labels["host"])
+
+	_, err = NewAgentConfig([]string{"--label", "broken"})
+	assert.Error(t, err)
+}
+
+func TestAgentConfig_Collectors(t *testing.T) {
+	config, err := NewAgentConfig([]string{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"runtime", "memory", "cpu", "filesystem", "diskio", "network", "tcp", "process"}, config.Collectors)
+	assert.Empty(t, config.CollectorIntervals)
+
+	config, err = NewAgentConfig([]string{"--collector", "runtime", "--collector-interval", "runtime=500ms"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"runtime"}, config.Collectors)
+	assert.Equal(t, map[string]time.Duration{"runtime": 500 * time.Millisecond}, config.CollectorIntervals)
+
+	t.Setenv("COLLECTORS", "cpu,memory")
+	config, err = NewAgentConfig([]string{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"cpu", "memory"}, config.Collectors)
+
+	_, err = NewAgentConfig([]string{"--collector-interval", "cpu"})
+	assert.Error(t, err)
+	_, err = NewAgentConfig([]string{"--collector-interval", "cpu=-1s"})
+	assert.Error(t, err)
+}
+
+func TestAgentConfig_CollectorsFromConfigFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agent.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{
+		"collectors": {
+			"memory": {"enabled": false},
+			"cpu": {"interval": "5s"},
+			"disk": {"enabled": true, "interval": "1m"}
+		}
+	}`), 0600))
+
+	config, err := NewAgentConfig([]string{"-c", path})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"runtime", "cpu", "filesystem", "diskio", "network", "tcp", "process", "disk"}, config.Collectors)
+	assert.Equal(t, map[string]time.Duration{"cpu": 5 * time.Second, "disk": time.Minute}, config.CollectorIntervals)
+
+	config, err = NewAgentConfig([]string{"-c", path, "--collector", "runtime"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"runtime"}, config.Collectors)
+}
+
+func TestAgentConfig_DiskFilters(t *testing.T) {
+	config, err := NewAgentConfig([]string{})
+	require.NoError(t, err)
+	assert.Empty(t, config.DiskMountpointsInclude)
+	assert.Equal(t, []string{"loop*", "ram*"}, config.DiskDevicesExclude)
+	assert.Contains(t, config.NetInterfacesExclude, "lo")
+	assert.Contains(t, config.NetInterfacesExclude, "docker*")
+
+	path := filepath.Join(t.TempDir(), "agent.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{
+		"disk_mountpoints_exclude": ["/snap/*", "/boot/efi"],
+		"disk_devices_exclude": []
+	}`), 0600))
+
+	t.Setenv("DISK_DEVICES_INCLUDE", "sd*,nvme*")
+	config, err = NewAgentConfig([]string{"-c", path})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/snap/*", "/boot/efi"}, config.DiskMountpointsExclude)
+	assert.Equal(t, []string{"sd*", "nvme*"}, config.DiskDevicesInclude)
+	assert.Empty(t, config.DiskDevicesExclude)
+}
+
+func TestAgentConfig_ProcessWatches(t *testing.T) {
+	config, err := NewAgentConfig([]string{"--process", "nginx=name:nginx*", "--process", "db=pidfile:/run/postgresql.pid"})
+	require.NoError(t, err)
+	assert.Equal(t, []ProcessWatch{
+		{Name: "nginx", Selector: ProcessSelectorName, Target: "nginx*"},
+		{Name: "db", Selector: ProcessSelectorPIDFile, Target: "/run/postgresql.pid"},
+	}, config.ProcessWatches)
+
+	path := filepath.Join(t.TempDir(), "agent.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{
+		"processes": {"app": {"cgroup": "system.slice/app.service"}}
+	}`), 0600))
+	config, err = NewAgentConfig([]string{"-c", path})
+	require.NoError(t, err)
+	assert.Equal(t, []ProcessWatch{{Name: "app", Selector: ProcessSelectorCgroup, Target: "system.slice/app.service"}}, config.ProcessWatches)
+
+	for _, invalid := range []string{"nginx", "nginx=nginx", "nginx=exe:nginx", "nginx=name:[", "=name:nginx"} {
+		_, err = NewAgentConfig([]string{"--process", invalid})
+		assert.Error(t, err, invalid)
+	}
+	_, err = NewAgentConfig([]string{"--process", "a=name:x", "--process", "a=name:y"})
+	assert.Error(t, err)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{
+		"processes": {"app": {"name": "app", "pidfile": "/run/app.pid"}}
+	}`), 0600))
+	_, err = NewAgentConfig([]string{"-c", path})
+	assert.Error(t, err)
+}
+
+func TestAgentConfig_AgentID(t *testing.T) {
+	config, err := NewAgentConfig([]string{})
+	require.NoError(t, err)
+	assert.NotEmpty(t, config.AgentID)
+	assert.Equal(t, config.AgentID, config.Labels["agent"])
+
+	t.Setenv("AGENT_ID", "agent-7")
+	config, err = NewAgentConfig([]string{})
+	require.NoError(t, err)
+	assert.Equal(t, "agent-7", config.AgentID)
+
+	config, err = NewAgentConfig([]string{"--label", "agent=custom"})
+	require.NoError(t, err)
+	assert.Equal(t, "agent-7", config.AgentID)
+	assert.Equal(t, "custom", config.Labels["agent"])
+}
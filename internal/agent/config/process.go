@@ -0,0 +1,102 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Способы выбора процессов для наблюдения.
+const (
+	// ProcessSelectorName выбирает процессы, имя которых соответствует шаблону path.Match.
+	ProcessSelectorName = "name"
+
+	// ProcessSelectorPIDFile выбирает процесс, PID которого записан в файле.
+	ProcessSelectorPIDFile = "pidfile"
+
+	// ProcessSelectorCgroup выбирает все процессы cgroup; путь задаётся относительно /sys/fs/cgroup.
+	ProcessSelectorCgroup = "cgroup"
+)
+
+// ProcessWatch описывает группу наблюдаемых процессов.
+type ProcessWatch struct {
+	// Name — имя группы, под которым её метрики отправляются на сервер в метке process.
+	Name string
+
+	// Selector — способ выбора процессов: ProcessSelectorName, ProcessSelectorPIDFile или ProcessSelectorCgroup.
+	Selector string
+
+	// Target — шаблон имени, путь к pidfile или путь cgroup в зависимости от Selector.
+	Target string
+}
+
+// String возвращает описание группы в формате флага --process.
+func (w ProcessWatch) String() string {
+	return w.Name + "=" + w.Selector + ":" + w.Target
+}
+
+// processFileConfig описывает группу процессов в JSON-файле конфигурации;
+// должен быть задан ровно один способ выбора.
+type processFileConfig struct {
+	Name    *string `json:"name"`
+	PIDFile *string `json:"pidfile"`
+	Cgroup  *string `json:"cgroup"`
+}
+
+// processPairs преобразует группы процессов из файла в значения флага --process.
+func processPairs(processes map[string]processFileConfig) ([]string, error) {
+	pairs := make([]string, 0, len(processes))
+	for name, process := range processes {
+		var selectors []string
+		if process.Name != nil {
+			selectors = append(selectors, ProcessSelectorName+":"+*process.Name)
+		}
+		if process.PIDFile != nil {
+			selectors = append(selectors, ProcessSelectorPIDFile+":"+*process.PIDFile)
+		}
+		if process.Cgroup != nil {
+			selectors = append(selectors, ProcessSelectorCgroup+":"+*process.Cgroup)
+		}
+		if len(selectors) != 1 {
+			return nil, fmt.Errorf("process %s must set exactly one of name, pidfile or cgroup", name)
+		}
+		pairs = append(pairs, name+"="+selectors[0])
+	}
+	sort.Strings(pairs)
+	return pairs, nil
+}
+
+// parseProcessWatches разбирает группы процессов вида "имя=способ:значение",
+// например "nginx=name:nginx*" или "db=pidfile:/run/postgresql.pid".
+func parseProcessWatches(pairs []string) ([]ProcessWatch, error) {
+	watches := make([]ProcessWatch, 0, len(pairs))
+	seen := make(map[string]struct{}, len(pairs))
+	for _, pair := range pairs {
+		name, spec, found := strings.Cut(pair, "=")
+		name = strings.TrimSpace(name)
+		selector, target, hasSelector := strings.Cut(spec, ":")
+		if !found || name == "" || !hasSelector || target == "" {
+			return nil, fmt.Errorf("invalid value for --process: %q, expected name=selector:value", pair)
+		}
+
+		switch selector {
+		case ProcessSelectorName:
+			if _, err := path.Match(target, ""); err != nil {
+				return nil, fmt.Errorf("invalid value for --process: %q: %w", pair, err)
+			}
+		case ProcessSelectorPIDFile, ProcessSelectorCgroup:
+		default:
+			return nil, fmt.Errorf("invalid value for --process: %q, selector must be %s, %s or %s",
+				pair, ProcessSelectorName, ProcessSelectorPIDFile, ProcessSelectorCgroup)
+		}
+
+		if _, ok := seen[name]; ok {
+			return nil, fmt.Errorf("invalid value for --process: process %s is set twice", name)
+		}
+		seen[name] = struct{}{}
+
+		watches = append(watches, ProcessWatch{Name: name, Selector: selector, Target: target})
+	}
+	return watches, nil
+}
@@ -1,20 +1,47 @@
 package constants
 
-import "time"
-
 const (
-	// MaxDelayForWaitingServer определяет время задержки перед повторной попыткой подключения к серверу.
-	MaxDelayForWaitingServer = 10 * time.Second
-
-	// IncreaseDelayForWaitingServer определяет прирост задержки между попытками подключения к серверу.
-	IncreaseDelayForWaitingServer = 2 * time.Second
-
 	// ServerHealthCheckURL шаблон URL для проверки состояния сервера.
 	ServerHealthCheckURL = "http://%v/health"
 
+	// RetryAfterHeaderName — имя HTTP-заголовка, в котором сервер сообщает, когда можно повторить запрос.
+	RetryAfterHeaderName = "Retry-After"
+
 	// HashHeaderName — имя HTTP-заголовка, в котором передаётся SHA256-хеш содержимого.
 	HashHeaderName = "HashSHA256"
 
+	// HashMetadataKey — ключ gRPC-метаданных, в котором передаётся HMAC-SHA256 подпись запроса.
+	HashMetadataKey = "hashsha256"
+
+	// HashTimestampMetadataKey — ключ gRPC-метаданных со временем открытия потока (секунды Unix),
+	// которое входит в подпись потоковых вызовов.
+	HashTimestampMetadataKey = "x-hash-timestamp"
+
+	// HashNonceMetadataKey — ключ gRPC-метаданных с одноразовым значением потока,
+	// которое входит в подпись потока и каждого его сообщения.
+	HashNonceMetadataKey = "x-hash-nonce"
+
+	// RealIPHeaderName — имя HTTP-заголовка, в котором агент передаёт свой IP-адрес.
+	RealIPHeaderName = "X-Real-IP"
+
+	// RealIPMetadataKey — ключ gRPC-метаданных, в котором агент передаёт свой IP-адрес.
+	RealIPMetadataKey = "x-real-ip"
+
+	// AgentLabelName — имя метки с идентификатором агента, автоматически добавляемой ко всем метрикам.
+	AgentLabelName = "agent"
+
+	// AgentIDHeaderName — имя HTTP-заголовка, в котором агент передаёт свой идентификатор.
+	AgentIDHeaderName = "X-Agent-ID"
+
+	// AgentIDMetadataKey — ключ gRPC-метаданных, в котором агент передаёт свой идентификатор.
+	AgentIDMetadataKey = "x-agent-id"
+
+	// RegisterAgentURL шаблон URL для регистрации агента на сервере.
+	RegisterAgentURL = "http://%v/api/v1/agents"
+
+	// HostLabelName — имя метки с именем хоста агента, автоматически добавляемой ко всем метрикам.
+	HostLabelName = "host"
+
 	// MetricChannelSize определяет размер буфера канала метрик.
 	MetricChannelSize = 100
 
@@ -22,11 +49,20 @@ const (
 	UpdateMetricURL = "http://%v/update"
 
 	// UpdateMetricsURL шаблон URL для пакетного обновления метрик.
-	UpdateMetricsURL = "http://%v/updates"
+	UpdateMetricsURL = "http://%v/updates/"
 
 	// GaugeMetricType указывает тип метрики "gauge"
 	GaugeMetricType = "gauge"
 
 	// CounterMetricType указывает тип метрики "counter"
 	CounterMetricType = "counter"
+
+	// TransportGRPC — доставка метрик через gRPC-сервис сервера.
+	TransportGRPC = "grpc"
+
+	// ReportModeBatch — режим, при котором метрики накапливаются и отправляются пакетом раз в ReportInterval.
+	ReportModeBatch = "batch"
+
+	// ReportModeSingle — режим, при котором каждая собранная метрика отправляется отдельным запросом.
+	ReportModeSingle = "single"
 )
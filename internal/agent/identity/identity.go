@@ -0,0 +1,76 @@
+// Package identity определяет постоянный идентификатор агента и сведения о хосте, передаваемые при регистрации.
+package identity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/model"
+	"github.com/shirou/gopsutil/v4/host"
+	"os"
+	"runtime"
+	"strings"
+)
+
+// machineIDFiles — файлы с идентификатором машины systemd/D-Bus, сохраняющимся между перезагрузками.
+var machineIDFiles = []string{"/etc/machine-id", "/var/lib/dbus/machine-id"}
+
+// Build — сведения о сборке агента.
+type Build struct {
+	Version string
+	Commit  string
+	Date    string
+}
+
+// ResolveID возвращает идентификатор агента: configured, если он задан, иначе идентификатор
+// машины из /etc/machine-id, иначе имя хоста.
+func ResolveID(configured string) (string, error) {
+	return resolveID(configured, machineIDFiles, os.Hostname)
+}
+
+func resolveID(configured string, files []string, hostname func() (string, error)) (string, error) {
+	if id := strings.TrimSpace(configured); id != "" {
+		return id, nil
+	}
+
+	for _, name := range files {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			continue
+		}
+		if id := strings.TrimSpace(string(data)); id != "" {
+			return id, nil
+		}
+	}
+
+	name, err := hostname()
+	if err == nil && name != "" {
+		return name, nil
+	}
+	return "", errors.Join(fmt.Errorf("failed to determine agent ID: set --agent-id"), err)
+}
+
+// Info собирает сведения об агенте id и его хосте. Сведения о платформе, которые не удалось
+// прочитать, остаются пустыми.
+func Info(ctx context.Context, id string, build Build) model.AgentInfo {
+	info := model.AgentInfo{
+		ID:          id,
+		Version:     build.Version,
+		BuildCommit: build.Commit,
+		BuildDate:   build.Date,
+		OS:          runtime.GOOS,
+		Arch:        runtime.GOARCH,
+		CPUs:        runtime.NumCPU(),
+	}
+
+	if hostInfo, err := host.InfoWithContext(ctx); err == nil {
+		info.Hostname = hostInfo.Hostname
+		info.Platform = hostInfo.Platform
+		info.PlatformVersion = hostInfo.PlatformVersion
+		info.KernelVersion = hostInfo.KernelVersion
+	} else if hostname, err := os.Hostname(); err == nil {
+		info.Hostname = hostname
+	}
+
+	return info
+}
@@ -0,0 +1,60 @@
+package identity
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestResolveID(t *testing.T) {
+	dir := t.TempDir()
+	machineID := filepath.Join(dir, "machine-id")
+	require.NoError(t, os.WriteFile(machineID, []byte("4c4c4544004b\n"), 0600))
+	empty := filepath.Join(dir, "empty")
+	require.NoError(t, os.WriteFile(empty, []byte("\n"), 0600))
+
+	hostname := func() (string, error) { return "web-1", nil }
+	noHostname := func() (string, error) { return "", errors.New("no hostname") }
+	missing := filepath.Join(dir, "missing")
+
+	tests := []struct {
+		name       string
+		configured string
+		files      []string
+		hostname   func() (string, error)
+		want       string
+		wantErr    bool
+	}{
+		{name: "configured", configured: " agent-7 ", files: []string{machineID}, hostname: hostname, want: "agent-7"},
+		{name: "machine-id", files: []string{missing, empty, machineID}, hostname: hostname, want: "4c4c4544004b"},
+		{name: "hostname", files: []string{missing}, hostname: hostname, want: "web-1"},
+		{name: "nothing", files: []string{missing}, hostname: noHostname, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, err := resolveID(tt.configured, tt.files, tt.hostname)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, id)
+		})
+	}
+}
+
+func TestInfo(t *testing.T) {
+	info := Info(context.Background(), "agent-7", Build{Version: "1.2.0", Commit: "abc123", Date: "2025-09-01"})
+
+	assert.Equal(t, "agent-7", info.ID)
+	assert.Equal(t, "1.2.0", info.Version)
+	assert.Equal(t, "abc123", info.BuildCommit)
+	assert.Equal(t, runtime.GOOS, info.OS)
+	assert.Equal(t, runtime.NumCPU(), info.CPUs)
+	assert.NotEmpty(t, info.Hostname)
+}
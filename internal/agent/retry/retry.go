@@ -0,0 +1,154 @@
+// Package retry реализует политику повторных попыток для всех исходящих вызовов агента.
+package retry
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// Policy описывает политику повторных попыток: экспоненциальная задержка с джиттером,
+// ограниченная MaxDelay, и ограничение на общее число попыток.
+type Policy struct {
+	// MaxAttempts — максимальное число попыток, включая первую. Значение меньше 1 снимает ограничение.
+	MaxAttempts int
+
+	// BaseDelay — задержка перед первой повторной попыткой.
+	BaseDelay time.Duration
+
+	// MaxDelay — верхняя граница задержки между попытками, в том числе указанной сервером в Retry-After.
+	MaxDelay time.Duration
+}
+
+// retryableError помечает ошибку как временную, после которой имеет смысл повторить вызов.
+type retryableError struct {
+	err   error
+	after time.Duration
+}
+
+func (e *retryableError) Error() string {
+	return e.err.Error()
+}
+
+func (e *retryableError) Unwrap() error {
+	return e.err
+}
+
+// Retryable помечает ошибку как временную. Если after больше нуля, следующая попытка
+// будет выполнена не раньше чем через after (например, по заголовку Retry-After).
+func Retryable(err error, after time.Duration) error {
+	if err == nil {
+		return nil
+	}
+	return &retryableError{err: err, after: after}
+}
+
+// IsRetryable сообщает, имеет ли смысл повторить вызов, завершившийся ошибкой err.
+//
+// Повторяются ошибки, явно помеченные через Retryable, а также сетевые ошибки:
+// отказ в соединении, сброс соединения и таймауты.
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var re *retryableError
+	if errors.As(err, &re) {
+		return true
+	}
+
+	if errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// IsRetryableStatus сообщает, является ли HTTP-статус временной ошибкой сервера (5xx или 429).
+func IsRetryableStatus(status int) bool {
+	return status == http.StatusTooManyRequests || status >= http.StatusInternalServerError
+}
+
+// ParseRetryAfter разбирает значение заголовка Retry-After: число секунд или HTTP-дату.
+// Возвращает 0, если заголовок пуст или некорректен.
+func ParseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		if d := date.Sub(now); d > 0 {
+			return d
+		}
+	}
+
+	return 0
+}
+
+// Do выполняет op, повторяя вызов, пока он завершается временной ошибкой
+// и не исчерпано число попыток. Между попытками выдерживается задержка,
+// вычисленная политикой, либо указанная сервером через Retry-After (не больше MaxDelay).
+// Ожидание прерывается при отмене контекста.
+func (p Policy) Do(ctx context.Context, op func(ctx context.Context) error) error {
+	for attempt := 0; ; attempt++ {
+		err := op(ctx)
+		if err == nil || !IsRetryable(err) {
+			return err
+		}
+
+		if p.MaxAttempts > 0 && attempt+1 >= p.MaxAttempts {
+			return err
+		}
+
+		timer := time.NewTimer(p.retryDelay(attempt, err))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.Join(err, ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+// retryDelay возвращает задержку перед повтором после ошибки err: значение Retry-After,
+// если сервер его указал, иначе Backoff. Retry-After ограничивается MaxDelay,
+// чтобы сервер не мог остановить отправку метрик на произвольно долгое время.
+func (p Policy) retryDelay(attempt int, err error) time.Duration {
+	var re *retryableError
+	if !errors.As(err, &re) || re.after <= 0 {
+		return p.Backoff(attempt)
+	}
+	if p.MaxDelay > 0 && re.after > p.MaxDelay {
+		return p.MaxDelay
+	}
+	return re.after
+}
+
+// Backoff возвращает задержку перед повторной попыткой с номером attempt (начиная с 0):
+// экспоненциально растущая величина, ограниченная MaxDelay, половина которой случайна.
+func (p Policy) Backoff(attempt int) time.Duration {
+	delay := float64(p.BaseDelay) * math.Exp2(float64(attempt))
+	if p.MaxDelay > 0 {
+		delay = math.Min(delay, float64(p.MaxDelay))
+	}
+
+	half := int64(delay / 2)
+	if half <= 0 {
+		return time.Duration(delay)
+	}
+	return time.Duration(half + rand.Int63n(half+1))
+}
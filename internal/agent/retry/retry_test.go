@@ -0,0 +1,111 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestPolicy_Do_RetriesRetryableErrors(t *testing.T) {
+	policy := Policy{MaxAttempts: 5, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}
+
+	calls := 0
+	err := policy.Do(context.Background(), func(ctx context.Context) error {
+		calls++
+		if calls < 3 {
+			return fmt.Errorf("dial: %w", syscall.ECONNREFUSED)
+		}
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, calls)
+}
+
+func TestPolicy_Do_StopsOnPermanentError(t *testing.T) {
+	policy := Policy{MaxAttempts: 5, BaseDelay: time.Millisecond}
+
+	calls := 0
+	err := policy.Do(context.Background(), func(ctx context.Context) error {
+		calls++
+		return errors.New("bad request")
+	})
+
+	assert.Error(t, err)
+	assert.Equal(t, 1, calls)
+}
+
+func TestPolicy_Do_LimitsAttempts(t *testing.T) {
+	policy := Policy{MaxAttempts: 3, BaseDelay: time.Millisecond}
+
+	calls := 0
+	err := policy.Do(context.Background(), func(ctx context.Context) error {
+		calls++
+		return Retryable(errors.New("503"), 0)
+	})
+
+	assert.Error(t, err)
+	assert.Equal(t, 3, calls)
+}
+
+func TestPolicy_Do_StopsOnContextCancel(t *testing.T) {
+	policy := Policy{BaseDelay: time.Hour}
+	ctx, cancel := context.WithCancel(context.Background())
+
+	err := policy.Do(ctx, func(ctx context.Context) error {
+		cancel()
+		return Retryable(errors.New("503"), 0)
+	})
+
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestPolicy_RetryDelay_CapsRetryAfter(t *testing.T) {
+	policy := Policy{BaseDelay: time.Millisecond, MaxDelay: 30 * time.Second}
+
+	assert.Equal(t, 30*time.Second, policy.retryDelay(0, Retryable(errors.New("429"), time.Hour)))
+	assert.Equal(t, 5*time.Second, policy.retryDelay(0, Retryable(errors.New("429"), 5*time.Second)))
+	assert.LessOrEqual(t, policy.retryDelay(0, Retryable(errors.New("503"), 0)), time.Millisecond)
+}
+
+func TestPolicy_Do_CapsRetryAfter(t *testing.T) {
+	policy := Policy{MaxAttempts: 2, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond}
+
+	start := time.Now()
+	err := policy.Do(context.Background(), func(ctx context.Context) error {
+		return Retryable(errors.New("429"), time.Hour)
+	})
+
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestPolicy_Backoff(t *testing.T) {
+	policy := Policy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+
+	for attempt, upper := range []time.Duration{100, 200, 400, 800, 1000, 1000} {
+		delay := policy.Backoff(attempt)
+		assert.GreaterOrEqual(t, delay, upper*time.Millisecond/2)
+		assert.LessOrEqual(t, delay, upper*time.Millisecond)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, 3*time.Second, ParseRetryAfter("3", now))
+	assert.Equal(t, 10*time.Second, ParseRetryAfter(now.Add(10*time.Second).Format(http.TimeFormat), now))
+	assert.Equal(t, time.Duration(0), ParseRetryAfter("", now))
+	assert.Equal(t, time.Duration(0), ParseRetryAfter("soon", now))
+}
+
+func TestIsRetryableStatus(t *testing.T) {
+	assert.True(t, IsRetryableStatus(http.StatusTooManyRequests))
+	assert.True(t, IsRetryableStatus(http.StatusServiceUnavailable))
+	assert.False(t, IsRetryableStatus(http.StatusBadRequest))
+}
@@ -2,129 +2,148 @@ package service
 
 import (
 	"context"
-	"crypto/rsa"
 	"fmt"
-	"github.com/go-resty/resty/v2"
+	"github.com/ruslanDantsov/osmetrics-server/internal/agent/collector"
 	"github.com/ruslanDantsov/osmetrics-server/internal/agent/config"
 	"github.com/ruslanDantsov/osmetrics-server/internal/agent/constants"
-	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/crypto"
+	"github.com/ruslanDantsov/osmetrics-server/internal/agent/retry"
 	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/model"
-	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/model/enum"
-	"github.com/shirou/gopsutil/v4/cpu"
-	"github.com/shirou/gopsutil/v4/mem"
 	"go.uber.org/zap"
-	"math/rand"
-	"net/http"
-	"runtime"
 	"sync"
 )
 
-// RestClient определяет интерфейс для HTTP-клиента, совместимого с resty.
-type RestClient interface {
-	R() *resty.Request
+// Transport определяет способ доставки метрик на сервер.
+type Transport interface {
+	SendMetric(ctx context.Context, metric model.Metrics) error
+	SendBatch(ctx context.Context, metricList model.MetricsList) error
+	HealthCheck(ctx context.Context) error
+	Register(ctx context.Context, info model.AgentInfo) error
+}
+
+// Spooler определяет интерфейс дисковой очереди неотправленных метрик.
+type Spooler interface {
+	Append(batch model.MetricsList) error
+	Replay(send func(batch model.MetricsList) error) error
+	IsEmpty() bool
 }
 
 // MetricService отвечает за сбор и отправку метрик.
-// Хранит текущее состояние метрик, использует клиент для HTTP-запросов и логгер.
+// Хранит текущее состояние метрик, использует транспорт для доставки на сервер и логгер.
 type MetricService struct {
-	mu      sync.Mutex
-	log     *zap.Logger
-	client  RestClient
-	config  *config.AgentConfig
-	metrics map[enum.MetricID]interface{}
-	pubKey  *rsa.PublicKey
+	mu        sync.Mutex
+	log       *zap.Logger
+	transport Transport
+	config    *config.AgentConfig
+	metrics   map[model.MetricKey]model.Metrics
+	spool     Spooler
+	retry     retry.Policy
+	labels    map[string]string
+
+	// pending — метрики режима single, которые ждут записи в дисковую очередь.
+	// Они дописываются в очередь одним батчем в FlushPending, а не по одной.
+	pendingMu sync.Mutex
+	pending   model.MetricsList
 }
 
 // NewMetricService создает и возвращает новый экземпляр MetricService.
-func NewMetricService(log *zap.Logger, client RestClient, agentConfig *config.AgentConfig, pubKey *rsa.PublicKey) *MetricService {
+// Если spool равен nil, неотправленные метрики не сохраняются на диск.
+// Ко всем отправляемым метрикам добавляются метки из agentConfig.Labels;
+// собственные метки метрики, заданные коллектором, имеют приоритет.
+func NewMetricService(log *zap.Logger, transport Transport, agentConfig *config.AgentConfig, spool Spooler) *MetricService {
 	return &MetricService{
-		log:     log,
-		client:  client,
-		config:  agentConfig,
-		metrics: make(map[enum.MetricID]interface{}),
-		pubKey:  pubKey,
+		log:       log,
+		transport: transport,
+		config:    agentConfig,
+		metrics:   make(map[model.MetricKey]model.Metrics),
+		spool:     spool,
+		retry:     NewRetryPolicy(agentConfig),
+		labels:    agentConfig.Labels,
+	}
+}
+
+// NewRetryPolicy создаёт политику повторных попыток из конфигурации агента.
+func NewRetryPolicy(agentConfig *config.AgentConfig) retry.Policy {
+	return retry.Policy{
+		MaxAttempts: agentConfig.RetryMaxAttempts,
+		BaseDelay:   agentConfig.RetryBaseDelay,
+		MaxDelay:    agentConfig.RetryMaxDelay,
 	}
 }
 
-// CollectMetrics собирает метрики из runtime и отправляет их в канал metricChan.
-func (ms *MetricService) CollectMetrics(metricChan chan<- model.Metrics) {
-	ms.log.Info("Collecting metrics...")
-	var memStats runtime.MemStats
-	runtime.ReadMemStats(&memStats)
+// Collect собирает метрики коллектора c и отправляет их в канал metricChan.
+// Ошибка коллектора логируется, собранные им до ошибки метрики всё равно отправляются.
+func (ms *MetricService) Collect(ctx context.Context, c collector.Collector, metricChan chan<- model.Metrics) {
+	for _, metric := range ms.collect(ctx, c) {
+		metricChan <- metric
+	}
+}
+
+// Poll собирает метрики коллектора c и накапливает их во внутреннем состоянии сервиса
+// до следующей пакетной отправки.
+func (ms *MetricService) Poll(ctx context.Context, c collector.Collector) {
+	ms.accumulate(ms.collect(ctx, c))
+}
+
+func (ms *MetricService) collect(ctx context.Context, c collector.Collector) []model.Metrics {
+	ms.log.Debug("Collecting metrics...", zap.String("collector", c.Name()))
+
+	metrics, err := c.Collect(ctx)
+	if err != nil {
+		ms.log.Warn("Collector failed", zap.String("collector", c.Name()), zap.Error(err))
+	}
+	return metrics
+}
 
+// accumulate сохраняет метрики во внутреннем состоянии:
+// для gauge сохраняется последнее значение, для counter значения суммируются.
+func (ms *MetricService) accumulate(metrics []model.Metrics) {
 	ms.mu.Lock()
 	defer ms.mu.Unlock()
 
-	metrics := map[enum.MetricID]float64{
-		enum.Alloc:         float64(memStats.Alloc),
-		enum.BuckHashSys:   float64(memStats.BuckHashSys),
-		enum.Frees:         float64(memStats.Frees),
-		enum.GCCPUFraction: memStats.GCCPUFraction,
-		enum.GCSys:         float64(memStats.GCSys),
-		enum.HeapAlloc:     float64(memStats.HeapAlloc),
-		enum.HeapIdle:      float64(memStats.HeapIdle),
-		enum.HeapInuse:     float64(memStats.HeapInuse),
-		enum.HeapObjects:   float64(memStats.HeapObjects),
-		enum.HeapReleased:  float64(memStats.HeapReleased),
-		enum.HeapSys:       float64(memStats.HeapSys),
-		enum.LastGC:        float64(memStats.LastGC),
-		enum.Lookups:       float64(memStats.Lookups),
-		enum.MCacheInuse:   float64(memStats.MCacheInuse),
-		enum.MCacheSys:     float64(memStats.MCacheSys),
-		enum.MSpanInuse:    float64(memStats.MSpanInuse),
-		enum.MSpanSys:      float64(memStats.MSpanSys),
-		enum.Mallocs:       float64(memStats.Mallocs),
-		enum.NextGC:        float64(memStats.NextGC),
-		enum.OtherSys:      float64(memStats.OtherSys),
-		enum.PauseTotalNs:  float64(memStats.PauseTotalNs),
-		enum.StackInuse:    float64(memStats.StackInuse),
-		enum.StackSys:      float64(memStats.StackSys),
-		enum.Sys:           float64(memStats.Sys),
-		enum.TotalAlloc:    float64(memStats.TotalAlloc),
-		enum.NumForcedGC:   float64(memStats.NumForcedGC),
-		enum.NumGC:         float64(memStats.NumGC),
-		enum.RandomValue:   rand.Float64(),
-	}
-
-	for id, value := range metrics {
-		metricChan <- model.Metrics{
-			ID:    id,
-			MType: constants.GaugeMetricType,
-			Value: &value,
+	for _, metric := range metrics {
+		metric.Labels = ms.withAgentLabels(metric.Labels)
+		key := metric.Key()
+
+		switch metric.MType {
+		case constants.GaugeMetricType:
+			if metric.Value != nil {
+				ms.metrics[key] = metric
+			}
+		case constants.CounterMetricType:
+			if metric.Delta != nil {
+				ms.addDelta(key, metric, *metric.Delta)
+			}
 		}
 	}
+}
 
-	count := int64(1)
-	metricChan <- model.Metrics{
-		ID:    enum.PollCount,
-		MType: constants.CounterMetricType,
-		Delta: &count,
+// addDelta прибавляет delta к накопленному значению счётчика key.
+func (ms *MetricService) addDelta(key model.MetricKey, metric model.Metrics, delta int64) {
+	if current, ok := ms.metrics[key]; ok && current.Delta != nil {
+		delta += *current.Delta
 	}
+	metric.Delta = &delta
+	ms.metrics[key] = metric
 }
 
-// CollectAdditionalMetrics собирает дополнительные системные метрики,
-// включая свободную и общую память, а также количество CPU, и отправляет их в канал.
-func (ms *MetricService) CollectAdditionalMetrics(metricChan chan<- model.Metrics) {
-	ms.log.Info("Collecting additional metrics...")
-	memInfo, _ := mem.VirtualMemory()
-	cpuCount, _ := cpu.Counts(false)
-
-	ms.mu.Lock()
-	defer ms.mu.Unlock()
-
-	metrics := map[enum.MetricID]float64{
-		enum.FreeMemory:      float64(memInfo.Free),
-		enum.TotalMemory:     float64(memInfo.Total),
-		enum.CPUutilization1: float64(cpuCount),
+// withAgentLabels объединяет метки агента с метками метрики; при совпадении имён
+// приоритет у меток метрики.
+func (ms *MetricService) withAgentLabels(labels map[string]string) map[string]string {
+	if len(labels) == 0 {
+		return ms.labels
+	}
+	if len(ms.labels) == 0 {
+		return labels
 	}
 
-	for id, value := range metrics {
-		metricChan <- model.Metrics{
-			ID:    id,
-			MType: constants.GaugeMetricType,
-			Value: &value,
-		}
+	merged := make(map[string]string, len(ms.labels)+len(labels))
+	for name, value := range ms.labels {
+		merged[name] = value
 	}
+	for name, value := range labels {
+		merged[name] = value
+	}
+	return merged
 }
 
 // Worker запускает воркер, который читает метрики из канала metricChan
@@ -140,84 +159,176 @@ func (ms *MetricService) Worker(ctx context.Context, metricChan chan model.Metri
 				ms.log.Info("Metric channel closed, worker exiting")
 				return
 			}
+			metric.Labels = ms.withAgentLabels(metric.Labels)
+
+			if ms.hasBacklog() {
+				// Пока очередь не отправлена, новые значения встают за ней, чтобы сохранить порядок.
+				ms.deferMetric(metric)
+				continue
+			}
 
 			if err := ms.sendMetric(ctx, metric); err != nil {
 				ms.log.Error("Failed to send metric", zap.String("id", string(metric.ID)), zap.Error(err))
+				ms.deferMetric(metric)
 			}
 		}
 	}
 }
 
-func (ms *MetricService) sendMetric(ctx context.Context, metric model.Metrics) error {
-	url := fmt.Sprintf(constants.UpdateMetricURL, ms.config.Address)
+// hasBacklog сообщает, есть ли метрики, ожидающие отправки через дисковую очередь.
+func (ms *MetricService) hasBacklog() bool {
+	if ms.spool == nil {
+		return false
+	}
 
-	json, err := metric.MarshalJSON()
-	if err != nil {
-		return fmt.Errorf("failed to marshal metric: %w", err)
+	ms.pendingMu.Lock()
+	pending := len(ms.pending)
+	ms.pendingMu.Unlock()
+
+	return pending > 0 || !ms.spool.IsEmpty()
+}
+
+// deferMetric откладывает неотправленную метрику до следующего вызова FlushPending.
+func (ms *MetricService) deferMetric(metric model.Metrics) {
+	if ms.spool == nil {
+		return
 	}
 
-	payload, err := crypto.EncryptPayload(ms.pubKey, json)
-	if err != nil {
-		return fmt.Errorf("failed to encrypt metric: %w", err)
+	ms.pendingMu.Lock()
+	defer ms.pendingMu.Unlock()
+	ms.pending = append(ms.pending, metric)
+}
+
+// FlushPending дописывает отложенные метрики в дисковую очередь одним батчем,
+// чтобы на диск они сбрасывались один раз за интервал, а не после каждой метрики.
+func (ms *MetricService) FlushPending() {
+	if ms.spool == nil {
+		return
 	}
 
-	resp, err := ms.client.R().
-		SetHeader("Content-Type", "application/json").
-		SetBody(payload).
-		SetContext(ctx).
-		Post(url)
+	ms.pendingMu.Lock()
+	batch := ms.pending
+	ms.pending = nil
+	ms.pendingMu.Unlock()
 
-	if err != nil {
-		return err
+	if len(batch) == 0 {
+		return
+	}
+	if err := ms.spool.Append(batch); err != nil {
+		ms.log.Error("Failed to spool metrics", zap.Int("count", len(batch)), zap.Error(err))
 	}
+}
+
+func (ms *MetricService) sendMetric(ctx context.Context, metric model.Metrics) error {
+	return ms.retry.Do(ctx, func(ctx context.Context) error {
+		return ms.transport.SendMetric(ctx, metric)
+	})
+}
+
+// SendAllMetrics отправляет все накопленные метрики в виде батча на сервер.
+//
+// Если настроена дисковая очередь, сначала отправляются ранее неотправленные батчи,
+// а батч, который не удалось отправить, сохраняется в очередь. Без очереди значения
+// счётчиков обнуляются только после успешной отправки; при ошибке накопленные дельты
+// сохраняются до следующей попытки.
+func (ms *MetricService) SendAllMetrics(ctx context.Context) error {
+	metricList := ms.takeMetrics()
+
+	if ms.spool != nil && !ms.spool.IsEmpty() {
+		if err := ms.ReplaySpool(ctx); err != nil {
+			return ms.deferBatch(metricList, err)
+		}
+	}
+
+	if len(metricList) == 0 {
+		return nil
+	}
+
+	ms.log.Info("Sending batch of metrics...", zap.Int("count", len(metricList)))
 
-	if resp.StatusCode() != http.StatusOK {
-		return fmt.Errorf("bad response for SendingMetric %s: %v", metric.ID, resp.StatusCode())
+	if err := ms.sendBatch(ctx, metricList); err != nil {
+		return ms.deferBatch(metricList, err)
 	}
 
 	return nil
 }
 
-// SendAllMetrics отправляет все накопленные метрики в виде батча на сервер.
-func (ms *MetricService) SendAllMetrics() {
-	ms.log.Info("Sending batch of metrics...")
-	url := fmt.Sprintf(constants.UpdateMetricsURL, ms.config.Address)
-	var metricList []model.Metrics
+// ReplaySpool дописывает отложенные метрики в дисковую очередь (см. FlushPending)
+// и отправляет накопленные в ней батчи, если сервер отвечает на /health.
+func (ms *MetricService) ReplaySpool(ctx context.Context) error {
+	ms.FlushPending()
+	if ms.spool == nil || ms.spool.IsEmpty() {
+		return nil
+	}
+
+	if !ms.isServerHealthy(ctx) {
+		return fmt.Errorf("server is not ready, spooled metrics are kept")
+	}
+
+	ms.log.Info("Replaying spooled metrics...")
+	return ms.spool.Replay(func(batch model.MetricsList) error {
+		return ms.sendBatch(ctx, batch)
+	})
+}
 
+// takeMetrics формирует батч из накопленных метрик и обнуляет счётчики.
+func (ms *MetricService) takeMetrics() model.MetricsList {
 	ms.mu.Lock()
-	for metricID, genericValue := range ms.metrics {
-		metric := model.Metrics{
-			ID: metricID,
-		}
+	defer ms.mu.Unlock()
 
-		switch value := genericValue.(type) {
-		case float64:
-			metric.MType = constants.GaugeMetricType
-			metric.Value = &value
-		case int64:
-			metric.MType = constants.CounterMetricType
-			metric.Delta = &value
-			ms.metrics[metricID] = int64(0)
-		}
+	metricList := make(model.MetricsList, 0, len(ms.metrics))
+	for key, metric := range ms.metrics {
 		metricList = append(metricList, metric)
-	}
-	ms.mu.Unlock()
 
-	json, err := model.MetricsList(metricList).MarshalJSON()
-	if err != nil {
-		ms.log.Error("failed to marshal batch of metrics: %w", zap.Error(err))
+		if metric.MType == constants.CounterMetricType {
+			zero := int64(0)
+			metric.Delta = &zero
+			ms.metrics[key] = metric
+		}
 	}
 
-	resp, err := ms.client.R().
-		SetHeader("Content-Type", "application/json").
-		SetBody(json).
-		Post(url)
+	return metricList
+}
+
+// deferBatch сохраняет неотправленный батч в дисковую очередь, а если очередь
+// не настроена или недоступна — возвращает дельты счётчиков во внутреннее состояние.
+func (ms *MetricService) deferBatch(metricList model.MetricsList, cause error) error {
+	if len(metricList) == 0 {
+		return cause
+	}
 
-	if err != nil {
-		ms.log.Error("failed to send batch of metrics: %w", zap.Error(err))
+	if ms.spool != nil {
+		err := ms.spool.Append(metricList)
+		if err == nil {
+			return fmt.Errorf("batch of metrics is spooled: %w", cause)
+		}
+		ms.log.Error("Failed to spool batch of metrics", zap.Error(err))
 	}
 
-	if resp.StatusCode() != http.StatusOK {
-		ms.log.Error(fmt.Sprintf("bad response for batch of metrics %v", resp.StatusCode()))
+	ms.restoreCounters(metricList)
+	return cause
+}
+
+func (ms *MetricService) isServerHealthy(ctx context.Context) bool {
+	return ms.transport.HealthCheck(ctx) == nil
+}
+
+func (ms *MetricService) sendBatch(ctx context.Context, metricList model.MetricsList) error {
+	return ms.retry.Do(ctx, func(ctx context.Context) error {
+		return ms.transport.SendBatch(ctx, metricList)
+	})
+}
+
+// restoreCounters возвращает неотправленные дельты счётчиков во внутреннее состояние,
+// складывая их со значениями, накопленными за время отправки.
+func (ms *MetricService) restoreCounters(metricList model.MetricsList) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	for _, metric := range metricList {
+		if metric.MType != constants.CounterMetricType || metric.Delta == nil {
+			continue
+		}
+		ms.addDelta(metric.Key(), metric, *metric.Delta)
 	}
 }
@@ -0,0 +1,287 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/go-resty/resty/v2"
+	"github.com/ruslanDantsov/osmetrics-server/internal/agent/collector"
+	"github.com/ruslanDantsov/osmetrics-server/internal/agent/config"
+	"github.com/ruslanDantsov/osmetrics-server/internal/agent/constants"
+	"github.com/ruslanDantsov/osmetrics-server/internal/agent/spool"
+	"github.com/ruslanDantsov/osmetrics-server/internal/agent/transport"
+	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/model"
+	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/model/enum"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestMetricService(address string) *MetricService {
+	return NewMetricService(zap.NewNop(), transport.NewHTTPTransport(resty.New(), address, nil), &config.AgentConfig{RetryMaxAttempts: 1}, nil)
+}
+
+// counterDelta возвращает накопленное значение счётчика id без меток.
+func counterDelta(ms *MetricService, id enum.MetricID) int64 {
+	metric, ok := ms.metrics[model.NewMetricKey(constants.CounterMetricType, id)]
+	if !ok || metric.Delta == nil {
+		return 0
+	}
+	return *metric.Delta
+}
+
+// stubCollector возвращает заранее заданные метрики и ошибку.
+type stubCollector struct {
+	metrics []model.Metrics
+	err     error
+}
+
+func (c *stubCollector) Name() string {
+	return "stub"
+}
+
+func (c *stubCollector) Collect(context.Context) ([]model.Metrics, error) {
+	return c.metrics, c.err
+}
+
+func TestMetricService_Poll_AccumulatesCounters(t *testing.T) {
+	ms := newTestMetricService("")
+
+	ms.Poll(context.Background(), collector.NewRuntimeCollector())
+	ms.Poll(context.Background(), collector.NewRuntimeCollector())
+	ms.Poll(context.Background(), collector.NewRuntimeCollector())
+
+	assert.Equal(t, int64(3), counterDelta(ms, enum.PollCount))
+	assert.Contains(t, ms.metrics, model.NewMetricKey(constants.GaugeMetricType, enum.Alloc))
+}
+
+func TestMetricService_SendAllMetrics(t *testing.T) {
+	var received model.MetricsList
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/updates/", r.URL.Path)
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		require.NoError(t, received.UnmarshalJSON(body))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	ms := newTestMetricService(strings.TrimPrefix(server.URL, "http://"))
+	ms.Poll(context.Background(), collector.NewRuntimeCollector())
+	ms.Poll(context.Background(), collector.NewRuntimeCollector())
+
+	err := ms.SendAllMetrics(context.Background())
+	require.NoError(t, err)
+
+	var pollCount *model.Metrics
+	for i := range received {
+		if received[i].ID == enum.PollCount {
+			pollCount = &received[i]
+		}
+	}
+	require.NotNil(t, pollCount)
+	assert.Equal(t, constants.CounterMetricType, pollCount.MType)
+	assert.Equal(t, int64(2), *pollCount.Delta)
+	assert.Equal(t, int64(0), counterDelta(ms, enum.PollCount))
+}
+
+func TestMetricService_SendAllMetrics_KeepsCountersOnFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	ms := newTestMetricService(strings.TrimPrefix(server.URL, "http://"))
+	ms.Poll(context.Background(), collector.NewRuntimeCollector())
+	ms.Poll(context.Background(), collector.NewRuntimeCollector())
+
+	err := ms.SendAllMetrics(context.Background())
+	assert.Error(t, err)
+
+	ms.Poll(context.Background(), collector.NewRuntimeCollector())
+	assert.Equal(t, int64(3), counterDelta(ms, enum.PollCount))
+}
+
+func TestMetricService_SendAllMetrics_SpoolsAndReplaysOnRecovery(t *testing.T) {
+	healthy := false
+	var received []model.MetricsList
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !healthy {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if r.URL.Path == "/updates/" {
+			body, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+			var batch model.MetricsList
+			require.NoError(t, batch.UnmarshalJSON(body))
+			received = append(received, batch)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	metricSpool, err := spool.New(t.TempDir(), 0, 0, zap.NewNop())
+	require.NoError(t, err)
+
+	ms := NewMetricService(zap.NewNop(), transport.NewHTTPTransport(resty.New(), strings.TrimPrefix(server.URL, "http://"), nil),
+		&config.AgentConfig{RetryMaxAttempts: 1}, metricSpool)
+
+	ms.Poll(context.Background(), collector.NewRuntimeCollector())
+	assert.Error(t, ms.SendAllMetrics(context.Background()))
+	ms.Poll(context.Background(), collector.NewRuntimeCollector())
+	assert.Error(t, ms.SendAllMetrics(context.Background()))
+	assert.False(t, metricSpool.IsEmpty())
+
+	healthy = true
+	require.NoError(t, ms.SendAllMetrics(context.Background()))
+	assert.True(t, metricSpool.IsEmpty())
+
+	var total int64
+	for _, batch := range received {
+		for _, metric := range batch {
+			if metric.ID == enum.PollCount {
+				total += *metric.Delta
+			}
+		}
+	}
+	assert.Equal(t, int64(2), total)
+}
+
+func TestMetricService_SendAllMetrics_RetriesServerErrors(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.Header().Set(constants.RetryAfterHeaderName, "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if calls == 2 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	ms := NewMetricService(zap.NewNop(), transport.NewHTTPTransport(resty.New(), strings.TrimPrefix(server.URL, "http://"), nil), &config.AgentConfig{
+		RetryMaxAttempts: 3,
+		RetryBaseDelay:   time.Millisecond,
+	}, nil)
+	ms.Poll(context.Background(), collector.NewRuntimeCollector())
+
+	require.NoError(t, ms.SendAllMetrics(context.Background()))
+	assert.Equal(t, 3, calls)
+}
+
+func TestMetricService_SendAllMetrics_DoesNotRetryClientErrors(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	ms := NewMetricService(zap.NewNop(), transport.NewHTTPTransport(resty.New(), strings.TrimPrefix(server.URL, "http://"), nil), &config.AgentConfig{
+		RetryMaxAttempts: 3,
+		RetryBaseDelay:   time.Millisecond,
+	}, nil)
+	ms.Poll(context.Background(), collector.NewRuntimeCollector())
+
+	assert.Error(t, ms.SendAllMetrics(context.Background()))
+	assert.Equal(t, 1, calls)
+}
+
+func TestMetricService_Poll_KeepsSeriesWithDifferentLabels(t *testing.T) {
+	ms := NewMetricService(zap.NewNop(), nil, &config.AgentConfig{
+		Labels: map[string]string{"host": "web-1", "device": "agent"},
+	}, nil)
+
+	read := func(device string, delta int64) model.Metrics {
+		return model.Metrics{
+			ID:     "DiskReadBytes",
+			MType:  constants.CounterMetricType,
+			Delta:  &delta,
+			Labels: map[string]string{"device": device},
+		}
+	}
+	c := &stubCollector{metrics: []model.Metrics{read("sda", 10), read("sdb", 5)}}
+
+	ms.Poll(context.Background(), c)
+	ms.Poll(context.Background(), c)
+
+	metricList := ms.takeMetrics()
+	require.Len(t, metricList, 2)
+	deltas := make(map[string]int64)
+	for _, metric := range metricList {
+		assert.Equal(t, "web-1", metric.Labels["host"])
+		deltas[metric.Labels["device"]] = *metric.Delta
+	}
+	assert.Equal(t, map[string]int64{"sda": 20, "sdb": 10}, deltas)
+}
+
+func TestMetricService_Poll_LogsCollectorErrors(t *testing.T) {
+	core, logs := observer.New(zap.WarnLevel)
+	ms := newTestMetricService("")
+	ms.log = zap.New(core)
+
+	value := 42.0
+	ms.Poll(context.Background(), &stubCollector{
+		metrics: []model.Metrics{{ID: enum.CPUutilization(1), MType: constants.GaugeMetricType, Value: &value}},
+		err:     errors.New("no /proc/meminfo"),
+	})
+
+	metric, ok := ms.metrics[model.NewMetricKey(constants.GaugeMetricType, enum.CPUutilization(1))]
+	require.True(t, ok)
+	assert.Equal(t, 42.0, *metric.Value)
+	require.Equal(t, 1, logs.FilterMessage("Collector failed").Len())
+}
+
+// countingSpool — дисковая очередь в памяти, считающая вызовы Append.
+type countingSpool struct {
+	batches []model.MetricsList
+}
+
+func (s *countingSpool) Append(batch model.MetricsList) error {
+	s.batches = append(s.batches, batch)
+	return nil
+}
+
+func (s *countingSpool) Replay(func(batch model.MetricsList) error) error {
+	return nil
+}
+
+func (s *countingSpool) IsEmpty() bool {
+	return len(s.batches) == 0
+}
+
+func TestMetricService_Worker_SpoolsFailedMetricsOncePerFlush(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	metricSpool := &countingSpool{}
+	ms := NewMetricService(zap.NewNop(), transport.NewHTTPTransport(resty.New(), strings.TrimPrefix(server.URL, "http://"), nil),
+		&config.AgentConfig{RetryMaxAttempts: 1}, metricSpool)
+
+	metricChan := make(chan model.Metrics, 10)
+	for i := 0; i < 10; i++ {
+		delta := int64(1)
+		metricChan <- model.Metrics{ID: enum.PollCount, MType: constants.CounterMetricType, Delta: &delta}
+	}
+	close(metricChan)
+	ms.Worker(context.Background(), metricChan)
+	assert.Empty(t, metricSpool.batches)
+
+	assert.Error(t, ms.ReplaySpool(context.Background()))
+	require.Len(t, metricSpool.batches, 1)
+	assert.Len(t, metricSpool.batches[0], 10)
+}
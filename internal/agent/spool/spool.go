@@ -0,0 +1,452 @@
+// Package spool реализует дисковую очередь батчей метрик, которые не удалось доставить на сервер.
+package spool
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/ruslanDantsov/osmetrics-server/internal/agent/constants"
+	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/model"
+	"go.uber.org/zap"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// segmentExt — расширение файлов сегментов очереди.
+	segmentExt = ".seg"
+
+	// segmentMaxBytes — размер сегмента, после превышения которого начинается новый сегмент.
+	segmentMaxBytes = 1 << 20
+
+	// maxRecordBytes — максимальный размер одной записи (батча) в сегменте.
+	maxRecordBytes = 16 << 20
+
+	// quarantineExt — расширение, которое получают нечитаемые сегменты; Replay их пропускает.
+	quarantineExt = ".bad"
+)
+
+// Spool — дисковая очередь неотправленных батчей метрик.
+//
+// Батчи дописываются в сегментированный append-only лог в каталоге dir:
+// каждый сегмент — файл с JSON-записями, по одной на строку.
+// Общий объём очереди ограничен maxBytes, а возраст сегментов — maxAge;
+// при превышении ограничений самые старые сегменты удаляются.
+type Spool struct {
+	mu          sync.Mutex
+	replayMu    sync.Mutex
+	dir         string
+	maxBytes    int64
+	maxAge      time.Duration
+	log         *zap.Logger
+	current     *os.File
+	currentSize int64
+	nextSeq     uint64
+
+	// segmentCount — число сегментов на диске, ожидающих отправки; IsEmpty читает его без обращения к диску.
+	segmentCount int
+	// replayed — отправленные сегменты, которые не удалось удалить; повторно они не отправляются.
+	replayed map[string]struct{}
+	remove   func(path string) error
+	// maxRecord — максимальный размер записи; батчи большего размера разбиваются в Append.
+	maxRecord int
+}
+
+type segment struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// New создаёт очередь в каталоге dir, при необходимости создавая его.
+// Сегменты, оставшиеся от предыдущего запуска, сохраняются и будут отправлены при Replay.
+func New(dir string, maxBytes int64, maxAge time.Duration, log *zap.Logger) (*Spool, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("could not create spool directory %s: %w", dir, err)
+	}
+
+	s := &Spool{
+		dir:      dir,
+		maxBytes: maxBytes,
+		maxAge:   maxAge,
+		log:      log,
+		replayed:  make(map[string]struct{}),
+		remove:    os.Remove,
+		maxRecord: maxRecordBytes,
+	}
+
+	segments, err := s.segments()
+	if err != nil {
+		return nil, err
+	}
+	s.segmentCount = len(segments)
+	if len(segments) > 0 {
+		lastSeq, _ := parseSeq(segments[len(segments)-1].path)
+		s.nextSeq = lastSeq + 1
+		log.Info("Found unsent metrics in spool", zap.Int("segments", len(segments)))
+	}
+
+	return s, nil
+}
+
+// Append дописывает батч в конец очереди и сбрасывает его на диск.
+// Батч, запись которого превышает максимальный размер, разбивается на несколько записей.
+func (s *Spool) Append(batch model.MetricsList) error {
+	if len(batch) == 0 {
+		return nil
+	}
+
+	records, err := s.encode(batch)
+	if err != nil {
+		return err
+	}
+	data := bytes.Join(records, nil)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.current == nil || s.currentSize >= segmentMaxBytes {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := s.current.Write(data)
+	s.currentSize += int64(n)
+	if err != nil {
+		return fmt.Errorf("failed to write spool segment: %w", err)
+	}
+	if err := s.current.Sync(); err != nil {
+		return fmt.Errorf("failed to sync spool segment: %w", err)
+	}
+
+	s.enforceLimits()
+	return nil
+}
+
+// encode сериализует батч в записи очереди, деля его пополам,
+// пока каждая запись не уложится в maxRecord байт.
+func (s *Spool) encode(batch model.MetricsList) ([][]byte, error) {
+	data, err := batch.MarshalJSON()
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal spooled batch: %w", err)
+	}
+	data = append(data, '\n')
+	if len(data) <= s.maxRecord {
+		return [][]byte{data}, nil
+	}
+	if len(batch) == 1 {
+		return nil, fmt.Errorf("metric %s does not fit into a spool record of %d bytes", batch[0].ID, s.maxRecord)
+	}
+
+	half := len(batch) / 2
+	head, err := s.encode(batch[:half])
+	if err != nil {
+		return nil, err
+	}
+	tail, err := s.encode(batch[half:])
+	if err != nil {
+		return nil, err
+	}
+	return append(head, tail...), nil
+}
+
+// Replay по порядку отправляет сегменты очереди с помощью send.
+//
+// Записи внутри сегмента объединяются в один батч: для gauge остаётся последнее значение,
+// дельты counter суммируются. Отправка идёт без блокировки очереди, поэтому Append и IsEmpty
+// не ждут её завершения; новые батчи попадают в следующий сегмент.
+// Сегмент удаляется только после успешной отправки. Если удалить его не удалось,
+// сегмент запоминается как отправленный и больше не передаётся, поэтому каждая дельта
+// будет учтена сервером ровно один раз.
+// Нечитаемый сегмент переименовывается с расширением .bad и пропускается, чтобы не задерживать остальные.
+// При первой ошибке отправки она прекращается, оставшиеся сегменты сохраняются.
+func (s *Spool) Replay(send func(batch model.MetricsList) error) error {
+	s.replayMu.Lock()
+	defer s.replayMu.Unlock()
+
+	s.mu.Lock()
+	s.closeCurrent()
+	s.removeReplayed()
+	s.enforceLimits()
+	segments, err := s.segments()
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	for _, seg := range segments {
+		batch, err := s.readSegment(seg.path)
+		if errors.Is(err, os.ErrNotExist) {
+			// Сегмент удалён enforceLimits во время отправки предыдущих.
+			continue
+		}
+		if err != nil {
+			if err := s.quarantine(seg.path, err); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if len(batch) > 0 {
+			if err := send(batch); err != nil {
+				return err
+			}
+		}
+
+		s.mu.Lock()
+		s.markReplayed(seg.path)
+		s.mu.Unlock()
+		s.log.Info("Replayed spooled metrics", zap.String("segment", filepath.Base(seg.path)), zap.Int("count", len(batch)))
+	}
+
+	return nil
+}
+
+// IsEmpty сообщает, есть ли в очереди неотправленные батчи.
+func (s *Spool) IsEmpty() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.segmentCount == 0
+}
+
+// Close закрывает текущий сегмент очереди.
+func (s *Spool) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closeCurrent()
+}
+
+func (s *Spool) rotate() error {
+	s.closeCurrent()
+
+	path := filepath.Join(s.dir, fmt.Sprintf("%016d%s", s.nextSeq, segmentExt))
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create spool segment: %w", err)
+	}
+
+	s.nextSeq++
+	s.segmentCount++
+	s.current = file
+	s.currentSize = 0
+	return nil
+}
+
+// markReplayed удаляет отправленный сегмент path, а при ошибке удаления
+// исключает его из очереди до следующей попытки в removeReplayed.
+func (s *Spool) markReplayed(path string) {
+	if err := s.remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		s.log.Error("Failed to remove replayed spool segment", zap.String("segment", filepath.Base(path)), zap.Error(err))
+		s.replayed[path] = struct{}{}
+	}
+	s.refreshCount()
+}
+
+// quarantine убирает нечитаемый сегмент path из очереди, сохраняя его на диске для разбора.
+func (s *Spool) quarantine(path string, cause error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.Rename(path, path+quarantineExt); err != nil {
+		return fmt.Errorf("failed to quarantine unreadable spool segment: %w", errors.Join(cause, err))
+	}
+	s.log.Error("Quarantined unreadable spool segment", zap.String("segment", filepath.Base(path)), zap.Error(cause))
+	s.refreshCount()
+	return nil
+}
+
+// removeReplayed повторяет удаление отправленных сегментов, которые не удалось удалить ранее.
+func (s *Spool) removeReplayed() {
+	for path := range s.replayed {
+		if err := s.remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		delete(s.replayed, path)
+	}
+}
+
+// refreshCount пересчитывает сегменты на диске. Если каталог прочитать не удалось,
+// очередь считается непустой, чтобы данные не остались неотправленными.
+func (s *Spool) refreshCount() {
+	segments, err := s.segments()
+	if err != nil {
+		s.log.Error("Failed to list spool segments", zap.Error(err))
+		s.segmentCount = max(s.segmentCount, 1)
+		return
+	}
+	s.segmentCount = len(segments)
+}
+
+func (s *Spool) closeCurrent() {
+	if s.current == nil {
+		return
+	}
+	if err := s.current.Close(); err != nil {
+		s.log.Error("Failed to close spool segment", zap.Error(err))
+	}
+	s.current = nil
+	s.currentSize = 0
+}
+
+// enforceLimits удаляет сегменты старше maxAge и самые старые сегменты,
+// пока общий объём очереди превышает maxBytes.
+func (s *Spool) enforceLimits() {
+	segments, err := s.segments()
+	if err != nil {
+		s.log.Error("Failed to list spool segments", zap.Error(err))
+		s.segmentCount = max(s.segmentCount, 1)
+		return
+	}
+	defer func() { s.segmentCount = len(segments) }()
+
+	var total int64
+	for _, seg := range segments {
+		total += seg.size
+	}
+
+	for len(segments) > 0 {
+		oldest := segments[0]
+		expired := s.maxAge > 0 && time.Since(oldest.modTime) > s.maxAge
+		oversized := s.maxBytes > 0 && total > s.maxBytes
+		if !expired && !oversized {
+			break
+		}
+
+		if s.current != nil && s.current.Name() == oldest.path {
+			s.closeCurrent()
+		}
+		if err := s.remove(oldest.path); err != nil {
+			s.log.Error("Failed to drop spool segment", zap.Error(err))
+			return
+		}
+		s.log.Warn("Dropped spooled metrics",
+			zap.String("segment", filepath.Base(oldest.path)),
+			zap.Bool("expired", expired),
+			zap.Bool("oversized", oversized),
+		)
+
+		total -= oldest.size
+		segments = segments[1:]
+	}
+}
+
+func (s *Spool) segments() ([]segment, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read spool directory: %w", err)
+	}
+
+	var segments []segment
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), segmentExt) {
+			continue
+		}
+		path := filepath.Join(s.dir, entry.Name())
+		if _, ok := s.replayed[path]; ok {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		segments = append(segments, segment{
+			path:    path,
+			size:    info.Size(),
+			modTime: info.ModTime(),
+		})
+	}
+
+	sort.Slice(segments, func(i, j int) bool {
+		return segments[i].path < segments[j].path
+	})
+	return segments, nil
+}
+
+func (s *Spool) readSegment(path string) (model.MetricsList, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read spool segment: %w", err)
+	}
+
+	var batches []model.MetricsList
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), s.maxRecord)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		var batch model.MetricsList
+		if err := batch.UnmarshalJSON(line); err != nil {
+			// Неполная запись могла остаться после аварийного завершения — пропускаем её.
+			s.log.Warn("Skipping corrupted spool record", zap.String("segment", filepath.Base(path)), zap.Error(err))
+			continue
+		}
+		batches = append(batches, batch)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to scan spool segment: %w", err)
+	}
+
+	return Merge(batches...), nil
+}
+
+// Merge объединяет батчи в один с сохранением порядка первого появления метрик:
+// для gauge остаётся последнее значение, дельты counter суммируются.
+func Merge(batches ...model.MetricsList) model.MetricsList {
+	var merged model.MetricsList
+	index := make(map[model.MetricKey]int)
+
+	for _, batch := range batches {
+		for _, metric := range batch {
+			key := metric.Key()
+			i, found := index[key]
+			if !found {
+				index[key] = len(merged)
+				merged = append(merged, copyMetric(metric))
+				continue
+			}
+
+			switch metric.MType {
+			case constants.CounterMetricType:
+				if metric.Delta != nil {
+					sum := *metric.Delta
+					if merged[i].Delta != nil {
+						sum += *merged[i].Delta
+					}
+					merged[i].Delta = &sum
+				}
+			default:
+				merged[i] = copyMetric(metric)
+			}
+		}
+	}
+
+	return merged
+}
+
+func copyMetric(metric model.Metrics) model.Metrics {
+	if metric.Delta != nil {
+		delta := *metric.Delta
+		metric.Delta = &delta
+	}
+	if metric.Value != nil {
+		value := *metric.Value
+		metric.Value = &value
+	}
+	return metric
+}
+
+func parseSeq(path string) (uint64, error) {
+	return strconv.ParseUint(strings.TrimSuffix(filepath.Base(path), segmentExt), 10, 64)
+}
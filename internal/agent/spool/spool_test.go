@@ -0,0 +1,195 @@
+package spool
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/ruslanDantsov/osmetrics-server/internal/agent/constants"
+	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/model"
+	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/model/enum"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func counter(id enum.MetricID, delta int64) model.Metrics {
+	return model.Metrics{ID: id, MType: constants.CounterMetricType, Delta: &delta}
+}
+
+func gauge(id enum.MetricID, value float64) model.Metrics {
+	return model.Metrics{ID: id, MType: constants.GaugeMetricType, Value: &value}
+}
+
+func TestSpool_ReplayMergesCounters(t *testing.T) {
+	s, err := New(t.TempDir(), 0, 0, zap.NewNop())
+	require.NoError(t, err)
+
+	require.NoError(t, s.Append(model.MetricsList{counter(enum.PollCount, 2), gauge(enum.Alloc, 1)}))
+	require.NoError(t, s.Append(model.MetricsList{counter(enum.PollCount, 3), gauge(enum.Alloc, 5)}))
+	assert.False(t, s.IsEmpty())
+
+	var replayed []model.MetricsList
+	err = s.Replay(func(batch model.MetricsList) error {
+		replayed = append(replayed, batch)
+		return nil
+	})
+	require.NoError(t, err)
+
+	require.Len(t, replayed, 1)
+	require.Len(t, replayed[0], 2)
+	assert.Equal(t, int64(5), *replayed[0][0].Delta)
+	assert.Equal(t, float64(5), *replayed[0][1].Value)
+	assert.True(t, s.IsEmpty())
+}
+
+func TestSpool_ReplayKeepsSegmentsOnError(t *testing.T) {
+	dir := t.TempDir()
+	s, err := New(dir, 0, 0, zap.NewNop())
+	require.NoError(t, err)
+
+	require.NoError(t, s.Append(model.MetricsList{counter(enum.PollCount, 1)}))
+
+	err = s.Replay(func(batch model.MetricsList) error {
+		return errors.New("server is down")
+	})
+	assert.Error(t, err)
+	assert.False(t, s.IsEmpty())
+
+	// Очередь переживает перезапуск агента.
+	s.Close()
+	reopened, err := New(dir, 0, 0, zap.NewNop())
+	require.NoError(t, err)
+	require.NoError(t, reopened.Append(model.MetricsList{counter(enum.PollCount, 4)}))
+
+	var deltas []int64
+	err = reopened.Replay(func(batch model.MetricsList) error {
+		deltas = append(deltas, *batch[0].Delta)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{1, 4}, deltas)
+}
+
+func TestSpool_DropsExpiredSegments(t *testing.T) {
+	s, err := New(t.TempDir(), 0, time.Nanosecond, zap.NewNop())
+	require.NoError(t, err)
+
+	require.NoError(t, s.Append(model.MetricsList{counter(enum.PollCount, 1)}))
+	time.Sleep(time.Millisecond)
+
+	called := false
+	err = s.Replay(func(batch model.MetricsList) error {
+		called = true
+		return nil
+	})
+	require.NoError(t, err)
+	assert.False(t, called)
+	assert.True(t, s.IsEmpty())
+}
+
+func TestSpool_DropsOldestSegmentsOverSizeLimit(t *testing.T) {
+	s, err := New(t.TempDir(), 1, 0, zap.NewNop())
+	require.NoError(t, err)
+
+	require.NoError(t, s.Append(model.MetricsList{counter(enum.PollCount, 1)}))
+	assert.True(t, s.IsEmpty())
+}
+
+func TestSpool_AppendDoesNotWaitForReplay(t *testing.T) {
+	s, err := New(t.TempDir(), 0, 0, zap.NewNop())
+	require.NoError(t, err)
+
+	require.NoError(t, s.Append(model.MetricsList{counter(enum.PollCount, 1)}))
+
+	var deltas []int64
+	err = s.Replay(func(batch model.MetricsList) error {
+		deltas = append(deltas, *batch[0].Delta)
+		// Пока идёт отправка, очередь принимает новые батчи в следующий сегмент.
+		assert.False(t, s.IsEmpty())
+		return s.Append(model.MetricsList{counter(enum.PollCount, 2)})
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{1}, deltas)
+	assert.False(t, s.IsEmpty())
+
+	err = s.Replay(func(batch model.MetricsList) error {
+		deltas = append(deltas, *batch[0].Delta)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{1, 2}, deltas)
+	assert.True(t, s.IsEmpty())
+}
+
+func TestSpool_ReplayDoesNotResendUnremovedSegment(t *testing.T) {
+	s, err := New(t.TempDir(), 0, 0, zap.NewNop())
+	require.NoError(t, err)
+	s.remove = func(path string) error { return errors.New("device busy") }
+
+	require.NoError(t, s.Append(model.MetricsList{counter(enum.PollCount, 1)}))
+
+	sent := 0
+	send := func(batch model.MetricsList) error {
+		sent++
+		return nil
+	}
+	require.NoError(t, s.Replay(send))
+	require.NoError(t, s.Replay(send))
+
+	assert.Equal(t, 1, sent)
+	assert.True(t, s.IsEmpty())
+}
+
+func TestSpool_AppendSplitsOversizedBatch(t *testing.T) {
+	s, err := New(t.TempDir(), 0, 0, zap.NewNop())
+	require.NoError(t, err)
+	s.maxRecord = 200
+
+	var batch model.MetricsList
+	for i := 0; i < 20; i++ {
+		batch = append(batch, gauge(enum.MetricID(fmt.Sprintf("Gauge%d", i)), float64(i)))
+	}
+	require.NoError(t, s.Append(batch))
+
+	var replayed model.MetricsList
+	err = s.Replay(func(batch model.MetricsList) error {
+		replayed = append(replayed, batch...)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Len(t, replayed, 20)
+	assert.True(t, s.IsEmpty())
+}
+
+func TestSpool_AppendRejectsOversizedMetric(t *testing.T) {
+	s, err := New(t.TempDir(), 0, 0, zap.NewNop())
+	require.NoError(t, err)
+	s.maxRecord = 10
+
+	assert.Error(t, s.Append(model.MetricsList{gauge(enum.Alloc, 1)}))
+	assert.True(t, s.IsEmpty())
+}
+
+func TestSpool_ReplayQuarantinesUnreadableSegment(t *testing.T) {
+	dir := t.TempDir()
+	oversized := append(bytes.Repeat([]byte("x"), maxRecordBytes+1), '\n')
+	require.NoError(t, os.WriteFile(filepath.Join(dir, fmt.Sprintf("%016d%s", 0, segmentExt)), oversized, 0600))
+
+	s, err := New(dir, 0, 0, zap.NewNop())
+	require.NoError(t, err)
+	require.NoError(t, s.Append(model.MetricsList{counter(enum.PollCount, 1)}))
+
+	var deltas []int64
+	err = s.Replay(func(batch model.MetricsList) error {
+		deltas = append(deltas, *batch[0].Delta)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{1}, deltas)
+	assert.True(t, s.IsEmpty())
+	assert.FileExists(t, filepath.Join(dir, fmt.Sprintf("%016d%s%s", 0, segmentExt, quarantineExt)))
+}
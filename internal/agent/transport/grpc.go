@@ -0,0 +1,104 @@
+package transport
+
+import (
+	"context"
+	"fmt"
+	"github.com/ruslanDantsov/osmetrics-server/internal/agent/retry"
+	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/metricspb"
+	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/model"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// GRPCTransport отправляет метрики на gRPC-сервис сервера.
+type GRPCTransport struct {
+	conn   *grpc.ClientConn
+	client metricspb.MetricsClient
+	agents metricspb.AgentsClient
+	health healthpb.HealthClient
+}
+
+// NewGRPCTransport создаёт gRPC-транспорт для сервера по адресу address.
+// Соединение устанавливается лениво при первом вызове.
+func NewGRPCTransport(address string, opts ...grpc.DialOption) (*GRPCTransport, error) {
+	opts = append([]grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}, opts...)
+
+	conn, err := grpc.NewClient(address, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gRPC client: %w", err)
+	}
+
+	return &GRPCTransport{
+		conn:   conn,
+		client: metricspb.NewMetricsClient(conn),
+		agents: metricspb.NewAgentsClient(conn),
+		health: healthpb.NewHealthClient(conn),
+	}, nil
+}
+
+// SendMetric отправляет одну метрику через UpdateMetric.
+func (t *GRPCTransport) SendMetric(ctx context.Context, metric model.Metrics) error {
+	pbMetric, err := metricspb.FromModel(metric)
+	if err != nil {
+		return err
+	}
+
+	_, err = t.client.UpdateMetric(ctx, &metricspb.UpdateMetricRequest{Metric: pbMetric})
+	return classifyGRPCError(err, fmt.Sprintf("SendingMetric %s", metric.ID))
+}
+
+// SendBatch отправляет батч метрик через UpdateMetrics.
+func (t *GRPCTransport) SendBatch(ctx context.Context, metricList model.MetricsList) error {
+	pbMetrics := make([]*metricspb.Metric, 0, len(metricList))
+	for _, metric := range metricList {
+		pbMetric, err := metricspb.FromModel(metric)
+		if err != nil {
+			return err
+		}
+		pbMetrics = append(pbMetrics, pbMetric)
+	}
+
+	_, err := t.client.UpdateMetrics(ctx, &metricspb.UpdateMetricsRequest{Metrics: pbMetrics})
+	return classifyGRPCError(err, "batch of metrics")
+}
+
+// Register регистрирует агент на сервере через RegisterAgent.
+func (t *GRPCTransport) Register(ctx context.Context, info model.AgentInfo) error {
+	_, err := t.agents.RegisterAgent(ctx, &metricspb.RegisterAgentRequest{Agent: metricspb.AgentInfoFromModel(info)})
+	return classifyGRPCError(err, "agent registration")
+}
+
+// HealthCheck проверяет состояние сервера через стандартный gRPC health-сервис.
+func (t *GRPCTransport) HealthCheck(ctx context.Context) error {
+	resp, err := t.health.Check(ctx, &healthpb.HealthCheckRequest{})
+	if err != nil {
+		return classifyGRPCError(err, "health check")
+	}
+	if resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		return retry.Retryable(fmt.Errorf("server is %s", resp.GetStatus()), 0)
+	}
+	return nil
+}
+
+// Close закрывает соединение с сервером.
+func (t *GRPCTransport) Close() error {
+	return t.conn.Close()
+}
+
+// classifyGRPCError помечает ошибки Unavailable, ResourceExhausted и DeadlineExceeded как временные.
+func classifyGRPCError(err error, request string) error {
+	if err == nil {
+		return nil
+	}
+
+	wrapped := fmt.Errorf("failed to send %s: %w", request, err)
+	switch status.Code(err) {
+	case codes.Unavailable, codes.ResourceExhausted, codes.DeadlineExceeded:
+		return retry.Retryable(wrapped, 0)
+	default:
+		return wrapped
+	}
+}
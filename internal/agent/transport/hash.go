@@ -0,0 +1,104 @@
+package transport
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/ruslanDantsov/osmetrics-server/internal/agent/constants"
+	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/metricspb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+	"strconv"
+	"time"
+)
+
+// WithHashKey возвращает опции gRPC-клиента, подписывающие вызовы ключом hashKey.
+//
+// Unary-вызовы подписываются HMAC-SHA256 от детерминированно сериализованного запроса.
+// Потоковый вызов подписывается при открытии по имени метода, времени открытия (x-hash-timestamp)
+// и одноразовому значению (x-hash-nonce), а каждая отправленная метрика — в поле hash
+// по одноразовому значению, порядковому номеру сообщения и самой метрике.
+// Пустой hashKey не добавляет подписи.
+func WithHashKey(hashKey string) []grpc.DialOption {
+	if hashKey == "" {
+		return nil
+	}
+
+	unary := func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		msg, ok := req.(proto.Message)
+		if !ok {
+			return fmt.Errorf("cannot sign request of type %T", req)
+		}
+		body, err := proto.MarshalOptions{Deterministic: true}.Marshal(msg)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request for signing: %w", err)
+		}
+
+		ctx = metadata.AppendToOutgoingContext(ctx, constants.HashMetadataKey, sign(hashKey, body))
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+	stream := func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		nonce := make([]byte, 16)
+		if _, err := rand.Read(nonce); err != nil {
+			return nil, fmt.Errorf("failed to generate stream nonce: %w", err)
+		}
+		nonceHex := hex.EncodeToString(nonce)
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+		ctx = metadata.AppendToOutgoingContext(ctx,
+			constants.HashTimestampMetadataKey, timestamp,
+			constants.HashNonceMetadataKey, nonceHex,
+			constants.HashMetadataKey, sign(hashKey, []byte(method+"\n"+timestamp+"\n"+nonceHex)),
+		)
+		cs, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			return nil, err
+		}
+		return &signedClientStream{ClientStream: cs, hashKey: hashKey, nonce: nonceHex}, nil
+	}
+
+	return []grpc.DialOption{
+		grpc.WithChainUnaryInterceptor(unary),
+		grpc.WithChainStreamInterceptor(stream),
+	}
+}
+
+// signedClientStream подписывает каждую отправляемую метрику потока.
+type signedClientStream struct {
+	grpc.ClientStream
+	hashKey string
+	nonce   string
+	seq     int64
+}
+
+// SendMsg отправляет копию метрики m с заполненным полем hash.
+func (s *signedClientStream) SendMsg(m any) error {
+	metric, ok := m.(*metricspb.Metric)
+	if !ok {
+		return fmt.Errorf("cannot sign stream message of type %T", m)
+	}
+
+	signed := proto.Clone(metric).(*metricspb.Metric)
+	signed.Hash = ""
+	body, err := proto.MarshalOptions{Deterministic: true}.Marshal(signed)
+	if err != nil {
+		return fmt.Errorf("failed to marshal stream message for signing: %w", err)
+	}
+	signed.Hash = sign(s.hashKey, append([]byte(s.nonce+"\n"+strconv.FormatInt(s.seq, 10)+"\n"), body...))
+
+	if err := s.ClientStream.SendMsg(signed); err != nil {
+		return err
+	}
+	s.seq++
+	return nil
+}
+
+func sign(hashKey string, body []byte) string {
+	h := hmac.New(sha256.New, []byte(hashKey))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
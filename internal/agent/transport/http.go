@@ -0,0 +1,125 @@
+// Package transport реализует способы доставки метрик агентом на сервер.
+package transport
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"github.com/go-resty/resty/v2"
+	"github.com/ruslanDantsov/osmetrics-server/internal/agent/constants"
+	"github.com/ruslanDantsov/osmetrics-server/internal/agent/retry"
+	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/crypto"
+	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/model"
+	"net/http"
+	"time"
+)
+
+// RestClient определяет интерфейс для HTTP-клиента, совместимого с resty.
+type RestClient interface {
+	R() *resty.Request
+}
+
+// HTTPTransport отправляет метрики на HTTP API сервера с помощью resty.
+type HTTPTransport struct {
+	client  RestClient
+	address string
+	pubKey  *rsa.PublicKey
+}
+
+// NewHTTPTransport создаёт HTTP-транспорт для сервера по адресу address.
+// Если pubKey задан, тело запросов шифруется.
+func NewHTTPTransport(client RestClient, address string, pubKey *rsa.PublicKey) *HTTPTransport {
+	return &HTTPTransport{
+		client:  client,
+		address: address,
+		pubKey:  pubKey,
+	}
+}
+
+// SendMetric отправляет одну метрику на /update.
+func (t *HTTPTransport) SendMetric(ctx context.Context, metric model.Metrics) error {
+	url := fmt.Sprintf(constants.UpdateMetricURL, t.address)
+
+	json, err := metric.MarshalJSON()
+	if err != nil {
+		return fmt.Errorf("failed to marshal metric: %w", err)
+	}
+
+	return t.post(ctx, url, json, fmt.Sprintf("SendingMetric %s", metric.ID))
+}
+
+// SendBatch отправляет батч метрик на /updates/.
+func (t *HTTPTransport) SendBatch(ctx context.Context, metricList model.MetricsList) error {
+	url := fmt.Sprintf(constants.UpdateMetricsURL, t.address)
+
+	json, err := metricList.MarshalJSON()
+	if err != nil {
+		return fmt.Errorf("failed to marshal batch of metrics: %w", err)
+	}
+
+	return t.post(ctx, url, json, "batch of metrics")
+}
+
+// Register регистрирует агент на сервере запросом на /api/v1/agents.
+func (t *HTTPTransport) Register(ctx context.Context, info model.AgentInfo) error {
+	url := fmt.Sprintf(constants.RegisterAgentURL, t.address)
+
+	body, err := json.Marshal(info)
+	if err != nil {
+		return fmt.Errorf("failed to marshal agent info: %w", err)
+	}
+
+	return t.post(ctx, url, body, "agent registration")
+}
+
+// HealthCheck проверяет, что сервер отвечает на /health.
+func (t *HTTPTransport) HealthCheck(ctx context.Context) error {
+	url := fmt.Sprintf(constants.ServerHealthCheckURL, t.address)
+
+	resp, err := t.client.R().SetContext(ctx).Get(url)
+	if err != nil {
+		return err
+	}
+	return checkResponse(resp, "health check")
+}
+
+// Close освобождает ресурсы транспорта.
+func (t *HTTPTransport) Close() error {
+	return nil
+}
+
+func (t *HTTPTransport) post(ctx context.Context, url string, body []byte, request string) error {
+	payload, err := crypto.EncryptPayload(t.pubKey, body)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt %s: %w", request, err)
+	}
+
+	resp, err := t.client.R().
+		SetHeader("Content-Type", "application/json").
+		SetBody(payload).
+		SetContext(ctx).
+		Post(url)
+
+	if err != nil {
+		return fmt.Errorf("failed to send %s: %w", request, err)
+	}
+
+	return checkResponse(resp, request)
+}
+
+// checkResponse преобразует HTTP-ответ в ошибку.
+// Ответы 5xx и 429 помечаются как временные с учётом заголовка Retry-After.
+func checkResponse(resp *resty.Response, request string) error {
+	if resp.StatusCode() == http.StatusOK {
+		return nil
+	}
+
+	statusErr := fmt.Errorf("bad response for %s: %v", request, resp.StatusCode())
+	if retry.IsRetryableStatus(resp.StatusCode()) {
+		retryAfter := retry.ParseRetryAfter(resp.Header().Get(constants.RetryAfterHeaderName), time.Now())
+		return retry.Retryable(statusErr, retryAfter)
+	}
+
+	return statusErr
+}
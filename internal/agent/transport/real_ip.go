@@ -0,0 +1,66 @@
+package transport
+
+import (
+	"context"
+	"fmt"
+	"github.com/ruslanDantsov/osmetrics-server/internal/agent/constants"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"net"
+)
+
+// OutboundIP возвращает IP-адрес локального интерфейса, через который идёт трафик к серверу address.
+//
+// Для определения адреса создаётся UDP-сокет без отправки пакетов:
+// операционная система выбирает маршрут и исходный адрес при вызове connect.
+func OutboundIP(address string) (net.IP, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid server address %q: %w", address, err)
+	}
+	if host == "" {
+		host = "localhost"
+	}
+
+	conn, err := net.Dial("udp", net.JoinHostPort(host, port))
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve route to %s: %w", address, err)
+	}
+	defer conn.Close()
+
+	return conn.LocalAddr().(*net.UDPAddr).IP, nil
+}
+
+// WithRealIP возвращает опции gRPC-клиента, добавляющие адрес агента ip в метаданные x-real-ip каждого вызова.
+// Пустой ip не добавляется.
+func WithRealIP(ip string) []grpc.DialOption {
+	return withMetadata(constants.RealIPMetadataKey, ip)
+}
+
+// WithAgentID возвращает опции gRPC-клиента, добавляющие идентификатор агента id
+// в метаданные x-agent-id каждого вызова. Пустой id не добавляется.
+func WithAgentID(id string) []grpc.DialOption {
+	return withMetadata(constants.AgentIDMetadataKey, id)
+}
+
+// withMetadata возвращает опции gRPC-клиента, добавляющие пару key=value в метаданные каждого вызова.
+func withMetadata(key, value string) []grpc.DialOption {
+	withValue := func(ctx context.Context) context.Context {
+		if value == "" {
+			return ctx
+		}
+		return metadata.AppendToOutgoingContext(ctx, key, value)
+	}
+
+	unary := func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return invoker(withValue(ctx), method, req, reply, cc, opts...)
+	}
+	stream := func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return streamer(withValue(ctx), desc, cc, method, opts...)
+	}
+
+	return []grpc.DialOption{
+		grpc.WithChainUnaryInterceptor(unary),
+		grpc.WithChainStreamInterceptor(stream),
+	}
+}
@@ -0,0 +1,129 @@
+// Package configfile загружает JSON-файл конфигурации и подставляет его значения
+// в парсер go-flags в качестве значений по умолчанию.
+//
+// Так обеспечивается единый порядок приоритетов:
+// флаги командной строки > переменные окружения > файл конфигурации > значения по умолчанию.
+package configfile
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/jessevdk/go-flags"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Duration — длительность, которая в JSON записывается строкой в формате time.ParseDuration, например "10s".
+type Duration time.Duration
+
+// UnmarshalJSON разбирает длительность из JSON-строки.
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var raw string
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf("duration must be a string like \"10s\": %w", err)
+	}
+
+	parsed, err := time.ParseDuration(raw)
+	if err != nil {
+		return err
+	}
+
+	*d = Duration(parsed)
+	return nil
+}
+
+// Load читает JSON-файл path в структуру v.
+// Неизвестные поля считаются ошибкой, чтобы опечатки в конфигурации не оставались незамеченными.
+func Load(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// Values — значения из файла конфигурации, сопоставленные длинным именам флагов.
+// Для флагов-срезов значений может быть несколько.
+type Values map[string][]string
+
+// String добавляет строковое значение, если оно задано в файле.
+func (v Values) String(longName string, value *string) {
+	if value != nil {
+		v[longName] = []string{*value}
+	}
+}
+
+// Bool добавляет логическое значение, если оно задано в файле.
+func (v Values) Bool(longName string, value *bool) {
+	if value != nil {
+		v[longName] = []string{strconv.FormatBool(*value)}
+	}
+}
+
+// Int добавляет целое значение, если оно задано в файле.
+func (v Values) Int(longName string, value *int64) {
+	if value != nil {
+		v[longName] = []string{strconv.FormatInt(*value, 10)}
+	}
+}
+
+// Strings добавляет список значений флага-среза, если он задан в файле.
+func (v Values) Strings(longName string, value []string) {
+	if value != nil {
+		v[longName] = value
+	}
+}
+
+// Map добавляет пары карты в виде "ключ=значение", отсортированные по ключу, если карта задана в файле.
+func (v Values) Map(longName string, value map[string]string) {
+	if value == nil {
+		return
+	}
+
+	pairs := make([]string, 0, len(value))
+	for key, val := range value {
+		pairs = append(pairs, key+"="+val)
+	}
+	sort.Strings(pairs)
+	v[longName] = pairs
+}
+
+// Duration добавляет длительность, выраженную целым числом единиц unit (например, секунд),
+// если она задана в файле. Длительность, не кратная unit, считается ошибкой.
+func (v Values) Duration(longName string, value *Duration, unit time.Duration) error {
+	if value == nil {
+		return nil
+	}
+
+	d := time.Duration(*value)
+	if d < 0 || d%unit != 0 {
+		return fmt.Errorf("invalid value %s for %s: must be a non-negative multiple of %s", d, longName, unit)
+	}
+
+	v[longName] = []string{strconv.FormatInt(int64(d/unit), 10)}
+	return nil
+}
+
+// ApplyDefaults подставляет значения из файла конфигурации как значения по умолчанию опций парсера.
+// Вызывается до разбора аргументов, поэтому переменные окружения и флаги имеют приоритет над файлом.
+func ApplyDefaults(parser *flags.Parser, values Values) error {
+	for longName, value := range values {
+		option := parser.FindOptionByLongName(longName)
+		if option == nil {
+			return fmt.Errorf("unknown option %q in config file mapping", longName)
+		}
+		option.Default = value
+	}
+
+	return nil
+}
@@ -69,13 +69,15 @@ func LoadPrivateKey(path string) (*rsa.PrivateKey, error) {
 	return nil, fmt.Errorf("unsupported private key type: %s", block.Type)
 }
 
+// EncryptPayload шифрует data в конверт (см. SealEnvelope) и возвращает его в Base64.
+// Если ключ не задан, данные возвращаются без изменений.
 func EncryptPayload(pubKey *rsa.PublicKey, data []byte) ([]byte, error) {
 	if pubKey == nil {
 		// Ключ не задан, возвращаем оригинальные данные (JSON метрики)
 		return data, nil
 	}
 
-	encrypted, err := rsa.EncryptPKCS1v15(rand.Reader, pubKey, data)
+	encrypted, err := SealEnvelope(pubKey, data)
 	if err != nil {
 		return nil, err
 	}
@@ -85,6 +87,21 @@ func EncryptPayload(pubKey *rsa.PublicKey, data []byte) ([]byte, error) {
 	return []byte(encoded), nil
 }
 
+// DecryptPayload расшифровывает полезную нагрузку cipherData, уже декодированную из Base64.
+//
+// Конверт расшифровывается всегда. Если allowLegacy включён, данные без заголовка конверта
+// расшифровываются как RSA PKCS#1 v1.5 — в этом формате шифруют старые версии агента.
+func DecryptPayload(privKey *rsa.PrivateKey, cipherData []byte, allowLegacy bool) ([]byte, error) {
+	if IsEnvelope(cipherData) {
+		return OpenEnvelope(privKey, cipherData)
+	}
+	if !allowLegacy {
+		return nil, ErrNotEnvelope
+	}
+	return DecryptRSA(privKey, cipherData)
+}
+
+// DecryptRSA расшифровывает данные в устаревшем формате RSA PKCS#1 v1.5.
 func DecryptRSA(privKey *rsa.PrivateKey, cipherData []byte) ([]byte, error) {
 	return rsa.DecryptPKCS1v15(rand.Reader, privKey, cipherData)
 }
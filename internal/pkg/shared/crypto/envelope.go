@@ -0,0 +1,125 @@
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"github.com/pkg/errors"
+)
+
+// Формат конверта (все числа — big-endian):
+//
+//	magic "OSME" (4 байта) | версия (1 байт) | длина ключа N (2 байта) |
+//	сессионный ключ AES-256, зашифрованный RSA-OAEP/SHA-256 (N байт) |
+//	nonce AES-GCM (12 байт) | шифротекст AES-GCM с тегом
+//
+// Размер полезной нагрузки не ограничен размером RSA-ключа: RSA шифрует только сессионный ключ.
+const (
+	// EnvelopeVersion — текущая версия формата конверта.
+	EnvelopeVersion byte = 1
+
+	envelopeMagic  = "OSME"
+	sessionKeySize = 32
+	headerSize     = len(envelopeMagic) + 1 + 2
+)
+
+// ErrNotEnvelope возвращается, если данные не начинаются с заголовка конверта.
+var ErrNotEnvelope = errors.New("payload is not an encrypted envelope")
+
+// IsEnvelope сообщает, начинаются ли данные с заголовка конверта.
+func IsEnvelope(data []byte) bool {
+	return len(data) >= headerSize && string(data[:len(envelopeMagic)]) == envelopeMagic
+}
+
+// SealEnvelope шифрует data случайным сессионным ключом AES-GCM и упаковывает его,
+// зашифрованный RSA-OAEP открытым ключом pubKey, в конверт.
+func SealEnvelope(pubKey *rsa.PublicKey, data []byte) ([]byte, error) {
+	sessionKey := make([]byte, sessionKeySize)
+	if _, err := rand.Read(sessionKey); err != nil {
+		return nil, fmt.Errorf("failed to generate session key: %w", err)
+	}
+
+	wrappedKey, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, pubKey, sessionKey, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to wrap session key: %w", err)
+	}
+
+	gcm, err := newGCM(sessionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	envelope := make([]byte, 0, headerSize+len(wrappedKey)+len(nonce)+len(data)+gcm.Overhead())
+	envelope = append(envelope, envelopeMagic...)
+	envelope = append(envelope, EnvelopeVersion)
+	envelope = binary.BigEndian.AppendUint16(envelope, uint16(len(wrappedKey)))
+	envelope = append(envelope, wrappedKey...)
+	envelope = append(envelope, nonce...)
+
+	// заголовок конверта передаётся как additional data, чтобы его нельзя было подменить
+	return gcm.Seal(envelope, nonce, data, envelope[:headerSize]), nil
+}
+
+// OpenEnvelope расшифровывает конверт, созданный SealEnvelope, закрытым ключом privKey.
+func OpenEnvelope(privKey *rsa.PrivateKey, envelope []byte) ([]byte, error) {
+	if !IsEnvelope(envelope) {
+		return nil, ErrNotEnvelope
+	}
+
+	version := envelope[len(envelopeMagic)]
+	if version != EnvelopeVersion {
+		return nil, fmt.Errorf("unsupported envelope version: %d", version)
+	}
+
+	keyLen := int(binary.BigEndian.Uint16(envelope[len(envelopeMagic)+1 : headerSize]))
+	if len(envelope) < headerSize+keyLen {
+		return nil, errors.New("envelope is truncated")
+	}
+	wrappedKey := envelope[headerSize : headerSize+keyLen]
+
+	sessionKey, err := rsa.DecryptOAEP(sha256.New(), nil, privKey, wrappedKey, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap session key: %w", err)
+	}
+
+	gcm, err := newGCM(sessionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	body := envelope[headerSize+keyLen:]
+	if len(body) < gcm.NonceSize()+gcm.Overhead() {
+		return nil, errors.New("envelope is truncated")
+	}
+	nonce, ciphertext := body[:gcm.NonceSize()], body[gcm.NonceSize():]
+
+	plain, err := gcm.Open(nil, nonce, ciphertext, envelope[:headerSize])
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt envelope: %w", err)
+	}
+
+	return plain, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AES cipher: %w", err)
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AES-GCM: %w", err)
+	}
+
+	return gcm, nil
+}
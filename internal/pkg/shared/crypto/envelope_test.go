@@ -0,0 +1,58 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func newTestKey(t *testing.T) *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	return key
+}
+
+func TestEnvelope_RoundTripLargePayload(t *testing.T) {
+	key := newTestKey(t)
+	payload := bytes.Repeat([]byte(`{"id":"Alloc","type":"gauge","value":1.5},`), 1000)
+
+	encoded, err := EncryptPayload(&key.PublicKey, payload)
+	require.NoError(t, err)
+
+	cipherData, err := base64.StdEncoding.DecodeString(string(encoded))
+	require.NoError(t, err)
+
+	plain, err := DecryptPayload(key, cipherData, false)
+	require.NoError(t, err)
+	assert.Equal(t, payload, plain)
+}
+
+func TestEnvelope_TamperedCiphertextIsRejected(t *testing.T) {
+	key := newTestKey(t)
+
+	envelope, err := SealEnvelope(&key.PublicKey, []byte("payload"))
+	require.NoError(t, err)
+	envelope[len(envelope)-1] ^= 0xff
+
+	_, err = OpenEnvelope(key, envelope)
+	assert.Error(t, err)
+}
+
+func TestDecryptPayload_Legacy(t *testing.T) {
+	key := newTestKey(t)
+	payload := []byte(`{"id":"PollCount","type":"counter","delta":1}`)
+
+	legacy, err := rsa.EncryptPKCS1v15(rand.Reader, &key.PublicKey, payload)
+	require.NoError(t, err)
+
+	_, err = DecryptPayload(key, legacy, false)
+	assert.ErrorIs(t, err, ErrNotEnvelope)
+
+	plain, err := DecryptPayload(key, legacy, true)
+	require.NoError(t, err)
+	assert.Equal(t, payload, plain)
+}
@@ -0,0 +1,128 @@
+package metricspb
+
+import (
+	"fmt"
+	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/model"
+	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/model/enum"
+	"github.com/ruslanDantsov/osmetrics-server/internal/server/constants"
+)
+
+// ParseMetricType преобразует строковый тип метрики в MetricType.
+func ParseMetricType(metricType string) (MetricType, error) {
+	switch metricType {
+	case constants.GaugeMetricType:
+		return MetricType_METRIC_TYPE_GAUGE, nil
+	case constants.CounterMetricType:
+		return MetricType_METRIC_TYPE_COUNTER, nil
+	default:
+		return MetricType_METRIC_TYPE_UNSPECIFIED, fmt.Errorf("metric type %s is unsupported", metricType)
+	}
+}
+
+// ModelType возвращает строковое представление типа метрики, принятое в model.Metrics.
+func (t MetricType) ModelType() (string, error) {
+	switch t {
+	case MetricType_METRIC_TYPE_GAUGE:
+		return constants.GaugeMetricType, nil
+	case MetricType_METRIC_TYPE_COUNTER:
+		return constants.CounterMetricType, nil
+	default:
+		return "", fmt.Errorf("metric type %s is unsupported", t)
+	}
+}
+
+// FromModel преобразует model.Metrics в сообщение Metric.
+func FromModel(metric model.Metrics) (*Metric, error) {
+	metricType, err := ParseMetricType(metric.MType)
+	if err != nil {
+		return nil, err
+	}
+
+	pbMetric := &Metric{
+		Id:     string(metric.ID),
+		Type:   metricType,
+		Labels: metric.Labels,
+	}
+	if metric.Delta != nil {
+		pbMetric.Delta = *metric.Delta
+	}
+	if metric.Value != nil {
+		pbMetric.Value = *metric.Value
+	}
+
+	return pbMetric, nil
+}
+
+// ToModel преобразует сообщение Metric в model.Metrics.
+func ToModel(pbMetric *Metric) (*model.Metrics, error) {
+	if pbMetric == nil {
+		return nil, fmt.Errorf("metric is empty")
+	}
+
+	metricID, err := enum.ParseMetricID(pbMetric.GetId())
+	if err != nil {
+		return nil, err
+	}
+
+	metricType, err := pbMetric.GetType().ModelType()
+	if err != nil {
+		return nil, err
+	}
+
+	metric := &model.Metrics{
+		ID:    metricID,
+		MType: metricType,
+	}
+	if len(pbMetric.GetLabels()) > 0 {
+		metric.Labels = pbMetric.GetLabels()
+	}
+
+	switch metricType {
+	case constants.GaugeMetricType:
+		value := pbMetric.GetValue()
+		metric.Value = &value
+	case constants.CounterMetricType:
+		delta := pbMetric.GetDelta()
+		metric.Delta = &delta
+	}
+
+	return metric, nil
+}
+
+// AgentInfoFromModel преобразует model.AgentInfo в сообщение AgentInfo.
+func AgentInfoFromModel(info model.AgentInfo) *AgentInfo {
+	return &AgentInfo{
+		Id:              info.ID,
+		Version:         info.Version,
+		BuildCommit:     info.BuildCommit,
+		BuildDate:       info.BuildDate,
+		Hostname:        info.Hostname,
+		Os:              info.OS,
+		Platform:        info.Platform,
+		PlatformVersion: info.PlatformVersion,
+		KernelVersion:   info.KernelVersion,
+		Arch:            info.Arch,
+		Cpus:            int32(info.CPUs),
+	}
+}
+
+// AgentInfoToModel преобразует сообщение AgentInfo в model.AgentInfo.
+func AgentInfoToModel(pbInfo *AgentInfo) (model.AgentInfo, error) {
+	if pbInfo == nil || pbInfo.GetId() == "" {
+		return model.AgentInfo{}, fmt.Errorf("agent ID is empty")
+	}
+
+	return model.AgentInfo{
+		ID:              pbInfo.GetId(),
+		Version:         pbInfo.GetVersion(),
+		BuildCommit:     pbInfo.GetBuildCommit(),
+		BuildDate:       pbInfo.GetBuildDate(),
+		Hostname:        pbInfo.GetHostname(),
+		OS:              pbInfo.GetOs(),
+		Platform:        pbInfo.GetPlatform(),
+		PlatformVersion: pbInfo.GetPlatformVersion(),
+		KernelVersion:   pbInfo.GetKernelVersion(),
+		Arch:            pbInfo.GetArch(),
+		CPUs:            int(pbInfo.GetCpus()),
+	}, nil
+}
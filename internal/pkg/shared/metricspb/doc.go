@@ -0,0 +1,4 @@
+// Package metricspb содержит gRPC-контракт сервиса метрик и сгенерированный по нему код.
+package metricspb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative metrics.proto
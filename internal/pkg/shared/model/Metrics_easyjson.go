@@ -17,7 +17,7 @@ var (
 	_ easyjson.Marshaler
 )
 
-func easyjson50d358d1DecodeGithubComRuslanDantsovOsmetricsServerInternalModel(in *jlexer.Lexer, out *MetricsList) {
+func easyjson50d358d1DecodeGithubComRuslanDantsovOsmetricsServerInternalPkgSharedModel(in *jlexer.Lexer, out *MetricsList) {
 	isTopLevel := in.IsStart()
 	if in.IsNull() {
 		in.Skip()
@@ -45,7 +45,7 @@ func easyjson50d358d1DecodeGithubComRuslanDantsovOsmetricsServerInternalModel(in
 		in.Consumed()
 	}
 }
-func easyjson50d358d1EncodeGithubComRuslanDantsovOsmetricsServerInternalModel(out *jwriter.Writer, in MetricsList) {
+func easyjson50d358d1EncodeGithubComRuslanDantsovOsmetricsServerInternalPkgSharedModel(out *jwriter.Writer, in MetricsList) {
 	if in == nil && (out.Flags&jwriter.NilSliceAsEmpty) == 0 {
 		out.RawString("null")
 	} else {
@@ -63,27 +63,27 @@ func easyjson50d358d1EncodeGithubComRuslanDantsovOsmetricsServerInternalModel(ou
 // MarshalJSON supports json.Marshaler interface
 func (v MetricsList) MarshalJSON() ([]byte, error) {
 	w := jwriter.Writer{}
-	easyjson50d358d1EncodeGithubComRuslanDantsovOsmetricsServerInternalModel(&w, v)
+	easyjson50d358d1EncodeGithubComRuslanDantsovOsmetricsServerInternalPkgSharedModel(&w, v)
 	return w.Buffer.BuildBytes(), w.Error
 }
 
 // MarshalEasyJSON supports easyjson.Marshaler interface
 func (v MetricsList) MarshalEasyJSON(w *jwriter.Writer) {
-	easyjson50d358d1EncodeGithubComRuslanDantsovOsmetricsServerInternalModel(w, v)
+	easyjson50d358d1EncodeGithubComRuslanDantsovOsmetricsServerInternalPkgSharedModel(w, v)
 }
 
 // UnmarshalJSON supports json.Unmarshaler interface
 func (v *MetricsList) UnmarshalJSON(data []byte) error {
 	r := jlexer.Lexer{Data: data}
-	easyjson50d358d1DecodeGithubComRuslanDantsovOsmetricsServerInternalModel(&r, v)
+	easyjson50d358d1DecodeGithubComRuslanDantsovOsmetricsServerInternalPkgSharedModel(&r, v)
 	return r.Error()
 }
 
 // UnmarshalEasyJSON supports easyjson.Unmarshaler interface
 func (v *MetricsList) UnmarshalEasyJSON(l *jlexer.Lexer) {
-	easyjson50d358d1DecodeGithubComRuslanDantsovOsmetricsServerInternalModel(l, v)
+	easyjson50d358d1DecodeGithubComRuslanDantsovOsmetricsServerInternalPkgSharedModel(l, v)
 }
-func easyjson50d358d1DecodeGithubComRuslanDantsovOsmetricsServerInternalModel1(in *jlexer.Lexer, out *Metrics) {
+func easyjson50d358d1DecodeGithubComRuslanDantsovOsmetricsServerInternalPkgSharedModel1(in *jlexer.Lexer, out *Metrics) {
 	isTopLevel := in.IsStart()
 	if in.IsNull() {
 		if isTopLevel {
@@ -128,6 +128,26 @@ func easyjson50d358d1DecodeGithubComRuslanDantsovOsmetricsServerInternalModel1(i
 				}
 				*out.Value = float64(in.Float64())
 			}
+		case "labels":
+			if in.IsNull() {
+				in.Skip()
+			} else {
+				in.Delim('{')
+				if !in.IsDelim('}') {
+					out.Labels = make(map[string]string)
+				} else {
+					out.Labels = nil
+				}
+				for !in.IsDelim('}') {
+					key := string(in.String())
+					in.WantColon()
+					var v4 string
+					v4 = string(in.String())
+					(out.Labels)[key] = v4
+					in.WantComma()
+				}
+				in.Delim('}')
+			}
 		default:
 			in.SkipRecursive()
 		}
@@ -138,7 +158,7 @@ func easyjson50d358d1DecodeGithubComRuslanDantsovOsmetricsServerInternalModel1(i
 		in.Consumed()
 	}
 }
-func easyjson50d358d1EncodeGithubComRuslanDantsovOsmetricsServerInternalModel1(out *jwriter.Writer, in Metrics) {
+func easyjson50d358d1EncodeGithubComRuslanDantsovOsmetricsServerInternalPkgSharedModel1(out *jwriter.Writer, in Metrics) {
 	out.RawByte('{')
 	first := true
 	_ = first
@@ -162,29 +182,48 @@ func easyjson50d358d1EncodeGithubComRuslanDantsovOsmetricsServerInternalModel1(o
 		out.RawString(prefix)
 		out.Float64(float64(*in.Value))
 	}
+	if len(in.Labels) != 0 {
+		const prefix string = ",\"labels\":"
+		out.RawString(prefix)
+		{
+			out.RawByte('{')
+			v5First := true
+			for v5Name, v5Value := range in.Labels {
+				if v5First {
+					v5First = false
+				} else {
+					out.RawByte(',')
+				}
+				out.String(string(v5Name))
+				out.RawByte(':')
+				out.String(string(v5Value))
+			}
+			out.RawByte('}')
+		}
+	}
 	out.RawByte('}')
 }
 
 // MarshalJSON supports json.Marshaler interface
 func (v Metrics) MarshalJSON() ([]byte, error) {
 	w := jwriter.Writer{}
-	easyjson50d358d1EncodeGithubComRuslanDantsovOsmetricsServerInternalModel1(&w, v)
+	easyjson50d358d1EncodeGithubComRuslanDantsovOsmetricsServerInternalPkgSharedModel1(&w, v)
 	return w.Buffer.BuildBytes(), w.Error
 }
 
 // MarshalEasyJSON supports easyjson.Marshaler interface
 func (v Metrics) MarshalEasyJSON(w *jwriter.Writer) {
-	easyjson50d358d1EncodeGithubComRuslanDantsovOsmetricsServerInternalModel1(w, v)
+	easyjson50d358d1EncodeGithubComRuslanDantsovOsmetricsServerInternalPkgSharedModel1(w, v)
 }
 
 // UnmarshalJSON supports json.Unmarshaler interface
 func (v *Metrics) UnmarshalJSON(data []byte) error {
 	r := jlexer.Lexer{Data: data}
-	easyjson50d358d1DecodeGithubComRuslanDantsovOsmetricsServerInternalModel1(&r, v)
+	easyjson50d358d1DecodeGithubComRuslanDantsovOsmetricsServerInternalPkgSharedModel1(&r, v)
 	return r.Error()
 }
 
 // UnmarshalEasyJSON supports easyjson.Unmarshaler interface
 func (v *Metrics) UnmarshalEasyJSON(l *jlexer.Lexer) {
-	easyjson50d358d1DecodeGithubComRuslanDantsovOsmetricsServerInternalModel1(l, v)
+	easyjson50d358d1DecodeGithubComRuslanDantsovOsmetricsServerInternalPkgSharedModel1(l, v)
 }
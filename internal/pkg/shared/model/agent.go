@@ -0,0 +1,19 @@
+package model
+
+// AgentInfo — сведения об агенте и его хосте, передаваемые при регистрации на сервере.
+type AgentInfo struct {
+	// ID — стабильный идентификатор агента; совпадает со значением метки agent его метрик.
+	ID string `json:"id"`
+
+	Version     string `json:"version"`
+	BuildCommit string `json:"build_commit"`
+	BuildDate   string `json:"build_date"`
+
+	Hostname        string `json:"hostname"`
+	OS              string `json:"os"`
+	Platform        string `json:"platform,omitempty"`
+	PlatformVersion string `json:"platform_version,omitempty"`
+	KernelVersion   string `json:"kernel_version,omitempty"`
+	Arch            string `json:"arch"`
+	CPUs            int    `json:"cpus"`
+}
@@ -42,6 +42,35 @@ const (
 	TotalMemory     MetricID = "TotalMemory"
 	FreeMemory      MetricID = "FreeMemory"
 	CPUutilization1 MetricID = "CPUutilization1"
+
+	DiskTotal       MetricID = "DiskTotal"
+	DiskFree        MetricID = "DiskFree"
+	DiskUsed        MetricID = "DiskUsed"
+	DiskInodesTotal MetricID = "DiskInodesTotal"
+	DiskInodesFree  MetricID = "DiskInodesFree"
+	DiskInodesUsed  MetricID = "DiskInodesUsed"
+	DiskReadBytes   MetricID = "DiskReadBytes"
+	DiskWriteBytes  MetricID = "DiskWriteBytes"
+	DiskReadOps     MetricID = "DiskReadOps"
+	DiskWriteOps    MetricID = "DiskWriteOps"
+
+	NetBytesSent   MetricID = "NetBytesSent"
+	NetBytesRecv   MetricID = "NetBytesRecv"
+	NetPacketsSent MetricID = "NetPacketsSent"
+	NetPacketsRecv MetricID = "NetPacketsRecv"
+	NetErrorsIn    MetricID = "NetErrorsIn"
+	NetErrorsOut   MetricID = "NetErrorsOut"
+	NetDropsIn     MetricID = "NetDropsIn"
+	NetDropsOut    MetricID = "NetDropsOut"
+	TCPConnections MetricID = "TCPConnections"
+
+	ProcessUp            MetricID = "process_up"
+	ProcessCount         MetricID = "process_count"
+	ProcessCPUPercent    MetricID = "process_cpu_percent"
+	ProcessRSSBytes      MetricID = "process_rss_bytes"
+	ProcessOpenFDs       MetricID = "process_open_fds"
+	ProcessThreads       MetricID = "process_threads"
+	ProcessUptimeSeconds MetricID = "process_uptime_seconds"
 )
 
 var validMetricIDs = map[MetricID]struct{}{
@@ -77,6 +106,33 @@ var validMetricIDs = map[MetricID]struct{}{
 	TotalMemory:     {},
 	FreeMemory:      {},
 	CPUutilization1: {},
+	DiskTotal:       {},
+	DiskFree:        {},
+	DiskUsed:        {},
+	DiskInodesTotal: {},
+	DiskInodesFree:  {},
+	DiskInodesUsed:  {},
+	DiskReadBytes:   {},
+	DiskWriteBytes:  {},
+	DiskReadOps:     {},
+	DiskWriteOps:    {},
+	NetBytesSent:    {},
+	NetBytesRecv:    {},
+	NetPacketsSent:  {},
+	NetPacketsRecv:  {},
+	NetErrorsIn:     {},
+	NetErrorsOut:    {},
+	NetDropsIn:      {},
+	NetDropsOut:     {},
+	TCPConnections:  {},
+
+	ProcessUp:            {},
+	ProcessCount:         {},
+	ProcessCPUPercent:    {},
+	ProcessRSSBytes:      {},
+	ProcessOpenFDs:       {},
+	ProcessThreads:       {},
+	ProcessUptimeSeconds: {},
 }
 
 // IsValid проверяет, является ли идентификатор метрики допустимым.
@@ -105,6 +161,12 @@ func (m *MetricID) String() string {
 	return string(*m)
 }
 
+// CPUutilization возвращает идентификатор метрики загрузки ядра CPU с номером core (нумерация с 1),
+// например CPUutilization1.
+func CPUutilization(core int) MetricID {
+	return MetricID(fmt.Sprintf("CPUutilization%d", core))
+}
+
 // ParseMetricID возвращает MetricID, созданный из переданной строки,
 // или ошибку, если строка пустая.
 func ParseMetricID(s string) (MetricID, error) {
@@ -0,0 +1,37 @@
+package model
+
+import "github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/model/enum"
+
+// MetricKey идентифицирует серию метрики в хранилище: тип, имя и набор меток.
+// gauge и counter с одинаковым именем, как и значения с разными метками, являются разными сериями.
+type MetricKey struct {
+	MType string
+	ID    enum.MetricID
+
+	// Labels — метки в каноническом виде (см. CanonicalLabels); пустая строка означает отсутствие меток.
+	Labels string
+}
+
+// NewMetricKey создаёт ключ метрики без меток с типом metricType и именем metricID.
+func NewMetricKey(metricType string, metricID enum.MetricID) MetricKey {
+	return MetricKey{MType: metricType, ID: metricID}
+}
+
+// WithLabels возвращает копию ключа с метками labels.
+func (k MetricKey) WithLabels(labels map[string]string) MetricKey {
+	k.Labels = CanonicalLabels(labels)
+	return k
+}
+
+// String возвращает ключ в виде "тип/имя" или "тип/имя{метки}".
+func (k MetricKey) String() string {
+	if k.Labels == "" {
+		return k.MType + "/" + string(k.ID)
+	}
+	return k.MType + "/" + string(k.ID) + "{" + k.Labels + "}"
+}
+
+// Key возвращает ключ метрики в хранилище.
+func (m *Metrics) Key() MetricKey {
+	return NewMetricKey(m.MType, m.ID).WithLabels(m.Labels)
+}
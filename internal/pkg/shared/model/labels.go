@@ -0,0 +1,170 @@
+package model
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// CanonicalLabels возвращает метки в каноническом виде `имя="значение",...`, отсортированными по имени.
+// Одинаковые наборы меток всегда дают одинаковую строку, поэтому она используется как часть ключа метрики.
+func CanonicalLabels(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for i, name := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(name)
+		b.WriteByte('=')
+		b.WriteString(strconv.Quote(labels[name]))
+	}
+	return b.String()
+}
+
+// ParseCanonicalLabels разбирает строку, полученную из CanonicalLabels.
+func ParseCanonicalLabels(s string) (map[string]string, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	labels := make(map[string]string)
+	for s != "" {
+		eq := strings.IndexByte(s, '=')
+		if eq <= 0 {
+			return nil, fmt.Errorf("invalid labels %q", s)
+		}
+		name := s[:eq]
+
+		quoted, err := strconv.QuotedPrefix(s[eq+1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid value of label %s: %w", name, err)
+		}
+		value, err := strconv.Unquote(quoted)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value of label %s: %w", name, err)
+		}
+		labels[name] = value
+
+		s = s[eq+1+len(quoted):]
+		if s != "" {
+			if s[0] != ',' {
+				return nil, fmt.Errorf("invalid labels separator %q", s[0])
+			}
+			s = s[1:]
+		}
+	}
+
+	return labels, nil
+}
+
+// MatchType — способ сравнения значения метки в LabelMatcher.
+type MatchType string
+
+// Поддерживаемые способы сравнения меток.
+const (
+	MatchEqual     MatchType = "="
+	MatchNotEqual  MatchType = "!="
+	MatchRegexp    MatchType = "=~"
+	MatchNotRegexp MatchType = "!~"
+)
+
+// LabelMatcher — условие на значение одной метки. Отсутствующая метка считается пустой строкой.
+type LabelMatcher struct {
+	Name  string
+	Type  MatchType
+	Value string
+
+	re *regexp.Regexp
+}
+
+// ParseLabelMatcher разбирает условие вида `имя=значение`, `имя!=значение`,
+// `имя=~регулярное_выражение` или `имя!~регулярное_выражение`.
+// Регулярное выражение должно совпадать со значением метки целиком.
+func ParseLabelMatcher(s string) (*LabelMatcher, error) {
+	eq := strings.IndexByte(s, '=')
+	bang := strings.IndexByte(s, '!')
+
+	var name, rest string
+	var matchType MatchType
+	switch {
+	case bang > 0 && (eq < 0 || bang < eq) && len(s) > bang+1 && (s[bang+1] == '=' || s[bang+1] == '~'):
+		name, matchType, rest = s[:bang], MatchType(s[bang:bang+2]), s[bang+2:]
+	case eq > 0 && len(s) > eq+1 && s[eq+1] == '~':
+		name, matchType, rest = s[:eq], MatchRegexp, s[eq+2:]
+	case eq > 0:
+		name, matchType, rest = s[:eq], MatchEqual, s[eq+1:]
+	default:
+		return nil, fmt.Errorf("invalid label matcher %q", s)
+	}
+
+	matcher := &LabelMatcher{Name: strings.TrimSpace(name), Type: matchType, Value: rest}
+	if matcher.Name == "" {
+		return nil, fmt.Errorf("invalid label matcher %q: empty label name", s)
+	}
+
+	if matchType == MatchRegexp || matchType == MatchNotRegexp {
+		re, err := regexp.Compile("^(?:" + rest + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid label matcher %q: %w", s, err)
+		}
+		matcher.re = re
+	}
+
+	return matcher, nil
+}
+
+// Matches сообщает, удовлетворяют ли метки labels условию.
+func (m *LabelMatcher) Matches(labels map[string]string) bool {
+	value := labels[m.Name]
+
+	switch m.Type {
+	case MatchEqual:
+		return value == m.Value
+	case MatchNotEqual:
+		return value != m.Value
+	case MatchRegexp:
+		return m.re.MatchString(value)
+	case MatchNotRegexp:
+		return !m.re.MatchString(value)
+	default:
+		return false
+	}
+}
+
+// LabelMatchers — набор условий, которые должны выполняться одновременно.
+type LabelMatchers []*LabelMatcher
+
+// ParseLabelMatchers разбирает список условий (см. ParseLabelMatcher).
+func ParseLabelMatchers(raw []string) (LabelMatchers, error) {
+	matchers := make(LabelMatchers, 0, len(raw))
+	for _, s := range raw {
+		matcher, err := ParseLabelMatcher(s)
+		if err != nil {
+			return nil, err
+		}
+		matchers = append(matchers, matcher)
+	}
+	return matchers, nil
+}
+
+// Matches сообщает, удовлетворяют ли метки labels всем условиям набора.
+func (ms LabelMatchers) Matches(labels map[string]string) bool {
+	for _, m := range ms {
+		if !m.Matches(labels) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,59 @@
+package model
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestCanonicalLabels_RoundTrip(t *testing.T) {
+	labels := map[string]string{"service": "api", "host": "web-1", "note": `a "quoted", value`}
+
+	canonical := CanonicalLabels(labels)
+	assert.Equal(t, `host="web-1",note="a \"quoted\", value",service="api"`, canonical)
+
+	parsed, err := ParseCanonicalLabels(canonical)
+	require.NoError(t, err)
+	assert.Equal(t, labels, parsed)
+}
+
+func TestMetrics_Key_DependsOnLabels(t *testing.T) {
+	web1 := Metrics{ID: "Alloc", MType: "gauge", Labels: map[string]string{"host": "web-1"}}
+	web2 := Metrics{ID: "Alloc", MType: "gauge", Labels: map[string]string{"host": "web-2"}}
+	plain := Metrics{ID: "Alloc", MType: "gauge"}
+
+	assert.NotEqual(t, web1.Key(), web2.Key())
+	assert.Equal(t, NewMetricKey("gauge", "Alloc"), plain.Key())
+}
+
+func TestLabelMatchers(t *testing.T) {
+	labels := map[string]string{"host": "web-1", "env": "prod"}
+
+	tests := []struct {
+		matcher string
+		want    bool
+	}{
+		{`host=web-1`, true},
+		{`host=web-2`, false},
+		{`env!=dev`, true},
+		{`host=~web-.*`, true},
+		{`host=~web`, false},
+		{`host!~db-.*`, true},
+		{`dc=`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.matcher, func(t *testing.T) {
+			matcher, err := ParseLabelMatcher(tt.matcher)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, matcher.Matches(labels))
+		})
+	}
+}
+
+func TestParseLabelMatcher_Invalid(t *testing.T) {
+	for _, raw := range []string{"host", "=web-1", "host=~(", ""} {
+		_, err := ParseLabelMatcher(raw)
+		assert.Error(t, err, raw)
+	}
+}
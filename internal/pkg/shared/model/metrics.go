@@ -7,7 +7,7 @@ import (
 	"strconv"
 )
 
-//go:generate easyjson -all Metrics.go
+//go:generate easyjson -all -output_filename Metrics_easyjson.go metrics.go
 
 // Metrics структура, которая может быть либо счетчиком (Counter), либо измеряемым значением (Gauge).
 type Metrics struct {
@@ -15,6 +15,30 @@ type Metrics struct {
 	MType string        `json:"type"`            // Тип метрики: "gauge" или "counter"
 	Delta *int64        `json:"delta,omitempty"` // Значение для счетчика (Counter); применяется, если тип метрики — "counter"
 	Value *float64      `json:"value,omitempty"` // Значение для измеряемой метрики (Gauge); применяется, если тип метрики — "gauge"
+
+	// Labels — необязательные метки метрики (например, host). Входят в идентичность метрики:
+	// значения с разными метками хранятся как разные серии.
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// Clone возвращает глубокую копию метрики: значения и метки не разделяются с исходной.
+func (m *Metrics) Clone() *Metrics {
+	clone := *m
+	if m.Delta != nil {
+		delta := *m.Delta
+		clone.Delta = &delta
+	}
+	if m.Value != nil {
+		value := *m.Value
+		clone.Value = &value
+	}
+	if m.Labels != nil {
+		clone.Labels = make(map[string]string, len(m.Labels))
+		for name, value := range m.Labels {
+			clone.Labels[name] = value
+		}
+	}
+	return &clone
 }
 
 // MetricsList представляет собой список метрик.
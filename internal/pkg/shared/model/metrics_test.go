@@ -0,0 +1,19 @@
+package model
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestMetrics_Clone(t *testing.T) {
+	delta := int64(5)
+	original := &Metrics{ID: "PollCount", MType: "counter", Delta: &delta, Labels: map[string]string{"host": "a"}}
+
+	clone := original.Clone()
+	*clone.Delta = 7
+	clone.Labels["host"] = "b"
+
+	assert.Equal(t, int64(5), *original.Delta)
+	assert.Equal(t, map[string]string{"host": "a"}, original.Labels)
+	assert.Nil(t, clone.Value)
+}
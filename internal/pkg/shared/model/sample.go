@@ -0,0 +1,26 @@
+package model
+
+import "time"
+
+// Sample — значение серии метрики, принятое сервером в момент Timestamp.
+// Для counter хранится накопленное значение после приёма, для gauge — принятое значение.
+type Sample struct {
+	Timestamp time.Time `json:"timestamp"`
+	Delta     *int64    `json:"delta,omitempty"`
+	Value     *float64  `json:"value,omitempty"`
+}
+
+// NewSample создаёт точку истории для текущего значения метрики metric в момент ts.
+// Значения копируются, поэтому последующие изменения metric не влияют на точку.
+func NewSample(metric *Metrics, ts time.Time) Sample {
+	sample := Sample{Timestamp: ts}
+	if metric.Delta != nil {
+		delta := *metric.Delta
+		sample.Delta = &delta
+	}
+	if metric.Value != nil {
+		value := *metric.Value
+		sample.Value = &value
+	}
+	return sample
+}
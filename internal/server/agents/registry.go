@@ -0,0 +1,98 @@
+// Package agents хранит реестр агентов, зарегистрировавшихся на сервере или отправивших ему метрики.
+package agents
+
+import (
+	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/model"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Record — сведения об агенте в реестре.
+type Record struct {
+	model.AgentInfo
+
+	// Address — IP-адрес, с которого агент обращался к серверу в последний раз.
+	Address string `json:"address,omitempty"`
+
+	// Registered сообщает, регистрировался ли агент. Агент, от которого пришли только метрики,
+	// известен лишь по идентификатору.
+	Registered bool `json:"registered"`
+
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// Registry хранит сведения об агентах в памяти; после перезапуска сервера агенты
+// появляются в нём снова при регистрации или с первыми принятыми метриками.
+type Registry struct {
+	mu      sync.RWMutex
+	records map[string]*Record
+
+	now func() time.Time
+}
+
+// NewRegistry создаёт пустой реестр агентов.
+func NewRegistry() *Registry {
+	return &Registry{
+		records: make(map[string]*Record),
+		now:     time.Now,
+	}
+}
+
+// Register сохраняет сведения info об агенте, обратившемся с адреса address,
+// и отмечает его активность. Возвращает запись реестра после обновления.
+func (r *Registry) Register(info model.AgentInfo, address string) Record {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	record := r.touch(info.ID, address)
+	record.AgentInfo = info
+	record.Registered = true
+	return *record
+}
+
+// Seen отмечает активность агента id, обратившегося с адреса address.
+// Незарегистрированный агент добавляется в реестр только с идентификатором.
+func (r *Registry) Seen(id, address string) {
+	if id == "" {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.touch(id, address)
+}
+
+// List возвращает записи всех известных агентов, упорядоченные по идентификатору.
+func (r *Registry) List() []Record {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	records := make([]Record, 0, len(r.records))
+	for _, record := range r.records {
+		records = append(records, *record)
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].ID < records[j].ID
+	})
+	return records
+}
+
+// touch возвращает запись агента id, создавая её при необходимости, и обновляет время активности.
+// Вызывается под блокировкой на запись.
+func (r *Registry) touch(id, address string) *Record {
+	now := r.now()
+
+	record, ok := r.records[id]
+	if !ok {
+		record = &Record{AgentInfo: model.AgentInfo{ID: id}, FirstSeen: now}
+		r.records[id] = record
+	}
+	record.LastSeen = now
+	if address != "" {
+		record.Address = address
+	}
+	return record
+}
@@ -0,0 +1,39 @@
+package agents
+
+import (
+	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestRegistry(t *testing.T) {
+	now := time.Date(2025, 9, 1, 12, 0, 0, 0, time.UTC)
+	registry := NewRegistry()
+	registry.now = func() time.Time { return now }
+
+	registry.Seen("web-2", "10.0.0.2")
+	registry.Seen("", "10.0.0.9")
+
+	now = now.Add(time.Minute)
+	record := registry.Register(model.AgentInfo{ID: "web-1", Version: "1.2.0", Hostname: "web-1"}, "10.0.0.1")
+	assert.True(t, record.Registered)
+	assert.Equal(t, now, record.FirstSeen)
+
+	now = now.Add(time.Minute)
+	registry.Seen("web-1", "")
+
+	records := registry.List()
+	require.Len(t, records, 2)
+
+	assert.Equal(t, "web-1", records[0].ID)
+	assert.Equal(t, "1.2.0", records[0].Version)
+	assert.Equal(t, "10.0.0.1", records[0].Address)
+	assert.Equal(t, now.Add(-time.Minute), records[0].FirstSeen)
+	assert.Equal(t, now, records[0].LastSeen)
+
+	assert.Equal(t, "web-2", records[1].ID)
+	assert.False(t, records[1].Registered)
+	assert.Equal(t, now.Add(-2*time.Minute), records[1].LastSeen)
+}
@@ -0,0 +1,229 @@
+package alerting
+
+import (
+	"context"
+	"fmt"
+	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/model"
+	"github.com/ruslanDantsov/osmetrics-server/internal/server/constants"
+	"go.uber.org/zap"
+	"time"
+)
+
+// State — состояние оповещения серии по правилу.
+type State string
+
+// Состояния оповещения.
+//
+// inactive → pending, когда условие начинает выполняться; pending → firing, когда условие
+// выполняется дольше For; pending → inactive, если условие перестало выполняться раньше;
+// firing → resolved, когда условие перестаёт выполняться. Из resolved оповещение снова
+// переходит в pending (или firing при For = 0), если условие выполняется, иначе — в inactive.
+const (
+	StateInactive State = "inactive"
+	StatePending  State = "pending"
+	StateFiring   State = "firing"
+	StateResolved State = "resolved"
+)
+
+// MetricReader определяет интерфейс чтения метрик, по которым вычисляются правила.
+type MetricReader interface {
+	GetKnownMetrics(ctx context.Context) []model.MetricKey
+	GetMetric(ctx context.Context, key model.MetricKey) (*model.Metrics, bool)
+}
+
+type alertKey struct {
+	rule   string
+	series model.MetricKey
+}
+
+type alert struct {
+	state       State
+	activeSince time.Time
+}
+
+type observation struct {
+	value float64
+	at    time.Time
+}
+
+// Engine периодически вычисляет правила по всем подходящим сериям и сообщает Notifier
+// о сменах состояний оповещений. Переход resolved → inactive не сообщается:
+// об окончании оповещения уже сообщил переход в resolved.
+type Engine struct {
+	storage  MetricReader
+	rules    []*Rule
+	notifier Notifier
+	log      zap.Logger
+
+	alerts       map[alertKey]*alert
+	observations map[model.MetricKey]observation
+	now          func() time.Time
+}
+
+// NewEngine создаёт движок оповещений для правил rules.
+func NewEngine(storage MetricReader, rules []*Rule, notifier Notifier, log zap.Logger) *Engine {
+	return &Engine{
+		storage:      storage,
+		rules:        rules,
+		notifier:     notifier,
+		log:          log,
+		alerts:       make(map[alertKey]*alert),
+		observations: make(map[model.MetricKey]observation),
+		now:          time.Now,
+	}
+}
+
+// Run вычисляет правила каждые interval до отмены ctx.
+func (e *Engine) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			e.Evaluate(ctx)
+		}
+	}
+}
+
+// Evaluate однократно вычисляет все правила и отправляет сведения о сменах состояний.
+// Не предназначен для конкурентного вызова.
+func (e *Engine) Evaluate(ctx context.Context) {
+	now := e.now()
+	keys := e.storage.GetKnownMetrics(ctx)
+	current := make(map[model.MetricKey]observation)
+
+	for _, rule := range e.rules {
+		for _, key := range keys {
+			if !rule.Matches(key) {
+				continue
+			}
+
+			obs, ok := current[key]
+			if !ok {
+				metric, found := e.storage.GetMetric(ctx, key)
+				if !found {
+					continue
+				}
+				value, ok := metricValue(metric)
+				if !ok {
+					continue
+				}
+				obs = observation{value: value, at: now}
+				current[key] = obs
+			}
+
+			value := obs.value
+			if rule.Rate {
+				rate, ok := e.rate(key, obs)
+				if !ok {
+					continue
+				}
+				value = rate
+			}
+
+			e.step(ctx, rule, key, value, now)
+		}
+	}
+
+	e.observations = current
+}
+
+// rate вычисляет скорость роста counter в секунду относительно предыдущего вычисления.
+// После сброса counter скорость считается от нуля.
+func (e *Engine) rate(key model.MetricKey, obs observation) (float64, bool) {
+	prev, ok := e.observations[key]
+	if !ok {
+		return 0, false
+	}
+
+	elapsed := obs.at.Sub(prev.at).Seconds()
+	if elapsed <= 0 {
+		return 0, false
+	}
+
+	increase := obs.value - prev.value
+	if increase < 0 {
+		increase = obs.value
+	}
+	return increase / elapsed, true
+}
+
+// step применяет результат вычисления правила к оповещению серии.
+func (e *Engine) step(ctx context.Context, rule *Rule, key model.MetricKey, value float64, now time.Time) {
+	k := alertKey{rule: rule.Name, series: key}
+	current, ok := e.alerts[k]
+	if !ok {
+		current = &alert{state: StateInactive}
+		e.alerts[k] = current
+	}
+
+	from := current.state
+	active := rule.Op.compare(value, rule.Threshold)
+
+	switch {
+	case active && (from == StateInactive || from == StateResolved):
+		current.activeSince = now
+		current.state = StatePending
+		if rule.For == 0 {
+			current.state = StateFiring
+		}
+	case active && from == StatePending && now.Sub(current.activeSince) >= rule.For:
+		current.state = StateFiring
+	case !active && from == StatePending:
+		current.state = StateInactive
+	case !active && from == StateFiring:
+		current.state = StateResolved
+	case !active && from == StateResolved:
+		current.state = StateInactive
+		return
+	}
+
+	if current.state == from {
+		return
+	}
+
+	e.notify(ctx, rule, key, from, current, value, now)
+}
+
+func (e *Engine) notify(ctx context.Context, rule *Rule, key model.MetricKey, from State, current *alert, value float64, now time.Time) {
+	labels, _ := model.ParseCanonicalLabels(key.Labels)
+	transition := Transition{
+		Rule:      rule.Name,
+		Expr:      rule.Expr,
+		ID:        key.ID,
+		MType:     key.MType,
+		Labels:    labels,
+		From:      from,
+		To:        current.state,
+		Value:     value,
+		Timestamp: now,
+	}
+	if current.state == StatePending || current.state == StateFiring {
+		activeSince := current.activeSince
+		transition.ActiveSince = &activeSince
+	}
+
+	e.log.Info(fmt.Sprintf("Alert %s for %s: %s -> %s", rule.Name, key, from, current.state))
+
+	if err := e.notifier.Notify(ctx, transition); err != nil {
+		e.log.Error(fmt.Sprintf("Failed to notify alert %s for %s", rule.Name, key), zap.Error(err))
+	}
+}
+
+// metricValue возвращает значение метрики в виде числа: value для gauge и delta для counter.
+func metricValue(metric *model.Metrics) (float64, bool) {
+	switch metric.MType {
+	case constants.GaugeMetricType:
+		if metric.Value != nil {
+			return *metric.Value, true
+		}
+	case constants.CounterMetricType:
+		if metric.Delta != nil {
+			return float64(*metric.Delta), true
+		}
+	}
+	return 0, false
+}
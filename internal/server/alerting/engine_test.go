@@ -0,0 +1,166 @@
+package alerting
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/model"
+	"github.com/ruslanDantsov/osmetrics-server/internal/server/repository/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// webhookSink — локальный HTTP-приёмник оповещений для тестов.
+type webhookSink struct {
+	mu          sync.Mutex
+	transitions []Transition
+}
+
+func (s *webhookSink) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var transition Transition
+	if err := json.NewDecoder(r.Body).Decode(&transition); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.transitions = append(s.transitions, transition)
+}
+
+func (s *webhookSink) take() []Transition {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result := s.transitions
+	s.transitions = nil
+	return result
+}
+
+func newTestEngine(t *testing.T, storage MetricReader, exprs map[string]string) (*Engine, *webhookSink, *time.Time) {
+	t.Helper()
+
+	sink := &webhookSink{}
+	server := httptest.NewServer(sink)
+	t.Cleanup(server.Close)
+
+	var rules []*Rule
+	for name, expr := range exprs {
+		rule, err := NewRule(name, expr, nil)
+		require.NoError(t, err)
+		rules = append(rules, rule)
+	}
+
+	engine := NewEngine(storage, rules, NewWebhookNotifier([]string{server.URL}, time.Second), *zap.NewNop())
+	now := time.Date(2025, 9, 1, 12, 0, 0, 0, time.UTC)
+	engine.now = func() time.Time { return now }
+	return engine, sink, &now
+}
+
+func states(transitions []Transition) []State {
+	result := make([]State, 0, len(transitions))
+	for _, transition := range transitions {
+		result = append(result, transition.To)
+	}
+	return result
+}
+
+func TestEngine_GaugeRuleLifecycle(t *testing.T) {
+	ctx := context.Background()
+	storage := memory.NewMemStorage(*zap.NewNop(), 0)
+	setHeap := func(v float64) {
+		_, err := storage.SaveMetric(ctx, &model.Metrics{ID: "HeapInuse", MType: "gauge", Value: &v})
+		require.NoError(t, err)
+	}
+
+	engine, sink, now := newTestEngine(t, storage, map[string]string{"HighHeap": "gauge HeapInuse > 500MB for 2m"})
+
+	setHeap(100 << 20)
+	engine.Evaluate(ctx)
+	assert.Empty(t, sink.take())
+
+	setHeap(600 << 20)
+	engine.Evaluate(ctx)
+	pending := sink.take()
+	require.Len(t, pending, 1)
+	assert.Equal(t, StateInactive, pending[0].From)
+	assert.Equal(t, StatePending, pending[0].To)
+	assert.Equal(t, "HighHeap", pending[0].Rule)
+	assert.Equal(t, float64(600<<20), pending[0].Value)
+	require.NotNil(t, pending[0].ActiveSince)
+
+	*now = now.Add(time.Minute)
+	engine.Evaluate(ctx)
+	assert.Empty(t, sink.take())
+
+	*now = now.Add(time.Minute)
+	engine.Evaluate(ctx)
+	assert.Equal(t, []State{StateFiring}, states(sink.take()))
+
+	setHeap(100 << 20)
+	engine.Evaluate(ctx)
+	assert.Equal(t, []State{StateResolved}, states(sink.take()))
+
+	engine.Evaluate(ctx)
+	assert.Empty(t, sink.take(), "resolved -> inactive is not reported")
+
+	setHeap(600 << 20)
+	engine.Evaluate(ctx)
+	setHeap(100 << 20)
+	engine.Evaluate(ctx)
+	assert.Equal(t, []State{StatePending, StateInactive}, states(sink.take()))
+}
+
+func TestEngine_CounterRateRule(t *testing.T) {
+	ctx := context.Background()
+	storage := memory.NewMemStorage(*zap.NewNop(), 0)
+	addPolls := func(delta int64) {
+		_, err := storage.SaveMetric(ctx, &model.Metrics{ID: "PollCount", MType: "counter", Delta: &delta})
+		require.NoError(t, err)
+	}
+
+	engine, sink, now := newTestEngine(t, storage, map[string]string{"AgentStalled": "counter PollCount rate == 0"})
+
+	addPolls(5)
+	engine.Evaluate(ctx)
+	assert.Empty(t, sink.take(), "rate needs two evaluations")
+
+	*now = now.Add(10 * time.Second)
+	addPolls(5)
+	engine.Evaluate(ctx)
+	assert.Empty(t, sink.take())
+
+	*now = now.Add(10 * time.Second)
+	engine.Evaluate(ctx)
+	transitions := sink.take()
+	require.Len(t, transitions, 1)
+	assert.Equal(t, StateFiring, transitions[0].To)
+	assert.Equal(t, "PollCount", string(transitions[0].ID))
+
+	*now = now.Add(10 * time.Second)
+	addPolls(1)
+	engine.Evaluate(ctx)
+	assert.Equal(t, []State{StateResolved}, states(sink.take()))
+}
+
+func TestEngine_Run_StopsOnContextCancel(t *testing.T) {
+	engine, _, _ := newTestEngine(t, memory.NewMemStorage(*zap.NewNop(), 0), nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		engine.Run(ctx, time.Millisecond)
+		close(done)
+	}()
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("engine did not stop")
+	}
+}
@@ -0,0 +1,197 @@
+// Package alerting вычисляет правила оповещения по сохранённым метрикам и сообщает webhook-ам о смене состояния оповещений.
+package alerting
+
+import (
+	"fmt"
+	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/model"
+	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/model/enum"
+	"github.com/ruslanDantsov/osmetrics-server/internal/server/constants"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Comparison — оператор сравнения значения метрики с порогом.
+type Comparison string
+
+// Поддерживаемые операторы сравнения.
+const (
+	Greater        Comparison = ">"
+	GreaterOrEqual Comparison = ">="
+	Less           Comparison = "<"
+	LessOrEqual    Comparison = "<="
+	Equal          Comparison = "=="
+	NotEqual       Comparison = "!="
+)
+
+// compare сравнивает value с threshold.
+func (c Comparison) compare(value, threshold float64) bool {
+	switch c {
+	case Greater:
+		return value > threshold
+	case GreaterOrEqual:
+		return value >= threshold
+	case Less:
+		return value < threshold
+	case LessOrEqual:
+		return value <= threshold
+	case Equal:
+		return value == threshold
+	case NotEqual:
+		return value != threshold
+	default:
+		return false
+	}
+}
+
+func parseComparison(raw string) (Comparison, error) {
+	switch c := Comparison(raw); c {
+	case Greater, GreaterOrEqual, Less, LessOrEqual, Equal, NotEqual:
+		return c, nil
+	default:
+		return "", fmt.Errorf("unknown comparison %q", raw)
+	}
+}
+
+// byteUnits — суффиксы порога в байтах (двоичные кратные), от длинных к коротким.
+var byteUnits = []struct {
+	suffix     string
+	multiplier float64
+}{
+	{"TB", 1 << 40},
+	{"GB", 1 << 30},
+	{"MB", 1 << 20},
+	{"KB", 1 << 10},
+	{"B", 1},
+}
+
+// Rule — правило оповещения, применяемое к каждой серии метрики MType с именем ID,
+// метки которой удовлетворяют Matchers.
+type Rule struct {
+	// Name — уникальное имя правила.
+	Name string
+
+	// Expr — исходное выражение правила.
+	Expr string
+
+	MType     string
+	ID        enum.MetricID
+	Matchers  model.LabelMatchers
+	Rate      bool
+	Op        Comparison
+	Threshold float64
+
+	// For — сколько условие должно выполняться непрерывно, прежде чем оповещение перейдёт из pending в firing.
+	For time.Duration
+}
+
+// NewRule создаёт правило с именем name из выражения expr и условий на метки match (см. model.ParseLabelMatcher).
+//
+// Выражение имеет вид "<тип> <имя> [rate] <оператор> <порог> [for <длительность>]", например
+// "gauge HeapInuse > 500MB for 2m" или "counter PollCount rate == 0 for 1m".
+// rate — скорость роста counter в единицах в секунду между соседними вычислениями.
+// Порог может иметь суффикс B, KB, MB, GB или TB (кратные 1024).
+func NewRule(name, expr string, match []string) (*Rule, error) {
+	if name == "" {
+		return nil, fmt.Errorf("rule %q: name is empty", expr)
+	}
+
+	rule := &Rule{Name: name, Expr: expr}
+	if err := rule.parseExpr(expr); err != nil {
+		return nil, fmt.Errorf("rule %s: %w", name, err)
+	}
+
+	matchers, err := model.ParseLabelMatchers(match)
+	if err != nil {
+		return nil, fmt.Errorf("rule %s: %w", name, err)
+	}
+	rule.Matchers = matchers
+
+	return rule, nil
+}
+
+func (r *Rule) parseExpr(expr string) error {
+	fields := strings.Fields(expr)
+	if len(fields) < 4 {
+		return fmt.Errorf("expression %q must look like \"<type> <name> [rate] <op> <threshold> [for <duration>]\"", expr)
+	}
+
+	r.MType = fields[0]
+	if r.MType != constants.GaugeMetricType && r.MType != constants.CounterMetricType {
+		return fmt.Errorf("metric type %s is unsupported", r.MType)
+	}
+
+	id, err := enum.ParseMetricID(fields[1])
+	if err != nil {
+		return err
+	}
+	r.ID = id
+
+	rest := fields[2:]
+	if rest[0] == "rate" {
+		if r.MType != constants.CounterMetricType {
+			return fmt.Errorf("rate is supported only for %s metrics", constants.CounterMetricType)
+		}
+		r.Rate = true
+		rest = rest[1:]
+	}
+
+	if len(rest) != 2 && len(rest) != 4 {
+		return fmt.Errorf("expression %q must look like \"<type> <name> [rate] <op> <threshold> [for <duration>]\"", expr)
+	}
+
+	if r.Op, err = parseComparison(rest[0]); err != nil {
+		return err
+	}
+	if r.Threshold, err = parseThreshold(rest[1]); err != nil {
+		return err
+	}
+
+	if len(rest) == 4 {
+		if rest[2] != "for" {
+			return fmt.Errorf("expected \"for\", got %q", rest[2])
+		}
+		if r.For, err = time.ParseDuration(rest[3]); err != nil {
+			return err
+		}
+		if r.For < 0 {
+			return fmt.Errorf("negative duration %s", rest[3])
+		}
+	}
+
+	return nil
+}
+
+// Matches сообщает, относится ли серия key к правилу.
+func (r *Rule) Matches(key model.MetricKey) bool {
+	if key.MType != r.MType || key.ID != r.ID {
+		return false
+	}
+	if len(r.Matchers) == 0 {
+		return true
+	}
+
+	labels, err := model.ParseCanonicalLabels(key.Labels)
+	if err != nil {
+		return false
+	}
+	return r.Matchers.Matches(labels)
+}
+
+// parseThreshold разбирает порог: число с необязательным суффиксом единиц объёма.
+func parseThreshold(raw string) (float64, error) {
+	number, multiplier := raw, 1.0
+	upper := strings.ToUpper(raw)
+	for _, unit := range byteUnits {
+		if strings.HasSuffix(upper, unit.suffix) {
+			number, multiplier = raw[:len(raw)-len(unit.suffix)], unit.multiplier
+			break
+		}
+	}
+
+	value, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid threshold %q", raw)
+	}
+	return value * multiplier, nil
+}
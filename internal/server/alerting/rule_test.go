@@ -0,0 +1,95 @@
+package alerting
+
+import (
+	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNewRule(t *testing.T) {
+	tests := []struct {
+		name    string
+		expr    string
+		want    Rule
+		wantErr bool
+	}{
+		{
+			name: "gauge with unit and for",
+			expr: "gauge HeapInuse > 500MB for 2m",
+			want: Rule{MType: "gauge", ID: "HeapInuse", Op: Greater, Threshold: 500 << 20, For: 2 * time.Minute},
+		},
+		{
+			name: "counter rate",
+			expr: "counter PollCount rate == 0 for 1m",
+			want: Rule{MType: "counter", ID: "PollCount", Rate: true, Op: Equal, Threshold: 0, For: time.Minute},
+		},
+		{
+			name: "without for",
+			expr: "gauge RandomValue <= 0.5",
+			want: Rule{MType: "gauge", ID: "RandomValue", Op: LessOrEqual, Threshold: 0.5},
+		},
+		{name: "unknown type", expr: "histogram Foo > 1", wantErr: true},
+		{name: "rate on gauge", expr: "gauge Alloc rate > 1", wantErr: true},
+		{name: "unknown comparison", expr: "gauge Alloc => 1", wantErr: true},
+		{name: "invalid threshold", expr: "gauge Alloc > lots", wantErr: true},
+		{name: "invalid duration", expr: "gauge Alloc > 1 for ever", wantErr: true},
+		{name: "missing for keyword", expr: "gauge Alloc > 1 during 1m", wantErr: true},
+		{name: "too short", expr: "gauge Alloc >", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := NewRule("rule", tt.expr, nil)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			tt.want.Name = "rule"
+			tt.want.Expr = tt.expr
+			tt.want.Matchers = model.LabelMatchers{}
+			assert.Equal(t, tt.want, *rule)
+		})
+	}
+}
+
+func TestRule_Matches(t *testing.T) {
+	rule, err := NewRule("rule", "gauge Alloc > 1", []string{"host=web-1"})
+	require.NoError(t, err)
+
+	key := model.NewMetricKey("gauge", "Alloc")
+	assert.True(t, rule.Matches(key.WithLabels(map[string]string{"host": "web-1"})))
+	assert.False(t, rule.Matches(key.WithLabels(map[string]string{"host": "web-2"})))
+	assert.False(t, rule.Matches(model.NewMetricKey("counter", "Alloc").WithLabels(map[string]string{"host": "web-1"})))
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	write := func(content string) string {
+		path := filepath.Join(dir, "rules.json")
+		require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+		return path
+	}
+
+	config, err := LoadConfig(write(`{
+		"webhooks": ["http://localhost:9000/alerts"],
+		"rules": [
+			{"name": "HighHeap", "expr": "gauge HeapInuse > 500MB for 2m", "match": ["host=web-1"]},
+			{"name": "AgentStalled", "expr": "counter PollCount rate == 0 for 1m"}
+		]
+	}`))
+	require.NoError(t, err)
+	assert.Len(t, config.Rules, 2)
+	assert.Equal(t, []string{"http://localhost:9000/alerts"}, config.Webhooks)
+
+	_, err = LoadConfig(write(`{"rules": [{"name": "A", "expr": "gauge Alloc > 1"}, {"name": "A", "expr": "gauge Alloc < 1"}]}`))
+	assert.ErrorContains(t, err, "duplicate rule name")
+
+	_, err = LoadConfig(write(`{"webhooks": ["localhost:9000"]}`))
+	assert.ErrorContains(t, err, "invalid webhook URL")
+}
@@ -0,0 +1,64 @@
+package alerting
+
+import (
+	"fmt"
+	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/configfile"
+	"net/url"
+)
+
+// rulesFile описывает JSON-файл правил оповещения, например:
+//
+//	{
+//	  "webhooks": ["http://localhost:9000/alerts"],
+//	  "rules": [
+//	    {"name": "HighHeap", "expr": "gauge HeapInuse > 500MB for 2m", "match": ["host=web-1"]},
+//	    {"name": "AgentStalled", "expr": "counter PollCount rate == 0 for 1m"}
+//	  ]
+//	}
+type rulesFile struct {
+	Webhooks []string `json:"webhooks"`
+	Rules    []struct {
+		Name  string   `json:"name"`
+		Expr  string   `json:"expr"`
+		Match []string `json:"match"`
+	} `json:"rules"`
+}
+
+// Config — правила оповещения и адреса webhook, загруженные из файла.
+type Config struct {
+	Rules    []*Rule
+	Webhooks []string
+}
+
+// LoadConfig загружает правила оповещения и адреса webhook из JSON-файла path.
+// Имена правил должны быть уникальны, адреса webhook — абсолютными URL с http или https.
+func LoadConfig(path string) (*Config, error) {
+	var file rulesFile
+	if err := configfile.Load(path, &file); err != nil {
+		return nil, err
+	}
+
+	config := &Config{Webhooks: file.Webhooks}
+
+	names := make(map[string]struct{}, len(file.Rules))
+	for _, raw := range file.Rules {
+		rule, err := NewRule(raw.Name, raw.Expr, raw.Match)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rules file %s: %w", path, err)
+		}
+		if _, duplicate := names[rule.Name]; duplicate {
+			return nil, fmt.Errorf("invalid rules file %s: duplicate rule name %s", path, rule.Name)
+		}
+		names[rule.Name] = struct{}{}
+		config.Rules = append(config.Rules, rule)
+	}
+
+	for _, webhook := range file.Webhooks {
+		parsed, err := url.Parse(webhook)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return nil, fmt.Errorf("invalid rules file %s: invalid webhook URL %q", path, webhook)
+		}
+	}
+
+	return config, nil
+}
@@ -0,0 +1,83 @@
+package alerting
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/model/enum"
+	"net/http"
+	"time"
+)
+
+// Transition — смена состояния оповещения одной серии, отправляемая в webhook.
+type Transition struct {
+	Rule   string            `json:"rule"`
+	Expr   string            `json:"expr"`
+	ID     enum.MetricID     `json:"id"`
+	MType  string            `json:"type"`
+	Labels map[string]string `json:"labels,omitempty"`
+	From   State             `json:"from"`
+	To     State             `json:"to"`
+	Value  float64           `json:"value"`
+
+	// ActiveSince — момент, с которого условие правила выполняется; пусто для inactive и resolved.
+	ActiveSince *time.Time `json:"active_since,omitempty"`
+	Timestamp   time.Time  `json:"timestamp"`
+}
+
+// Notifier доставляет сведения о смене состояний оповещений.
+type Notifier interface {
+	Notify(ctx context.Context, transition Transition) error
+}
+
+// WebhookNotifier отправляет смены состояний POST-запросом с JSON-телом на каждый из адресов.
+type WebhookNotifier struct {
+	urls   []string
+	client *http.Client
+}
+
+// NewWebhookNotifier создаёт WebhookNotifier для адресов urls с таймаутом запроса timeout.
+func NewWebhookNotifier(urls []string, timeout time.Duration) *WebhookNotifier {
+	return &WebhookNotifier{
+		urls:   urls,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+// Notify отправляет transition на все адреса. Ошибки отдельных адресов не прерывают рассылку
+// и возвращаются вместе.
+func (n *WebhookNotifier) Notify(ctx context.Context, transition Transition) error {
+	body, err := json.Marshal(transition)
+	if err != nil {
+		return fmt.Errorf("failed to marshal alert transition: %w", err)
+	}
+
+	var errs []error
+	for _, url := range n.urls {
+		if err := n.post(ctx, url, body); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (n *WebhookNotifier) post(ctx context.Context, url string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request to %s: %w", url, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send webhook to %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("bad response from webhook %s: %v", url, resp.StatusCode)
+	}
+	return nil
+}
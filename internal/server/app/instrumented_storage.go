@@ -0,0 +1,75 @@
+package app
+
+import (
+	"context"
+	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/model"
+	"github.com/ruslanDantsov/osmetrics-server/internal/server/telemetry"
+	"time"
+)
+
+// instrumentedStorage учитывает длительность и ошибки операций хранилища, а также число принятых метрик.
+type instrumentedStorage struct {
+	Storager
+	backend   string
+	telemetry *telemetry.Telemetry
+}
+
+func newInstrumentedStorage(storage Storager, backend string, t *telemetry.Telemetry) *instrumentedStorage {
+	return &instrumentedStorage{Storager: storage, backend: backend, telemetry: t}
+}
+
+func (s *instrumentedStorage) GetKnownMetrics(ctx context.Context) []model.MetricKey {
+	start := time.Now()
+	keys := s.Storager.GetKnownMetrics(ctx)
+	s.telemetry.ObserveStorage(s.backend, "get_known_metrics", time.Since(start), nil)
+	return keys
+}
+
+func (s *instrumentedStorage) GetMetric(ctx context.Context, key model.MetricKey) (*model.Metrics, bool) {
+	start := time.Now()
+	metric, found := s.Storager.GetMetric(ctx, key)
+	s.telemetry.ObserveStorage(s.backend, "get_metric", time.Since(start), nil)
+	return metric, found
+}
+
+func (s *instrumentedStorage) GetSeries(ctx context.Context, key model.MetricKey, from, to time.Time) ([]model.Sample, error) {
+	start := time.Now()
+	samples, err := s.Storager.GetSeries(ctx, key, from, to)
+	s.telemetry.ObserveStorage(s.backend, "get_series", time.Since(start), err)
+	return samples, err
+}
+
+func (s *instrumentedStorage) SaveMetric(ctx context.Context, metric *model.Metrics) (*model.Metrics, error) {
+	metricType := metric.MType
+
+	start := time.Now()
+	saved, err := s.Storager.SaveMetric(ctx, metric)
+	s.telemetry.ObserveStorage(s.backend, "save_metric", time.Since(start), err)
+	if err == nil {
+		s.telemetry.AddIngested(metricType, 1)
+	}
+	return saved, err
+}
+
+func (s *instrumentedStorage) SaveAllMetrics(ctx context.Context, metricList model.MetricsList) (model.MetricsList, error) {
+	start := time.Now()
+	saved, err := s.Storager.SaveAllMetrics(ctx, metricList)
+	s.telemetry.ObserveStorage(s.backend, "save_all_metrics", time.Since(start), err)
+	if err == nil {
+		counts := make(map[string]int)
+		for _, metric := range metricList {
+			counts[metric.MType]++
+		}
+		for metricType, count := range counts {
+			s.telemetry.AddIngested(metricType, count)
+		}
+	}
+	return saved, err
+}
+
+func (s *instrumentedStorage) HealthCheck(ctx context.Context) error {
+	start := time.Now()
+	err := s.Storager.HealthCheck(ctx)
+	s.telemetry.ObserveStorage(s.backend, "health_check", time.Since(start), err)
+	return err
+}
@@ -0,0 +1,48 @@
+package app
+
+import (
+	"context"
+	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/model"
+	"github.com/ruslanDantsov/osmetrics-server/internal/server/repository/file"
+	"github.com/ruslanDantsov/osmetrics-server/internal/server/repository/memory"
+	"github.com/ruslanDantsov/osmetrics-server/internal/server/telemetry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func TestInstrumentedStorage_RecordsOperationsAndIngest(t *testing.T) {
+	tele := telemetry.New()
+	fileStorage := file.NewPersistentStorage(memory.NewMemStorage(*zap.NewNop(), 0), filepath.Join(t.TempDir(), "metrics.json"), 0, *zap.NewNop(), false, 0, false)
+	fileStorage.SetSnapshotObserver(tele)
+	storage := newInstrumentedStorage(fileStorage, "file", tele)
+	ctx := context.Background()
+
+	value := 1.5
+	delta := int64(2)
+	_, err := storage.SaveAllMetrics(ctx, model.MetricsList{
+		{ID: "Alloc", MType: "gauge", Value: &value},
+		{ID: "PollCount", MType: "counter", Delta: &delta},
+		{ID: "Frees", MType: "counter", Delta: &delta},
+	})
+	require.NoError(t, err)
+	storage.GetMetric(ctx, model.NewMetricKey("gauge", "Alloc"))
+
+	w := httptest.NewRecorder()
+	tele.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body, err := io.ReadAll(w.Body)
+	require.NoError(t, err)
+	metrics := string(body)
+
+	assert.Contains(t, metrics, `osmetrics_ingest_metrics_total{type="counter"} 2`)
+	assert.Contains(t, metrics, `osmetrics_ingest_metrics_total{type="gauge"} 1`)
+	assert.Contains(t, metrics, `osmetrics_storage_operation_duration_seconds_count{backend="file",operation="save_all_metrics"} 1`)
+	assert.Contains(t, metrics, `osmetrics_storage_operation_duration_seconds_count{backend="file",operation="get_metric"} 1`)
+	assert.Contains(t, metrics, `osmetrics_file_snapshot_duration_seconds_count 1`)
+	assert.Contains(t, metrics, `osmetrics_file_snapshot_failures_total 0`)
+}
@@ -2,18 +2,31 @@ package app
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/crypto"
+	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/metricspb"
 	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/model"
-	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/model/enum"
+	"github.com/ruslanDantsov/osmetrics-server/internal/server/agents"
+	"github.com/ruslanDantsov/osmetrics-server/internal/server/alerting"
 	"github.com/ruslanDantsov/osmetrics-server/internal/server/config"
+	"github.com/ruslanDantsov/osmetrics-server/internal/server/constants"
+	"github.com/ruslanDantsov/osmetrics-server/internal/server/grpcserver"
 	"github.com/ruslanDantsov/osmetrics-server/internal/server/handler"
+	"github.com/ruslanDantsov/osmetrics-server/internal/server/handler/agent"
 	"github.com/ruslanDantsov/osmetrics-server/internal/server/handler/metric"
 	"github.com/ruslanDantsov/osmetrics-server/internal/server/middleware"
 	"github.com/ruslanDantsov/osmetrics-server/internal/server/repository/file"
 	"github.com/ruslanDantsov/osmetrics-server/internal/server/repository/memory"
 	"github.com/ruslanDantsov/osmetrics-server/internal/server/repository/postgre"
+	"github.com/ruslanDantsov/osmetrics-server/internal/server/telemetry"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"net"
 	"net/http/pprof"
+	"time"
 
 	"go.uber.org/zap"
 	"net/http"
@@ -26,16 +39,23 @@ type ServerApp struct {
 	logger             *zap.Logger
 	getMetricHandler   *metric.GetMetricHandler
 	storeMetricHandler *metric.StoreMetricHandler
+	seriesHandler      *metric.SeriesHandler
+	agentHandler       *agent.AgentHandler
+	agentRegistry      *agents.Registry
 	commonHandler      *handler.CommonHandler
 	healthHandler      *handler.HealthHandler
 	dbHealthHandler    *handler.DBHandler
 	storage            Storager
+	grpcServer         *grpc.Server
+	alertEngine        *alerting.Engine
+	telemetry          *telemetry.Telemetry
 }
 
 // Storager определяет интерфейс для взаимодействия с хранилищем метрик.
 type Storager interface {
-	GetKnownMetrics(ctx context.Context) []string
-	GetMetric(ctx context.Context, metricID enum.MetricID) (*model.Metrics, bool)
+	GetKnownMetrics(ctx context.Context) []model.MetricKey
+	GetMetric(ctx context.Context, key model.MetricKey) (*model.Metrics, bool)
+	GetSeries(ctx context.Context, key model.MetricKey, from, to time.Time) ([]model.Sample, error)
 	SaveMetric(ctx context.Context, metric *model.Metrics) (*model.Metrics, error)
 	SaveAllMetrics(ctx context.Context, metricList model.MetricsList) (model.MetricsList, error)
 	HealthCheck(ctx context.Context) error
@@ -45,51 +65,113 @@ type Storager interface {
 // NewServerApp создаёт и инициализирует экземпляр ServerApp.
 func NewServerApp(cfg *config.ServerConfig, log *zap.Logger) (*ServerApp, error) {
 	var storage Storager
-	var err error
+	tele := telemetry.New()
 
 	if cfg.DatabaseConnection != "" {
-		storage, err = postgre.NewPostgreStorage(*log, cfg.DatabaseConnection)
+		postgreStorage, err := postgre.NewPostgreStorage(*log, cfg.DatabaseConnection, cfg.HistoryRetention)
 		if err != nil {
 			return nil, err
 		}
+		if err := tele.Register(telemetry.NewPgxPoolCollector(postgreStorage)); err != nil {
+			postgreStorage.Close()
+			return nil, err
+		}
+		storage = newInstrumentedStorage(postgreStorage, "postgres", tele)
 	} else {
-		baseStorage := memory.NewMemStorage(*log)
-		storage = file.NewPersistentStorage(baseStorage, cfg.FileStoragePath, cfg.StoreInterval, *log, cfg.Restore)
+		baseStorage := memory.NewMemStorage(*log, cfg.HistorySize)
+		fileStorage := file.NewPersistentStorage(baseStorage, cfg.FileStoragePath, cfg.StoreInterval, *log, cfg.Restore, cfg.SnapshotKeep, cfg.WAL)
+		fileStorage.SetSnapshotObserver(tele)
+		storage = newInstrumentedStorage(fileStorage, "file", tele)
 	}
 
 	getMetricHandler := metric.NewGetMetricHandler(storage, *log)
 	storeMetricHandler := metric.NewStoreMetricHandler(storage, *log)
+	seriesHandler := metric.NewSeriesHandler(storage, *log)
+	agentRegistry := agents.NewRegistry()
+	agentHandler := agent.NewAgentHandler(agentRegistry, *log)
 	commonHandler := handler.NewCommonHandler(*log)
 	healthHandler := handler.NewHealthHandler(*log)
 
 	dbHealthHandler := handler.NewDBHandler(*log, storage)
 
+	var alertEngine *alerting.Engine
+	if cfg.AlertRulesPath != "" {
+		alertConfig, err := alerting.LoadConfig(cfg.AlertRulesPath)
+		if err != nil {
+			storage.Close()
+			return nil, err
+		}
+		notifier := alerting.NewWebhookNotifier(alertConfig.Webhooks, constants.AlertWebhookTimeout)
+		alertEngine = alerting.NewEngine(storage, alertConfig.Rules, notifier, *log)
+		log.Info("Alerting rules loaded", zap.Int("rules", len(alertConfig.Rules)), zap.Int("webhooks", len(alertConfig.Webhooks)))
+	}
+
+	var grpcServer *grpc.Server
+	if cfg.GRPCAddress != "" {
+		grpcServer = grpc.NewServer(
+			grpc.ChainUnaryInterceptor(
+				grpcserver.TrustedSubnetUnaryInterceptor(cfg.TrustedNet, log),
+				grpcserver.HashUnaryInterceptor(cfg.HashKey, log),
+				grpcserver.AgentSeenUnaryInterceptor(agentRegistry),
+			),
+			grpc.ChainStreamInterceptor(
+				grpcserver.TrustedSubnetStreamInterceptor(cfg.TrustedNet, log),
+				grpcserver.HashStreamInterceptor(cfg.HashKey, log),
+				grpcserver.AgentSeenStreamInterceptor(agentRegistry),
+			),
+		)
+		metricspb.RegisterMetricsServer(grpcServer, grpcserver.NewMetricsServer(storage, *log))
+		metricspb.RegisterAgentsServer(grpcServer, grpcserver.NewAgentsServer(agentRegistry, *log))
+
+		healthServer := health.NewServer()
+		healthServer.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+		healthpb.RegisterHealthServer(grpcServer, healthServer)
+	}
+
 	return &ServerApp{
 		cfg:                cfg,
 		logger:             log,
 		getMetricHandler:   getMetricHandler,
 		storeMetricHandler: storeMetricHandler,
+		seriesHandler:      seriesHandler,
+		agentHandler:       agentHandler,
+		agentRegistry:      agentRegistry,
 		commonHandler:      commonHandler,
 		healthHandler:      healthHandler,
 		dbHealthHandler:    dbHealthHandler,
 		storage:            storage,
+		grpcServer:         grpcServer,
+		alertEngine:        alertEngine,
+		telemetry:          tele,
 	}, nil
 }
 
 // Run запускает HTTP-сервер и регистрирует все маршруты,
 // настраивает middleware и маршруты профилирования pprof.
-func (app *ServerApp) Run() error {
+//
+// Сервер работает до отмены ctx. После отмены новые соединения не принимаются,
+// а обрабатываемые запросы завершаются в пределах constants.ShutdownTimeout.
+func (app *ServerApp) Run(ctx context.Context) error {
 	router := gin.Default()
 
-	var decryptMW gin.HandlerFunc
+	// payloadMW применяется к запросам с телом метрики, updateMW — к запросам агентов на запись метрик
+	// и регистрацию; успешные запросы агентов отмечаются в реестре агентов.
+	var payloadMW []gin.HandlerFunc
 	if len(app.cfg.CryptoPrivateKeyPath) > 0 {
 		privKey, err := crypto.LoadPrivateKey(app.cfg.CryptoPrivateKeyPath)
 		if err != nil {
 			app.logger.Fatal("failed to load private key", zap.Error(err))
 		}
-		decryptMW = middleware.NewDecryptPayloadMiddleware(privKey, app.logger)
+		payloadMW = append(payloadMW, middleware.NewDecryptPayloadMiddleware(privKey, app.cfg.CryptoLegacy, app.logger))
+	}
+
+	updateMW := []gin.HandlerFunc{middleware.NewAgentSeenMiddleware(app.agentRegistry)}
+	if app.cfg.TrustedNet != nil {
+		updateMW = append(updateMW, middleware.NewTrustedSubnetMiddleware(app.cfg.TrustedNet, app.logger))
 	}
+	updateMW = append(updateMW, payloadMW...)
 
+	router.Use(middleware.NewMetricsMiddleware(app.telemetry))
 	router.Use(middleware.NewLoggerRequestMiddleware(app.logger))
 	if len(app.cfg.HashKey) != 0 {
 		router.Use(middleware.HashCheckerMiddleware(app.cfg.HashKey, app.logger))
@@ -100,19 +182,15 @@ func (app *ServerApp) Run() error {
 	router.GET(`/`, app.getMetricHandler.List)
 	router.GET("/health", app.healthHandler.GetHealth)
 	router.GET("/ping", app.dbHealthHandler.GetDBHealth)
+	router.GET("/metrics", app.getMetricHandler.Prometheus)
 	router.GET("/value/:type/:name", app.getMetricHandler.Get)
-	if decryptMW != nil {
-		router.POST("/value/", decryptMW, app.getMetricHandler.GetJSON)
-		router.POST("/update", decryptMW, app.storeMetricHandler.StoreJSON)
-		router.POST("/updates/", decryptMW, app.storeMetricHandler.StoreBatchJSON)
-		router.POST("/update/:type/:name/:value", decryptMW, app.storeMetricHandler.Store)
-	} else {
-		// fallback без дешифровки
-		router.POST("/value/", app.getMetricHandler.GetJSON)
-		router.POST("/update", app.storeMetricHandler.StoreJSON)
-		router.POST("/updates/", app.storeMetricHandler.StoreBatchJSON)
-		router.POST("/update/:type/:name/:value", app.storeMetricHandler.Store)
-	}
+	router.GET("/api/v1/series/:type/:name", app.seriesHandler.Get)
+	router.GET("/api/v1/agents", app.agentHandler.List)
+	router.POST("/api/v1/agents", withMiddleware(updateMW, app.agentHandler.Register)...)
+	router.POST("/value/", withMiddleware(payloadMW, app.getMetricHandler.GetJSON)...)
+	router.POST("/update", withMiddleware(updateMW, app.storeMetricHandler.StoreJSON)...)
+	router.POST("/updates/", withMiddleware(updateMW, app.storeMetricHandler.StoreBatchJSON)...)
+	router.POST("/update/:type/:name/:value", withMiddleware(updateMW, app.storeMetricHandler.Store)...)
 	router.Any(`/:path`, app.commonHandler.ServeHTTP)
 
 	pprofGroup := router.Group("/debug/pprof")
@@ -129,10 +207,117 @@ func (app *ServerApp) Run() error {
 		pprofGroup.GET("/mutex", gin.WrapH(http.HandlerFunc(pprof.Handler("mutex").ServeHTTP)))
 	}
 
-	return http.ListenAndServe(app.cfg.Address, router)
+	if app.grpcServer != nil {
+		listener, err := net.Listen("tcp", app.cfg.GRPCAddress)
+		if err != nil {
+			return fmt.Errorf("failed to listen gRPC address %s: %w", app.cfg.GRPCAddress, err)
+		}
+
+		go func() {
+			app.logger.Info("Starting gRPC server...", zap.String("address", app.cfg.GRPCAddress))
+			if err := app.grpcServer.Serve(listener); err != nil {
+				app.logger.Error("gRPC server stopped", zap.Error(err))
+			}
+		}()
+	}
+
+	if app.alertEngine != nil {
+		// Движок оповещений читает хранилище, поэтому Run дожидается его остановки до возврата.
+		alertCtx, stopAlerts := context.WithCancel(ctx)
+		alertsDone := make(chan struct{})
+		go func() {
+			defer close(alertsDone)
+			app.alertEngine.Run(alertCtx, app.cfg.AlertInterval)
+		}()
+		defer func() {
+			stopAlerts()
+			<-alertsDone
+		}()
+	}
+
+	httpServer := &http.Server{
+		Addr:    app.cfg.Address,
+		Handler: router,
+	}
+
+	var adminServer *http.Server
+	if app.cfg.AdminAddress != "" {
+		adminMux := http.NewServeMux()
+		adminMux.Handle("/metrics", app.telemetry.Handler())
+		adminServer = &http.Server{
+			Addr:    app.cfg.AdminAddress,
+			Handler: adminMux,
+		}
+
+		go func() {
+			app.logger.Info("Starting admin server...", zap.String("address", app.cfg.AdminAddress))
+			if err := adminServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				app.logger.Error("Admin server stopped", zap.Error(err))
+			}
+		}()
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- httpServer.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		app.stopGRPC(ctx)
+		if adminServer != nil {
+			_ = adminServer.Close()
+		}
+		return err
+	case <-ctx.Done():
+	}
+
+	app.logger.Info("Shutting down server...")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), constants.ShutdownTimeout)
+	defer cancel()
+
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		app.logger.Error("HTTP server did not drain in time", zap.Error(err))
+	}
+	app.stopGRPC(shutdownCtx)
+	if adminServer != nil {
+		if err := adminServer.Shutdown(shutdownCtx); err != nil {
+			app.logger.Error("Admin server did not drain in time", zap.Error(err))
+		}
+	}
+
+	return nil
+}
+
+// stopGRPC останавливает gRPC-сервер, дожидаясь завершения активных вызовов до отмены ctx.
+func (app *ServerApp) stopGRPC(ctx context.Context) {
+	if app.grpcServer == nil {
+		return
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		app.grpcServer.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		app.grpcServer.Stop()
+	}
+}
+
+// withMiddleware возвращает цепочку обработчиков маршрута: middleware, затем handler.
+func withMiddleware(middleware []gin.HandlerFunc, handler gin.HandlerFunc) []gin.HandlerFunc {
+	chain := make([]gin.HandlerFunc, 0, len(middleware)+1)
+	chain = append(chain, middleware...)
+	return append(chain, handler)
 }
 
-// Close завершает работу приложения.
+// Close освобождает ресурсы приложения после остановки Run:
+// файловое хранилище выполняет финальное сохранение, пул соединений с базой данных закрывается.
 func (app *ServerApp) Close() {
 	app.storage.Close()
 }
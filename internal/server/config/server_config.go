@@ -3,6 +3,8 @@ package config
 import (
 	"fmt"
 	"github.com/jessevdk/go-flags"
+	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/configfile"
+	"net"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -11,7 +13,13 @@ import (
 )
 
 // ServerConfig содержит конфигурационные параметры для запуска сервера.
+//
+// Значения берутся в порядке приоритета: флаги командной строки, переменные окружения,
+// JSON-файл конфигурации (см. fileConfig), значения по умолчанию.
 type ServerConfig struct {
+	// ConfigPath — путь к JSON-файлу конфигурации.
+	ConfigPath string `short:"c" long:"config" env:"CONFIG" description:"Path to JSON config file"`
+
 	// Address — Адрес хоста сервера.
 	Address string `short:"a" long:"address" env:"ADDRESS" default:"localhost:8080" description:"Server host address"`
 
@@ -27,6 +35,12 @@ type ServerConfig struct {
 	// FileStoragePath — Путь к файлу хранения метрик.
 	FileStoragePath string `short:"f" long:"path" env:"FILE_STORAGE_PATH" default:"" description:"Path to file with metrics data"`
 
+	// SnapshotKeep — число предыдущих снимков метрик, хранимых рядом с файлом FileStoragePath.
+	SnapshotKeep int `long:"snapshot-keep" env:"SNAPSHOT_KEEP" default:"2" description:"Number of previous metrics snapshots to keep next to the storage file"`
+
+	// WAL — включает журнал предзаписи принятых обновлений между снимками (при StoreInterval > 0).
+	WAL bool `long:"wal" env:"WAL" description:"Write accepted updates to a write-ahead log between snapshots"`
+
 	// RestoreRaw — Флаг восстановления ранее сохранённых метрик.
 	RestoreRaw string `short:"r" long:"restore" env:"RESTORE" description:"Flag indicating whether to load previously saved metrics data" no-ini:"true"`
 
@@ -39,19 +53,109 @@ type ServerConfig struct {
 	// HashKey — Секретный ключ для хеширования.
 	HashKey string `long:"key" short:"k" env:"KEY" description:"Secret key for hashing"`
 
-	CryptoPrivateKeyPath string `short:"c" long:"crypto-key" env:"CRYPTO_KEY" description:"path to private key"`
+	// CryptoPrivateKeyPath — путь к закрытому ключу для расшифровки данных от агентов.
+	CryptoPrivateKeyPath string `long:"crypto-key" env:"CRYPTO_KEY" description:"path to private key"`
+
+	// CryptoLegacy — разрешает приём данных, зашифрованных устаревшим форматом RSA PKCS#1 v1.5.
+	CryptoLegacy bool `long:"crypto-legacy" env:"CRYPTO_LEGACY" description:"Accept payloads encrypted with legacy RSA PKCS#1 v1.5"`
+
+	// TrustedSubnet — доверенная подсеть в нотации CIDR. Если задана, метрики принимаются
+	// только от агентов, чей адрес из X-Real-IP (метаданных x-real-ip для gRPC) входит в эту подсеть.
+	// Запросы без этого адреса отклоняются на обоих транспортах.
+	TrustedSubnet string `short:"t" long:"trusted-subnet" env:"TRUSTED_SUBNET" description:"Trusted subnet in CIDR notation; HTTP requests without X-Real-IP and gRPC calls without x-real-ip metadata are rejected"`
+
+	// TrustedNet — доверенная подсеть, вычисляемая на основании TrustedSubnet.
+	TrustedNet *net.IPNet `ignored:"true"`
+
+	// GRPCAddress — адрес gRPC-сервера. Пустое значение отключает gRPC.
+	GRPCAddress string `short:"g" long:"grpc-address" env:"GRPC_ADDRESS" description:"gRPC server address"`
+
+	// HistorySize — число последних точек истории каждой серии, хранимых в памяти. 0 отключает историю.
+	HistorySize int `long:"history-size" env:"HISTORY_SIZE" default:"1000" description:"Number of samples kept in memory per series"`
+
+	// HistoryRetentionInSeconds — срок (в секундах) хранения точек истории в таблице metric_samples.
+	// Более старые точки периодически удаляются; 0 отключает удаление. Используется только с базой данных.
+	HistoryRetentionInSeconds int `long:"history-retention" env:"HISTORY_RETENTION" default:"604800" description:"Retention in seconds for history samples stored in the database, 0 keeps them forever"`
+
+	// HistoryRetention — срок хранения в формате time.Duration, вычисляется на основе HistoryRetentionInSeconds.
+	HistoryRetention time.Duration `ignored:"true"`
+
+	// AdminAddress — адрес служебного HTTP-сервера с метриками самого сервера (/metrics). Пустое значение отключает его.
+	AdminAddress string `long:"admin-address" env:"ADMIN_ADDRESS" description:"Admin server address exposing the server's own metrics"`
+
+	// AlertRulesPath — путь к JSON-файлу правил оповещения. Пустое значение отключает оповещения.
+	AlertRulesPath string `long:"alert-rules" env:"ALERT_RULES" description:"Path to JSON file with alerting rules and webhooks"`
+
+	// AlertIntervalInSeconds — интервал (в секундах) вычисления правил оповещения.
+	AlertIntervalInSeconds int `long:"alert-interval" env:"ALERT_INTERVAL" default:"15" description:"Interval in seconds for evaluating alerting rules"`
+
+	// AlertInterval — интервал в формате time.Duration, вычисляется на основе AlertIntervalInSeconds.
+	AlertInterval time.Duration `ignored:"true"`
 }
 
-// NewServerConfig создаёт и инициализирует конфигурацию сервера на основе аргументов командной строки.
-// При необходимости также создаёт директорию для хранения метрик.
-func NewServerConfig(cliArgs []string) (*ServerConfig, error) {
-	config := &ServerConfig{}
-	parser := flags.NewParser(config, flags.Default)
+// parserOptions — опции парсера go-flags. Ошибки не печатаются, а возвращаются вызывающему коду.
+const parserOptions = flags.HelpFlag | flags.PassDoubleDash
 
-	_, err := parser.ParseArgs(cliArgs)
+// fileConfig описывает JSON-файл конфигурации сервера. Интервалы задаются строками, например "1s".
+type fileConfig struct {
+	Address          *string              `json:"address"`
+	LogLevel         *string              `json:"log_level"`
+	Restore          *bool                `json:"restore"`
+	StoreInterval    *configfile.Duration `json:"store_interval"`
+	StoreFile        *string              `json:"store_file"`
+	SnapshotKeep     *int64               `json:"snapshot_keep"`
+	WAL              *bool                `json:"wal"`
+	DatabaseDSN      *string              `json:"database_dsn"`
+	Key              *string              `json:"key"`
+	CryptoKey        *string              `json:"crypto_key"`
+	CryptoLegacy     *bool                `json:"crypto_legacy"`
+	TrustedSubnet    *string              `json:"trusted_subnet"`
+	GRPCAddress      *string              `json:"grpc_address"`
+	HistorySize      *int64               `json:"history_size"`
+	HistoryRetention *configfile.Duration `json:"history_retention"`
+	AdminAddress     *string              `json:"admin_address"`
+	AlertRules       *string              `json:"alert_rules"`
+	AlertInterval    *configfile.Duration `json:"alert_interval"`
+}
+
+// values сопоставляет поля файла длинным именам флагов ServerConfig.
+func (f *fileConfig) values() (configfile.Values, error) {
+	values := configfile.Values{}
+	values.String("address", f.Address)
+	values.String("log", f.LogLevel)
+	values.Bool("restore", f.Restore)
+	values.String("path", f.StoreFile)
+	values.Int("snapshot-keep", f.SnapshotKeep)
+	values.Bool("wal", f.WAL)
+	values.String("database", f.DatabaseDSN)
+	values.String("key", f.Key)
+	values.String("crypto-key", f.CryptoKey)
+	values.Bool("crypto-legacy", f.CryptoLegacy)
+	values.String("trusted-subnet", f.TrustedSubnet)
+	values.String("grpc-address", f.GRPCAddress)
+	values.Int("history-size", f.HistorySize)
+	values.String("admin-address", f.AdminAddress)
+	values.String("alert-rules", f.AlertRules)
+
+	if err := values.Duration("interval", f.StoreInterval, time.Second); err != nil {
+		return nil, err
+	}
+	if err := values.Duration("alert-interval", f.AlertInterval, time.Second); err != nil {
+		return nil, err
+	}
+	if err := values.Duration("history-retention", f.HistoryRetention, time.Second); err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}
+
+// NewServerConfig создаёт и инициализирует конфигурацию сервера на основе аргументов командной строки,
+// переменных окружения и файла конфигурации. При необходимости также создаёт директорию для хранения метрик.
+func NewServerConfig(cliArgs []string) (*ServerConfig, error) {
+	config, err := parseServerConfig(cliArgs)
 	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+		return nil, err
 	}
 
 	if config.FileStoragePath == "" {
@@ -64,6 +168,11 @@ func NewServerConfig(cliArgs []string) (*ServerConfig, error) {
 
 	config.StoreInterval = time.Duration(config.StoreIntervalInSeconds) * time.Second
 
+	if config.AlertIntervalInSeconds <= 0 {
+		return nil, fmt.Errorf("invalid value for --alert-interval: %d", config.AlertIntervalInSeconds)
+	}
+	config.AlertInterval = time.Duration(config.AlertIntervalInSeconds) * time.Second
+
 	if config.RestoreRaw != "" {
 		val, err := strconv.ParseBool(config.RestoreRaw)
 		if err != nil {
@@ -72,9 +181,58 @@ func NewServerConfig(cliArgs []string) (*ServerConfig, error) {
 		config.Restore = val
 	}
 
-	//workaround for hashkey when on test set env variable and command line argument, and for this param priority of value should be from env
-	if keyFromEnv := os.Getenv("KEY"); keyFromEnv != "" {
-		config.HashKey = keyFromEnv
+	if config.SnapshotKeep < 0 {
+		return nil, fmt.Errorf("invalid value for --snapshot-keep: %d", config.SnapshotKeep)
+	}
+
+	if config.HistorySize < 0 {
+		return nil, fmt.Errorf("invalid value for --history-size: %d", config.HistorySize)
+	}
+
+	if config.HistoryRetentionInSeconds < 0 {
+		return nil, fmt.Errorf("invalid value for --history-retention: %d", config.HistoryRetentionInSeconds)
+	}
+	config.HistoryRetention = time.Duration(config.HistoryRetentionInSeconds) * time.Second
+
+	if config.TrustedSubnet != "" {
+		_, trustedNet, err := net.ParseCIDR(config.TrustedSubnet)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for --trusted-subnet: %v", err)
+		}
+		config.TrustedNet = trustedNet
+	}
+
+	return config, nil
+}
+
+// parseServerConfig разбирает аргументы и переменные окружения. Если в них указан файл конфигурации,
+// разбор повторяется с подставленными из файла значениями по умолчанию.
+func parseServerConfig(cliArgs []string) (*ServerConfig, error) {
+	config := &ServerConfig{}
+	if _, err := flags.NewParser(config, parserOptions).ParseArgs(cliArgs); err != nil {
+		return nil, err
+	}
+
+	if config.ConfigPath == "" {
+		return config, nil
+	}
+
+	var file fileConfig
+	if err := configfile.Load(config.ConfigPath, &file); err != nil {
+		return nil, err
+	}
+	values, err := file.values()
+	if err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", config.ConfigPath, err)
+	}
+
+	config = &ServerConfig{}
+	parser := flags.NewParser(config, parserOptions)
+	if err := configfile.ApplyDefaults(parser, values); err != nil {
+		return nil, err
+	}
+	if _, err := parser.ParseArgs(cliArgs); err != nil {
+		return nil, err
 	}
 
 	return config, nil
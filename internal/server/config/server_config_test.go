@@ -2,7 +2,11 @@ package config
 
 import (
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestServerConfig_FromEnv(t *testing.T) {
@@ -40,3 +44,78 @@ func TestServerConfig_RestoreArgument_FromCommandLineArg(t *testing.T) {
 	assert.Equal(t, expectedRestore, config.Restore)
 
 }
+
+func TestServerConfig_TrustedSubnet_FromEnv(t *testing.T) {
+	t.Setenv("TRUSTED_SUBNET", "192.168.1.0/24")
+
+	config, err := NewServerConfig([]string{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "192.168.1.0/24", config.TrustedNet.String())
+}
+
+func TestServerConfig_TrustedSubnet_Invalid(t *testing.T) {
+	_, err := NewServerConfig([]string{"-t=not-a-cidr"})
+
+	assert.Error(t, err)
+}
+
+func TestServerConfig_SnapshotKeep_Invalid(t *testing.T) {
+	_, err := NewServerConfig([]string{"--snapshot-keep=-1"})
+
+	assert.Error(t, err)
+}
+
+func TestServerConfig_HistoryRetention_Invalid(t *testing.T) {
+	_, err := NewServerConfig([]string{"--history-retention=-1"})
+
+	assert.Error(t, err)
+}
+
+func TestServerConfig_FromConfigFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "server.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{
+		"address": "localhost:9090",
+		"restore": true,
+		"store_interval": "1m",
+		"store_file": "`+filepath.ToSlash(filepath.Join(dir, "metrics.json"))+`",
+		"database_dsn": "postgres://file",
+		"crypto_key": "/tmp/private.pem",
+		"history_size": 50,
+		"history_retention": "24h",
+		"snapshot_keep": 5,
+		"wal": true,
+		"alert_rules": "/etc/osmetrics/rules.json",
+		"alert_interval": "30s",
+		"admin_address": "localhost:9091"
+	}`), 0600))
+
+	t.Setenv("CONFIG", path)
+	t.Setenv("DATABASE_DSN", "postgres://env")
+
+	config, err := NewServerConfig([]string{"-a=localhost:1234"})
+	require.NoError(t, err)
+
+	assert.Equal(t, "localhost:1234", config.Address)
+	assert.True(t, config.Restore)
+	assert.Equal(t, time.Minute, config.StoreInterval)
+	assert.Equal(t, "postgres://env", config.DatabaseConnection)
+	assert.Equal(t, "/tmp/private.pem", config.CryptoPrivateKeyPath)
+	assert.Equal(t, 50, config.HistorySize)
+	assert.Equal(t, 24*time.Hour, config.HistoryRetention)
+	assert.Equal(t, 5, config.SnapshotKeep)
+	assert.True(t, config.WAL)
+	assert.Equal(t, "/etc/osmetrics/rules.json", config.AlertRulesPath)
+	assert.Equal(t, 30*time.Second, config.AlertInterval)
+	assert.Equal(t, "localhost:9091", config.AdminAddress)
+}
+
+func TestServerConfig_ConfigFileWithUnknownField(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "server.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"adress": "localhost:9090"}`), 0600))
+
+	_, err := NewServerConfig([]string{"-c", path})
+
+	assert.Error(t, err)
+}
@@ -1,12 +1,32 @@
 package constants
 
+import "time"
+
 const (
+	// ShutdownTimeout — максимальное время ожидания завершения обрабатываемых запросов при остановке сервера.
+	ShutdownTimeout = 10 * time.Second
+
+	// HistoryCleanupInterval — период удаления устаревших точек истории из базы данных.
+	HistoryCleanupInterval = 10 * time.Minute
+
+	// AlertWebhookTimeout — максимальное время отправки одного оповещения в webhook.
+	AlertWebhookTimeout = 5 * time.Second
+
 	// URLParamMetricType — имя параметра URL, указывающее тип метрики (например, "gauge" или "counter").
 	URLParamMetricType = "type"
 
 	// URLParamMetricName — имя параметра URL, указывающее название метрики.
 	URLParamMetricName = "name"
 
+	// QueryParamLabelMatcher — имя параметра запроса с условием на метку метрики (например, "host=web-1").
+	QueryParamLabelMatcher = "match"
+
+	// QueryParamFrom — имя параметра запроса с началом интервала истории (RFC 3339 или секунды Unix).
+	QueryParamFrom = "from"
+
+	// QueryParamTo — имя параметра запроса с концом интервала истории (RFC 3339 или секунды Unix).
+	QueryParamTo = "to"
+
 	// URLParamMetricValue — имя параметра URL, указывающее значение метрики.
 	URLParamMetricValue = "value"
 
@@ -18,4 +38,30 @@ const (
 
 	// HashHeaderName — имя HTTP-заголовка, содержащего хеш-сумму (SHA256) тела запроса для проверки целостности данных.
 	HashHeaderName = "HashSHA256"
+
+	// HashMetadataKey — ключ gRPC-метаданных с HMAC-SHA256 подписью запроса.
+	HashMetadataKey = "hashsha256"
+
+	// HashTimestampMetadataKey — ключ gRPC-метаданных со временем открытия потока (секунды Unix),
+	// которое входит в подпись потоковых вызовов.
+	HashTimestampMetadataKey = "x-hash-timestamp"
+
+	// HashNonceMetadataKey — ключ gRPC-метаданных с одноразовым значением потока,
+	// которое входит в подпись потока и каждого его сообщения.
+	HashNonceMetadataKey = "x-hash-nonce"
+
+	// StreamSignatureMaxAge — максимальный возраст подписи потокового gRPC-вызова.
+	StreamSignatureMaxAge = 5 * time.Minute
+
+	// RealIPHeaderName — имя HTTP-заголовка с IP-адресом агента, отправившего запрос.
+	RealIPHeaderName = "X-Real-IP"
+
+	// RealIPMetadataKey — ключ gRPC-метаданных с IP-адресом агента, отправившего запрос.
+	RealIPMetadataKey = "x-real-ip"
+
+	// AgentIDHeaderName — имя HTTP-заголовка с идентификатором агента, отправившего запрос.
+	AgentIDHeaderName = "X-Agent-ID"
+
+	// AgentIDMetadataKey — ключ gRPC-метаданных с идентификатором агента, отправившего запрос.
+	AgentIDMetadataKey = "x-agent-id"
 )
@@ -0,0 +1,14 @@
+-- +goose Up
+-- Метрика идентифицируется парой (тип, имя): gauge и counter с одинаковым именем хранятся раздельно
+ALTER TABLE metrics DROP CONSTRAINT metrics_pkey;
+ALTER TABLE metrics ADD PRIMARY KEY (Type, Id);
+
+-- +goose Down
+-- При откате из метрик с одинаковым именем сохраняется gauge
+DELETE FROM metrics AS counter
+USING metrics AS gauge
+WHERE counter.Id = gauge.Id
+  AND counter.Type = 'counter'
+  AND gauge.Type = 'gauge';
+ALTER TABLE metrics DROP CONSTRAINT metrics_pkey;
+ALTER TABLE metrics ADD PRIMARY KEY (Id);
@@ -0,0 +1,14 @@
+-- +goose Up
+-- Метки метрики: labels хранит сами метки, labels_key — их канонический вид, входящий в ключ серии
+ALTER TABLE metrics ADD COLUMN labels JSONB NOT NULL DEFAULT '{}';
+ALTER TABLE metrics ADD COLUMN labels_key TEXT NOT NULL DEFAULT '';
+ALTER TABLE metrics DROP CONSTRAINT metrics_pkey;
+ALTER TABLE metrics ADD PRIMARY KEY (Type, Id, labels_key);
+
+-- +goose Down
+-- При откате остаются только серии без меток
+DELETE FROM metrics WHERE labels_key <> '';
+ALTER TABLE metrics DROP CONSTRAINT metrics_pkey;
+ALTER TABLE metrics ADD PRIMARY KEY (Type, Id);
+ALTER TABLE metrics DROP COLUMN labels_key;
+ALTER TABLE metrics DROP COLUMN labels;
@@ -0,0 +1,15 @@
+-- +goose Up
+-- История значений: по точке на каждое принятое значение серии.
+-- Для counter хранится накопленное значение после приёма
+CREATE TABLE metric_samples (
+    type VARCHAR(10) NOT NULL,
+    id VARCHAR(50) NOT NULL,
+    labels_key TEXT NOT NULL DEFAULT '',
+    ts TIMESTAMPTZ NOT NULL DEFAULT now(),
+    delta BIGINT,
+    value DOUBLE PRECISION
+);
+CREATE INDEX metric_samples_series_ts_idx ON metric_samples (type, id, labels_key, ts);
+
+-- +goose Down
+DROP TABLE metric_samples;
@@ -0,0 +1,6 @@
+-- +goose Up
+-- Индекс для периодического удаления устаревших точек истории по ts.
+CREATE INDEX metric_samples_ts_idx ON metric_samples (ts);
+
+-- +goose Down
+DROP INDEX metric_samples_ts_idx;
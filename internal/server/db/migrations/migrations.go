@@ -0,0 +1,9 @@
+// Package migrations содержит SQL-миграции схемы базы данных сервера, встроенные в бинарный файл.
+package migrations
+
+import "embed"
+
+// FS — файлы миграций goose.
+//
+//go:embed *.sql
+var FS embed.FS
@@ -0,0 +1,91 @@
+package grpcserver
+
+import (
+	"context"
+	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/metricspb"
+	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/model"
+	"github.com/ruslanDantsov/osmetrics-server/internal/server/agents"
+	"github.com/ruslanDantsov/osmetrics-server/internal/server/constants"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// AgentRegistry определяет интерфейс реестра агентов.
+type AgentRegistry interface {
+	Register(info model.AgentInfo, address string) agents.Record
+	Seen(id, address string)
+}
+
+// AgentsServer реализует gRPC-сервис metricspb.AgentsServer поверх реестра агентов.
+type AgentsServer struct {
+	metricspb.UnimplementedAgentsServer
+
+	Registry AgentRegistry
+	Log      zap.Logger
+}
+
+// NewAgentsServer создаёт новый экземпляр AgentsServer.
+func NewAgentsServer(registry AgentRegistry, log zap.Logger) *AgentsServer {
+	return &AgentsServer{
+		Registry: registry,
+		Log:      log,
+	}
+}
+
+// RegisterAgent сохраняет сведения об агенте в реестре.
+func (s *AgentsServer) RegisterAgent(ctx context.Context, req *metricspb.RegisterAgentRequest) (*metricspb.RegisterAgentResponse, error) {
+	info, err := metricspb.AgentInfoToModel(req.GetAgent())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	record := s.Registry.Register(info, ipString(ctx))
+	s.Log.Info("Agent registered", zap.String("agent_id", record.ID), zap.String("version", record.Version), zap.String("address", record.Address))
+
+	return &metricspb.RegisterAgentResponse{}, nil
+}
+
+// AgentSeenUnaryInterceptor возвращает unary-интерцептор, отмечающий в registry активность агента
+// из метаданных x-agent-id после успешной записи метрик.
+func AgentSeenUnaryInterceptor(registry AgentRegistry) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		resp, err := handler(ctx, req)
+		if err == nil {
+			markAgentSeen(ctx, info.FullMethod, registry)
+		}
+		return resp, err
+	}
+}
+
+// AgentSeenStreamInterceptor возвращает stream-интерцептор, отмечающий активность агента
+// при открытии потока записи метрик: поток может быть открыт долго.
+func AgentSeenStreamInterceptor(registry AgentRegistry) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		markAgentSeen(ss.Context(), info.FullMethod, registry)
+		return handler(srv, ss)
+	}
+}
+
+func markAgentSeen(ctx context.Context, method string, registry AgentRegistry) {
+	if _, ok := ingestMethods[method]; !ok {
+		return
+	}
+
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return
+	}
+	if values := md.Get(constants.AgentIDMetadataKey); len(values) > 0 {
+		registry.Seen(values[0], ipString(ctx))
+	}
+}
+
+func ipString(ctx context.Context) string {
+	if ip := realIP(ctx); ip != nil {
+		return ip.String()
+	}
+	return ""
+}
@@ -0,0 +1,68 @@
+package grpcserver
+
+import (
+	"context"
+	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/metricspb"
+	"github.com/ruslanDantsov/osmetrics-server/internal/server/agents"
+	"github.com/ruslanDantsov/osmetrics-server/internal/server/repository/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"net"
+	"testing"
+)
+
+func TestAgentsServer_RegisterAndSeen(t *testing.T) {
+	registry := agents.NewRegistry()
+	listener := bufconn.Listen(1 << 20)
+
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(AgentSeenUnaryInterceptor(registry)),
+		grpc.ChainStreamInterceptor(AgentSeenStreamInterceptor(registry)),
+	)
+	metricspb.RegisterMetricsServer(server, NewMetricsServer(memory.NewMemStorage(*zap.NewNop(), 0), *zap.NewNop()))
+	metricspb.RegisterAgentsServer(server, NewAgentsServer(registry, *zap.NewNop()))
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-agent-id", "web-2", "x-real-ip", "10.0.0.2")
+
+	_, err = metricspb.NewAgentsClient(conn).RegisterAgent(ctx, &metricspb.RegisterAgentRequest{
+		Agent: &metricspb.AgentInfo{Id: "web-1", Version: "1.2.0", Cpus: 4},
+	})
+	require.NoError(t, err)
+
+	_, err = metricspb.NewAgentsClient(conn).RegisterAgent(ctx, &metricspb.RegisterAgentRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = metricspb.NewMetricsClient(conn).UpdateMetric(ctx, &metricspb.UpdateMetricRequest{
+		Metric: &metricspb.Metric{Id: "PollCount", Type: metricspb.MetricType_METRIC_TYPE_COUNTER, Delta: 1},
+	})
+	require.NoError(t, err)
+
+	records := registry.List()
+	require.Len(t, records, 2)
+	assert.Equal(t, "web-1", records[0].ID)
+	assert.Equal(t, 4, records[0].CPUs)
+	assert.True(t, records[0].Registered)
+	assert.Equal(t, "web-2", records[1].ID)
+	assert.Equal(t, "10.0.0.2", records[1].Address)
+	assert.False(t, records[1].Registered)
+}
@@ -0,0 +1,211 @@
+package grpcserver
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/metricspb"
+	"github.com/ruslanDantsov/osmetrics-server/internal/server/constants"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// HashUnaryInterceptor возвращает unary-интерцептор, проверяющий HMAC-SHA256 подпись запросов на запись.
+//
+// Подпись передаётся в метаданных hashsha256 и вычисляется ключом hashKey
+// от детерминированно сериализованного сообщения запроса.
+// Запросы без подписи или с неверной подписью отклоняются с кодом Unauthenticated.
+// Пустой hashKey отключает проверку.
+func HashUnaryInterceptor(hashKey string, logger *zap.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if !needsSignature(hashKey, info.FullMethod) {
+			return handler(ctx, req)
+		}
+
+		msg, ok := req.(proto.Message)
+		if !ok {
+			return nil, status.Error(codes.Internal, "unsupported request type")
+		}
+		body, err := proto.MarshalOptions{Deterministic: true}.Marshal(msg)
+		if err != nil {
+			logger.Error("Failed to marshal gRPC request", zap.String("method", info.FullMethod), zap.Error(err))
+			return nil, status.Error(codes.Internal, "internal server error")
+		}
+
+		if err := checkSignature(ctx, hashKey, info.FullMethod, body, logger); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// HashStreamInterceptor возвращает stream-интерцептор, проверяющий подпись потоковых вызовов на запись.
+//
+// При открытии потока проверяется подпись имени метода, времени открытия (x-hash-timestamp)
+// и одноразового значения (x-hash-nonce). Подпись старше constants.StreamSignatureMaxAge
+// и повторно использованное одноразовое значение отклоняются.
+// Каждое сообщение потока несёт собственную подпись в поле hash: HMAC-SHA256 от одноразового
+// значения потока, порядкового номера сообщения и детерминированно сериализованной метрики без hash.
+// Поток с неподписанным или изменённым сообщением завершается с кодом Unauthenticated.
+func HashStreamInterceptor(hashKey string, logger *zap.Logger) grpc.StreamServerInterceptor {
+	nonces := newNonceCache(2 * constants.StreamSignatureMaxAge)
+
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if !needsSignature(hashKey, info.FullMethod) {
+			return handler(srv, ss)
+		}
+
+		ctx := ss.Context()
+		timestamp := firstMetadata(ctx, constants.HashTimestampMetadataKey)
+		nonce := firstMetadata(ctx, constants.HashNonceMetadataKey)
+		seconds, err := strconv.ParseInt(timestamp, 10, 64)
+		if err != nil || nonce == "" {
+			logger.Warn("gRPC stream without signature timestamp or nonce", zap.String("method", info.FullMethod))
+			return status.Error(codes.Unauthenticated, "invalid request signature")
+		}
+		if age := time.Since(time.Unix(seconds, 0)); age > constants.StreamSignatureMaxAge || age < -constants.StreamSignatureMaxAge {
+			logger.Warn("gRPC stream signature expired", zap.String("method", info.FullMethod), zap.Duration("age", age))
+			return status.Error(codes.Unauthenticated, "invalid request signature")
+		}
+
+		body := []byte(info.FullMethod + "\n" + timestamp + "\n" + nonce)
+		if err := checkSignature(ctx, hashKey, info.FullMethod, body, logger); err != nil {
+			return err
+		}
+		if !nonces.use(nonce, time.Now()) {
+			logger.Warn("gRPC stream nonce reused", zap.String("method", info.FullMethod))
+			return status.Error(codes.Unauthenticated, "invalid request signature")
+		}
+
+		return handler(srv, &signedServerStream{
+			ServerStream: ss,
+			hashKey:      hashKey,
+			nonce:        nonce,
+			method:       info.FullMethod,
+			logger:       logger,
+		})
+	}
+}
+
+// signedServerStream проверяет подпись каждого принятого сообщения потока.
+type signedServerStream struct {
+	grpc.ServerStream
+	hashKey string
+	nonce   string
+	method  string
+	seq     int64
+	logger  *zap.Logger
+}
+
+// RecvMsg принимает сообщение и сверяет его поле hash с подписью, вычисленной сервером.
+func (s *signedServerStream) RecvMsg(m any) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+
+	metric, ok := m.(*metricspb.Metric)
+	if !ok {
+		return status.Error(codes.Internal, "unsupported stream message type")
+	}
+
+	agentHash := metric.GetHash()
+	metric.Hash = ""
+	serverHash, err := streamMessageHash(s.hashKey, s.nonce, s.seq, metric)
+	if err != nil {
+		s.logger.Error("Failed to marshal gRPC stream message", zap.String("method", s.method), zap.Error(err))
+		return status.Error(codes.Internal, "internal server error")
+	}
+	if agentHash == "" || !hmac.Equal([]byte(serverHash), []byte(agentHash)) {
+		s.logger.Warn("Invalid gRPC stream message signature",
+			zap.String("method", s.method), zap.Int64("seq", s.seq), zap.Bool("signed", agentHash != ""))
+		return status.Error(codes.Unauthenticated, "invalid request signature")
+	}
+
+	s.seq++
+	return nil
+}
+
+// streamMessageHash вычисляет подпись сообщения metric с порядковым номером seq в потоке с одноразовым значением nonce.
+func streamMessageHash(hashKey, nonce string, seq int64, metric *metricspb.Metric) (string, error) {
+	body, err := proto.MarshalOptions{Deterministic: true}.Marshal(metric)
+	if err != nil {
+		return "", err
+	}
+
+	h := hmac.New(sha256.New, []byte(hashKey))
+	h.Write([]byte(nonce + "\n" + strconv.FormatInt(seq, 10) + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// nonceCache запоминает одноразовые значения потоков на время ttl, чтобы отклонять их повторное использование.
+type nonceCache struct {
+	mu   sync.Mutex
+	ttl  time.Duration
+	seen map[string]time.Time
+}
+
+func newNonceCache(ttl time.Duration) *nonceCache {
+	return &nonceCache{ttl: ttl, seen: make(map[string]time.Time)}
+}
+
+// use отмечает nonce использованным; возвращает false, если он уже встречался.
+// Значения старше ttl забываются: подписи с таким временем уже отклоняются по возрасту.
+func (c *nonceCache) use(nonce string, now time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for n, usedAt := range c.seen {
+		if now.Sub(usedAt) > c.ttl {
+			delete(c.seen, n)
+		}
+	}
+
+	if _, ok := c.seen[nonce]; ok {
+		return false
+	}
+	c.seen[nonce] = now
+	return true
+}
+
+// needsSignature сообщает, требует ли метод method подписи при ключе hashKey.
+func needsSignature(hashKey, method string) bool {
+	if hashKey == "" {
+		return false
+	}
+	_, ok := ingestMethods[method]
+	return ok
+}
+
+// checkSignature сравнивает подпись из метаданных с HMAC-SHA256 от body.
+func checkSignature(ctx context.Context, hashKey, method string, body []byte, logger *zap.Logger) error {
+	agentHash := firstMetadata(ctx, constants.HashMetadataKey)
+
+	h := hmac.New(sha256.New, []byte(hashKey))
+	h.Write(body)
+	serverHash := hex.EncodeToString(h.Sum(nil))
+
+	if agentHash == "" || !hmac.Equal([]byte(serverHash), []byte(agentHash)) {
+		logger.Warn("Invalid gRPC request signature", zap.String("method", method), zap.Bool("signed", agentHash != ""))
+		return status.Error(codes.Unauthenticated, "invalid request signature")
+	}
+
+	return nil
+}
+
+func firstMetadata(ctx context.Context, key string) string {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(key); len(values) > 0 {
+			return values[0]
+		}
+	}
+	return ""
+}
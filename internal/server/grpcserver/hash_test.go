@@ -0,0 +1,206 @@
+package grpcserver
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/metricspb"
+	"github.com/ruslanDantsov/osmetrics-server/internal/server/constants"
+	"github.com/ruslanDantsov/osmetrics-server/internal/server/repository/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+)
+
+const testHashKey = "secret"
+
+func newSignedTestClient(t *testing.T) metricspb.MetricsClient {
+	listener := bufconn.Listen(1 << 20)
+
+	server := grpc.NewServer(
+		grpc.UnaryInterceptor(HashUnaryInterceptor(testHashKey, zap.NewNop())),
+		grpc.StreamInterceptor(HashStreamInterceptor(testHashKey, zap.NewNop())),
+	)
+	metricspb.RegisterMetricsServer(server, NewMetricsServer(memory.NewMemStorage(*zap.NewNop(), 0), *zap.NewNop()))
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return metricspb.NewMetricsClient(conn)
+}
+
+func testSign(t *testing.T, key string, body []byte) string {
+	t.Helper()
+	h := hmac.New(sha256.New, []byte(key))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func TestHashUnaryInterceptor(t *testing.T) {
+	client := newSignedTestClient(t)
+	req := &metricspb.UpdateMetricRequest{Metric: &metricspb.Metric{Id: "Alloc", Type: metricspb.MetricType_METRIC_TYPE_GAUGE, Value: 1.5}}
+	body, err := proto.MarshalOptions{Deterministic: true}.Marshal(req)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		hash     string
+		wantCode codes.Code
+	}{
+		{name: "unsigned write is rejected", wantCode: codes.Unauthenticated},
+		{name: "wrong key is rejected", hash: testSign(t, "other", body), wantCode: codes.Unauthenticated},
+		{name: "signed write is accepted", hash: testSign(t, testHashKey, body), wantCode: codes.OK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.hash != "" {
+				ctx = metadata.AppendToOutgoingContext(ctx, constants.HashMetadataKey, tt.hash)
+			}
+
+			_, err := client.UpdateMetric(ctx, req)
+
+			assert.Equal(t, tt.wantCode, status.Code(err))
+		})
+	}
+
+	t.Run("read method is not checked", func(t *testing.T) {
+		_, err := client.GetMetric(context.Background(), &metricspb.GetMetricRequest{Id: "Alloc", Type: metricspb.MetricType_METRIC_TYPE_GAUGE})
+		assert.NoError(t, err)
+	})
+}
+
+// streamOpening возвращает метаданные открытия потока StreamMetrics, подписанные ключом key.
+func streamOpening(t *testing.T, key, timestamp, nonce string) metadata.MD {
+	method := metricspb.Metrics_StreamMetrics_FullMethodName
+	return metadata.Pairs(
+		constants.HashTimestampMetadataKey, timestamp,
+		constants.HashNonceMetadataKey, nonce,
+		constants.HashMetadataKey, testSign(t, key, []byte(method+"\n"+timestamp+"\n"+nonce)),
+	)
+}
+
+// signMetric заполняет подпись метрики с порядковым номером seq в потоке с одноразовым значением nonce.
+func signMetric(t *testing.T, nonce string, seq int, metric *metricspb.Metric) *metricspb.Metric {
+	body, err := proto.MarshalOptions{Deterministic: true}.Marshal(metric)
+	require.NoError(t, err)
+	metric.Hash = testSign(t, testHashKey, append([]byte(nonce+"\n"+strconv.Itoa(seq)+"\n"), body...))
+	return metric
+}
+
+func TestHashStreamInterceptor(t *testing.T) {
+	client := newSignedTestClient(t)
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	expired := strconv.FormatInt(time.Now().Add(-2*constants.StreamSignatureMaxAge).Unix(), 10)
+	counter := func() *metricspb.Metric {
+		return &metricspb.Metric{Id: "PollCount", Type: metricspb.MetricType_METRIC_TYPE_COUNTER, Delta: 1}
+	}
+
+	tests := []struct {
+		name     string
+		md       metadata.MD
+		messages func(nonce string) []*metricspb.Metric
+		wantCode codes.Code
+	}{
+		{
+			name:     "unsigned stream is rejected",
+			md:       metadata.MD{},
+			messages: func(string) []*metricspb.Metric { return []*metricspb.Metric{counter()} },
+			wantCode: codes.Unauthenticated,
+		},
+		{
+			name:     "wrong opening signature is rejected",
+			md:       streamOpening(t, "other", now, "nonce-1"),
+			messages: func(nonce string) []*metricspb.Metric { return []*metricspb.Metric{signMetric(t, nonce, 0, counter())} },
+			wantCode: codes.Unauthenticated,
+		},
+		{
+			name:     "expired opening signature is rejected",
+			md:       streamOpening(t, testHashKey, expired, "nonce-2"),
+			messages: func(nonce string) []*metricspb.Metric { return []*metricspb.Metric{signMetric(t, nonce, 0, counter())} },
+			wantCode: codes.Unauthenticated,
+		},
+		{
+			name:     "unsigned message is rejected",
+			md:       streamOpening(t, testHashKey, now, "nonce-3"),
+			messages: func(string) []*metricspb.Metric { return []*metricspb.Metric{counter()} },
+			wantCode: codes.Unauthenticated,
+		},
+		{
+			name: "tampered message is rejected",
+			md:   streamOpening(t, testHashKey, now, "nonce-4"),
+			messages: func(nonce string) []*metricspb.Metric {
+				metric := signMetric(t, nonce, 0, counter())
+				metric.Delta = 1000
+				return []*metricspb.Metric{metric}
+			},
+			wantCode: codes.Unauthenticated,
+		},
+		{
+			name: "reordered messages are rejected",
+			md:   streamOpening(t, testHashKey, now, "nonce-5"),
+			messages: func(nonce string) []*metricspb.Metric {
+				return []*metricspb.Metric{signMetric(t, nonce, 1, counter()), signMetric(t, nonce, 0, counter())}
+			},
+			wantCode: codes.Unauthenticated,
+		},
+		{
+			name: "signed stream is accepted",
+			md:   streamOpening(t, testHashKey, now, "nonce-6"),
+			messages: func(nonce string) []*metricspb.Metric {
+				return []*metricspb.Metric{signMetric(t, nonce, 0, counter()), signMetric(t, nonce, 1, counter())}
+			},
+			wantCode: codes.OK,
+		},
+		{
+			name: "reused nonce is rejected",
+			md:   streamOpening(t, testHashKey, now, "nonce-6"),
+			messages: func(nonce string) []*metricspb.Metric {
+				return []*metricspb.Metric{signMetric(t, nonce, 0, counter())}
+			},
+			wantCode: codes.Unauthenticated,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := metadata.NewOutgoingContext(context.Background(), tt.md)
+			nonce := ""
+			if values := tt.md.Get(constants.HashNonceMetadataKey); len(values) > 0 {
+				nonce = values[0]
+			}
+
+			stream, err := client.StreamMetrics(ctx)
+			require.NoError(t, err)
+			for _, metric := range tt.messages(nonce) {
+				_ = stream.Send(metric)
+			}
+			_, err = stream.CloseAndRecv()
+
+			assert.Equal(t, tt.wantCode, status.Code(err))
+		})
+	}
+}
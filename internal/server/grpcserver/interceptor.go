@@ -0,0 +1,91 @@
+package grpcserver
+
+import (
+	"context"
+	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/metricspb"
+	"github.com/ruslanDantsov/osmetrics-server/internal/server/constants"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"net"
+)
+
+// ingestMethods — методы, принимающие данные от агентов: запись метрик и регистрация агента.
+// Только к ним применяется проверка доверенной подсети.
+var ingestMethods = map[string]struct{}{
+	metricspb.Metrics_UpdateMetric_FullMethodName:  {},
+	metricspb.Metrics_UpdateMetrics_FullMethodName: {},
+	metricspb.Metrics_StreamMetrics_FullMethodName: {},
+	metricspb.Agents_RegisterAgent_FullMethodName:  {},
+}
+
+// TrustedSubnetUnaryInterceptor возвращает unary-интерцептор, отклоняющий запросы на запись метрик
+// с кодом PermissionDenied, если адрес агента не входит в доверенную подсеть trustedNet.
+func TrustedSubnetUnaryInterceptor(trustedNet *net.IPNet, logger *zap.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if err := checkTrustedSubnet(ctx, info.FullMethod, trustedNet, logger); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// TrustedSubnetStreamInterceptor возвращает stream-интерцептор с той же проверкой, что и TrustedSubnetUnaryInterceptor.
+func TrustedSubnetStreamInterceptor(trustedNet *net.IPNet, logger *zap.Logger) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := checkTrustedSubnet(ss.Context(), info.FullMethod, trustedNet, logger); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+// checkTrustedSubnet проверяет, что адрес агента из метаданных x-real-ip входит в trustedNet.
+// Как и в HTTP, запрос без этих метаданных или с некорректным адресом отклоняется:
+// адрес сетевого пира не используется, так как за прокси он не совпадает с адресом агента.
+func checkTrustedSubnet(ctx context.Context, method string, trustedNet *net.IPNet, logger *zap.Logger) error {
+	if trustedNet == nil {
+		return nil
+	}
+	if _, ok := ingestMethods[method]; !ok {
+		return nil
+	}
+
+	ip := metadataIP(ctx)
+	if ip == nil || !trustedNet.Contains(ip) {
+		logger.Warn("gRPC request from untrusted address", zap.String("method", method), zap.Stringer("real_ip", ip))
+		return status.Error(codes.PermissionDenied, "forbidden")
+	}
+
+	return nil
+}
+
+// metadataIP возвращает адрес агента из метаданных x-real-ip или nil, если его нет или он некорректен.
+func metadataIP(ctx context.Context) net.IP {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(constants.RealIPMetadataKey); len(values) > 0 {
+			return net.ParseIP(values[0])
+		}
+	}
+	return nil
+}
+
+// realIP возвращает адрес агента из метаданных x-real-ip, а при их отсутствии — адрес сетевого пира.
+func realIP(ctx context.Context) net.IP {
+	if ip := metadataIP(ctx); ip != nil {
+		return ip
+	}
+
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		host, _, err := net.SplitHostPort(p.Addr.String())
+		if err != nil {
+			return nil
+		}
+		return net.ParseIP(host)
+	}
+
+	return nil
+}
@@ -0,0 +1,56 @@
+package grpcserver
+
+import (
+	"context"
+	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/metricspb"
+	"github.com/ruslanDantsov/osmetrics-server/internal/server/constants"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"net"
+	"testing"
+)
+
+func TestTrustedSubnetUnaryInterceptor(t *testing.T) {
+	_, trustedNet, _ := net.ParseCIDR("10.0.0.0/8")
+	interceptor := TrustedSubnetUnaryInterceptor(trustedNet, zap.NewNop())
+	handler := func(ctx context.Context, req any) (any, error) { return "ok", nil }
+
+	tests := []struct {
+		name     string
+		method   string
+		realIP   string
+		peerAddr string
+		wantCode codes.Code
+	}{
+		{name: "trusted address", method: metricspb.Metrics_UpdateMetric_FullMethodName, realIP: "10.1.2.3", wantCode: codes.OK},
+		{name: "untrusted address", method: metricspb.Metrics_UpdateMetrics_FullMethodName, realIP: "192.168.0.1", wantCode: codes.PermissionDenied},
+		{name: "missing address", method: metricspb.Metrics_UpdateMetric_FullMethodName, wantCode: codes.PermissionDenied},
+		{name: "missing address from trusted peer", method: metricspb.Metrics_UpdateMetric_FullMethodName, peerAddr: "10.1.2.3:5000", wantCode: codes.PermissionDenied},
+		{name: "invalid address", method: metricspb.Metrics_UpdateMetric_FullMethodName, realIP: "not-an-ip", wantCode: codes.PermissionDenied},
+		{name: "read method is not checked", method: metricspb.Metrics_GetMetric_FullMethodName, realIP: "192.168.0.1", wantCode: codes.OK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.realIP != "" {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(constants.RealIPMetadataKey, tt.realIP))
+			}
+			if tt.peerAddr != "" {
+				addr, err := net.ResolveTCPAddr("tcp", tt.peerAddr)
+				require.NoError(t, err)
+				ctx = peer.NewContext(ctx, &peer.Peer{Addr: addr})
+			}
+
+			_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: tt.method}, handler)
+
+			assert.Equal(t, tt.wantCode, status.Code(err))
+		})
+	}
+}
@@ -0,0 +1,163 @@
+// Package grpcserver реализует gRPC-сервис метрик сервера.
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/metricspb"
+	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/model"
+	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/model/enum"
+	"github.com/ruslanDantsov/osmetrics-server/internal/server/lookup"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"io"
+)
+
+// MetricStorage определяет интерфейс хранилища, общий с HTTP-хендлерами.
+type MetricStorage interface {
+	GetKnownMetrics(ctx context.Context) []model.MetricKey
+	GetMetric(ctx context.Context, key model.MetricKey) (*model.Metrics, bool)
+	SaveMetric(ctx context.Context, metric *model.Metrics) (*model.Metrics, error)
+	SaveAllMetrics(ctx context.Context, metricList model.MetricsList) (model.MetricsList, error)
+}
+
+// MetricsServer реализует gRPC-сервис metricspb.MetricsServer поверх хранилища метрик.
+type MetricsServer struct {
+	metricspb.UnimplementedMetricsServer
+
+	Storage MetricStorage
+	Log     zap.Logger
+}
+
+// NewMetricsServer создаёт новый экземпляр MetricsServer.
+func NewMetricsServer(storage MetricStorage, log zap.Logger) *MetricsServer {
+	return &MetricsServer{
+		Storage: storage,
+		Log:     log,
+	}
+}
+
+// UpdateMetric сохраняет одну метрику и возвращает её состояние после сохранения.
+func (s *MetricsServer) UpdateMetric(ctx context.Context, req *metricspb.UpdateMetricRequest) (*metricspb.UpdateMetricResponse, error) {
+	metric, err := metricspb.ToModel(req.GetMetric())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	updated, err := s.Storage.SaveMetric(ctx, metric)
+	if err != nil {
+		s.Log.Error(err.Error())
+		return nil, status.Errorf(codes.Internal, "can't update metric: %v", err)
+	}
+
+	pbMetric, err := metricspb.FromModel(*updated)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &metricspb.UpdateMetricResponse{Metric: pbMetric}, nil
+}
+
+// UpdateMetrics сохраняет батч метрик.
+func (s *MetricsServer) UpdateMetrics(ctx context.Context, req *metricspb.UpdateMetricsRequest) (*metricspb.UpdateMetricsResponse, error) {
+	metricList := make(model.MetricsList, 0, len(req.GetMetrics()))
+	for _, pbMetric := range req.GetMetrics() {
+		metric, err := metricspb.ToModel(pbMetric)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		metricList = append(metricList, *metric)
+	}
+
+	if len(metricList) == 0 {
+		return &metricspb.UpdateMetricsResponse{}, nil
+	}
+
+	if _, err := s.Storage.SaveAllMetrics(ctx, metricList); err != nil {
+		s.Log.Error(err.Error())
+		return nil, status.Errorf(codes.Internal, "error on saving batch metrics: %v", err)
+	}
+
+	return &metricspb.UpdateMetricsResponse{}, nil
+}
+
+// GetMetric возвращает метрику по идентификатору и типу.
+func (s *MetricsServer) GetMetric(ctx context.Context, req *metricspb.GetMetricRequest) (*metricspb.GetMetricResponse, error) {
+	metricID, err := enum.ParseMetricID(req.GetId())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	metricType, err := req.GetType().ModelType()
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	metric, err := lookup.Find(ctx, s.Storage, metricType, metricID, req.GetLabels(), nil, s.Log)
+	if err != nil {
+		var ambiguous *lookup.AmbiguousError
+		if errors.As(err, &ambiguous) {
+			return nil, status.Errorf(codes.FailedPrecondition, "metric %s matches %d series, specify labels", metricID, ambiguous.Count)
+		}
+		return nil, status.Errorf(codes.NotFound, "metric %s not found", metricID)
+	}
+
+	pbMetric, err := metricspb.FromModel(*metric)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &metricspb.GetMetricResponse{Metric: pbMetric}, nil
+}
+
+// ListMetrics возвращает все известные метрики.
+func (s *MetricsServer) ListMetrics(ctx context.Context, _ *metricspb.ListMetricsRequest) (*metricspb.ListMetricsResponse, error) {
+	keys := s.Storage.GetKnownMetrics(ctx)
+
+	resp := &metricspb.ListMetricsResponse{Metrics: make([]*metricspb.Metric, 0, len(keys))}
+	for _, key := range keys {
+		metric, found := s.Storage.GetMetric(ctx, key)
+		if !found {
+			continue
+		}
+
+		pbMetric, err := metricspb.FromModel(*metric)
+		if err != nil {
+			s.Log.Warn(fmt.Sprintf("Skip metric %s: %v", key, err))
+			continue
+		}
+		resp.Metrics = append(resp.Metrics, pbMetric)
+	}
+
+	return resp, nil
+}
+
+// StreamMetrics принимает поток метрик от клиента и сохраняет каждую по мере поступления.
+// По завершении потока возвращает количество принятых метрик.
+func (s *MetricsServer) StreamMetrics(stream metricspb.Metrics_StreamMetricsServer) error {
+	ctx := stream.Context()
+
+	var received int64
+	for {
+		pbMetric, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return stream.SendAndClose(&metricspb.StreamMetricsResponse{Received: received})
+		}
+		if err != nil {
+			return err
+		}
+
+		metric, err := metricspb.ToModel(pbMetric)
+		if err != nil {
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+
+		if _, err := s.Storage.SaveMetric(ctx, metric); err != nil {
+			s.Log.Error(err.Error())
+			return status.Errorf(codes.Internal, "can't update metric: %v", err)
+		}
+		received++
+	}
+}
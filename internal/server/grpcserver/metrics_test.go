@@ -0,0 +1,136 @@
+package grpcserver
+
+import (
+	"context"
+	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/metricspb"
+	"github.com/ruslanDantsov/osmetrics-server/internal/server/repository/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"net"
+	"testing"
+)
+
+func newTestClient(t *testing.T) metricspb.MetricsClient {
+	listener := bufconn.Listen(1 << 20)
+
+	server := grpc.NewServer()
+	metricspb.RegisterMetricsServer(server, NewMetricsServer(memory.NewMemStorage(*zap.NewNop(), 0), *zap.NewNop()))
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return metricspb.NewMetricsClient(conn)
+}
+
+func TestMetricsServer_UpdateAndGetMetric(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	_, err := client.UpdateMetric(ctx, &metricspb.UpdateMetricRequest{
+		Metric: &metricspb.Metric{Id: "PollCount", Type: metricspb.MetricType_METRIC_TYPE_COUNTER, Delta: 2},
+	})
+	require.NoError(t, err)
+
+	resp, err := client.UpdateMetric(ctx, &metricspb.UpdateMetricRequest{
+		Metric: &metricspb.Metric{Id: "PollCount", Type: metricspb.MetricType_METRIC_TYPE_COUNTER, Delta: 3},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), resp.GetMetric().GetDelta())
+
+	got, err := client.GetMetric(ctx, &metricspb.GetMetricRequest{Id: "PollCount", Type: metricspb.MetricType_METRIC_TYPE_COUNTER})
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), got.GetMetric().GetDelta())
+}
+
+func TestMetricsServer_GetMetric_NotFound(t *testing.T) {
+	client := newTestClient(t)
+
+	_, err := client.GetMetric(context.Background(), &metricspb.GetMetricRequest{Id: "Alloc", Type: metricspb.MetricType_METRIC_TYPE_GAUGE})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestMetricsServer_GetMetric_LabeledSeries(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	save := func(agent string, value float64) {
+		_, err := client.UpdateMetric(ctx, &metricspb.UpdateMetricRequest{
+			Metric: &metricspb.Metric{Id: "Alloc", Type: metricspb.MetricType_METRIC_TYPE_GAUGE, Value: value,
+				Labels: map[string]string{"host": "web-1", "agent": agent}},
+		})
+		require.NoError(t, err)
+	}
+
+	save("a1", 1.5)
+	got, err := client.GetMetric(ctx, &metricspb.GetMetricRequest{Id: "Alloc", Type: metricspb.MetricType_METRIC_TYPE_GAUGE})
+	require.NoError(t, err)
+	assert.Equal(t, 1.5, got.GetMetric().GetValue())
+
+	save("a2", 2.5)
+	_, err = client.GetMetric(ctx, &metricspb.GetMetricRequest{Id: "Alloc", Type: metricspb.MetricType_METRIC_TYPE_GAUGE})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	got, err = client.GetMetric(ctx, &metricspb.GetMetricRequest{Id: "Alloc", Type: metricspb.MetricType_METRIC_TYPE_GAUGE,
+		Labels: map[string]string{"agent": "a2"}})
+	require.NoError(t, err)
+	assert.Equal(t, 2.5, got.GetMetric().GetValue())
+}
+
+func TestMetricsServer_UpdateMetric_InvalidType(t *testing.T) {
+	client := newTestClient(t)
+
+	_, err := client.UpdateMetric(context.Background(), &metricspb.UpdateMetricRequest{
+		Metric: &metricspb.Metric{Id: "Alloc"},
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestMetricsServer_UpdateMetricsAndList(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	_, err := client.UpdateMetrics(ctx, &metricspb.UpdateMetricsRequest{Metrics: []*metricspb.Metric{
+		{Id: "Alloc", Type: metricspb.MetricType_METRIC_TYPE_GAUGE, Value: 1.5},
+		{Id: "PollCount", Type: metricspb.MetricType_METRIC_TYPE_COUNTER, Delta: 1},
+	}})
+	require.NoError(t, err)
+
+	resp, err := client.ListMetrics(ctx, &metricspb.ListMetricsRequest{})
+	require.NoError(t, err)
+	assert.Len(t, resp.GetMetrics(), 2)
+}
+
+func TestMetricsServer_StreamMetrics(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	stream, err := client.StreamMetrics(ctx)
+	require.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		require.NoError(t, stream.Send(&metricspb.Metric{Id: "PollCount", Type: metricspb.MetricType_METRIC_TYPE_COUNTER, Delta: 1}))
+	}
+
+	resp, err := stream.CloseAndRecv()
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), resp.GetReceived())
+
+	got, err := client.GetMetric(ctx, &metricspb.GetMetricRequest{Id: "PollCount", Type: metricspb.MetricType_METRIC_TYPE_COUNTER})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), got.GetMetric().GetDelta())
+}
@@ -0,0 +1,70 @@
+// Package agent реализует HTTP-обработчики регистрации агентов и просмотра их реестра.
+package agent
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/model"
+	"github.com/ruslanDantsov/osmetrics-server/internal/server/agents"
+	"github.com/ruslanDantsov/osmetrics-server/internal/server/constants"
+	"go.uber.org/zap"
+	"net/http"
+	"strings"
+)
+
+// Registry определяет интерфейс реестра агентов.
+type Registry interface {
+	Register(info model.AgentInfo, address string) agents.Record
+	List() []agents.Record
+}
+
+// AgentHandler обрабатывает запросы регистрации агентов и чтения реестра.
+type AgentHandler struct {
+	Registry Registry
+	Log      zap.Logger
+}
+
+// NewAgentHandler создаёт новый экземпляр AgentHandler.
+func NewAgentHandler(registry Registry, log zap.Logger) *AgentHandler {
+	return &AgentHandler{
+		Registry: registry,
+		Log:      log,
+	}
+}
+
+// Register обрабатывает запрос POST /api/v1/agents с JSON-описанием агента model.AgentInfo
+// и возвращает запись реестра. Адрес агента берётся из заголовка X-Real-IP, а при его отсутствии —
+// из адреса клиента. Возвращает 400, если тело некорректно или не содержит идентификатор агента.
+func (h *AgentHandler) Register(ginContext *gin.Context) {
+	var info model.AgentInfo
+	if err := ginContext.ShouldBindJSON(&info); err != nil {
+		h.Log.Error(fmt.Sprintf("Error on unmarshal agent info from request. %v", err))
+		ginContext.JSON(http.StatusBadRequest, gin.H{"error": "invalid JSON"})
+		return
+	}
+
+	info.ID = strings.TrimSpace(info.ID)
+	if info.ID == "" {
+		ginContext.JSON(http.StatusBadRequest, gin.H{"error": "agent ID is required"})
+		return
+	}
+
+	record := h.Registry.Register(info, RequestAddress(ginContext))
+	h.Log.Info("Agent registered", zap.String("agent_id", record.ID), zap.String("version", record.Version), zap.String("address", record.Address))
+
+	ginContext.JSON(http.StatusOK, record)
+}
+
+// List обрабатывает запрос GET /api/v1/agents и возвращает JSON-массив известных агентов
+// со временем первой и последней активности.
+func (h *AgentHandler) List(ginContext *gin.Context) {
+	ginContext.JSON(http.StatusOK, h.Registry.List())
+}
+
+// RequestAddress возвращает адрес агента из заголовка X-Real-IP или, при его отсутствии, адрес клиента.
+func RequestAddress(ginContext *gin.Context) string {
+	if realIP := ginContext.GetHeader(constants.RealIPHeaderName); realIP != "" {
+		return realIP
+	}
+	return ginContext.ClientIP()
+}
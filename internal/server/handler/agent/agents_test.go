@@ -0,0 +1,72 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/ruslanDantsov/osmetrics-server/internal/server/agents"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func ExampleAgentHandler_List() {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+
+	handler := NewAgentHandler(agents.NewRegistry(), *zap.NewNop())
+	r.POST("/api/v1/agents", handler.Register)
+	r.GET("/api/v1/agents", handler.List)
+
+	body := `{"id":"web-1","version":"1.2.0","hostname":"web-1","os":"linux","arch":"amd64","cpus":4}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/agents", strings.NewReader(body))
+	req.Header.Set("X-Real-IP", "10.0.0.1")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/agents", nil))
+
+	var records []agents.Record
+	_ = json.Unmarshal(w.Body.Bytes(), &records)
+	fmt.Println(w.Code, len(records))
+	fmt.Println(records[0].ID, records[0].Version, records[0].Address, records[0].Registered)
+
+	// Output:
+	// 200 1
+	// web-1 1.2.0 10.0.0.1 true
+}
+
+func TestAgentHandler_Register(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+
+	registry := agents.NewRegistry()
+	handler := NewAgentHandler(registry, *zap.NewNop())
+	r.POST("/api/v1/agents", handler.Register)
+
+	tests := []struct {
+		name     string
+		body     string
+		wantCode int
+	}{
+		{name: "valid", body: `{"id":"web-1","build_commit":"abc123"}`, wantCode: http.StatusOK},
+		{name: "missing ID", body: `{"id":"  ","version":"1.2.0"}`, wantCode: http.StatusBadRequest},
+		{name: "invalid JSON", body: `{"id":`, wantCode: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/agents", strings.NewReader(tt.body)))
+			assert.Equal(t, tt.wantCode, w.Code)
+		})
+	}
+
+	records := registry.List()
+	require.Len(t, records, 1)
+	assert.Equal(t, "abc123", records[0].BuildCommit)
+	assert.Equal(t, "192.0.2.1", records[0].Address)
+}
@@ -3,20 +3,22 @@ package metric
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/model"
 	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/model/enum"
 	"github.com/ruslanDantsov/osmetrics-server/internal/server/constants"
+	"github.com/ruslanDantsov/osmetrics-server/internal/server/lookup"
 	"go.uber.org/zap"
 	"net/http"
 	"strconv"
 )
 
-// MetricGetter определяет интерфейс для получения метрик по их идентификатору.
+// MetricGetter определяет интерфейс для получения метрик по ключу серии.
 type MetricGetter interface {
-	GetMetric(ctx context.Context, metricID enum.MetricID) (*model.Metrics, bool)
-	GetKnownMetrics(ctx context.Context) []string
+	GetMetric(ctx context.Context, key model.MetricKey) (*model.Metrics, bool)
+	GetKnownMetrics(ctx context.Context) []model.MetricKey
 }
 
 // GetMetricHandler представляет обработчик HTTP-запросов для получения метрик.
@@ -37,6 +39,10 @@ func NewGetMetricHandler(storage MetricGetter, log zap.Logger) *GetMetricHandler
 //
 // Определяет тип метрики (gauge или counter) и вызывает соответствующий обработчик.
 // В случае некорректного типа возвращает ошибку 400.
+//
+// Серию можно выбрать по меткам параметрами запроса match, например
+// ?match=host=web-1&match=env!=dev (поддерживаются =, !=, =~ и !~).
+// Если условиям соответствует несколько серий, возвращается ошибка 409.
 func (h *GetMetricHandler) Get(ginContext *gin.Context) {
 	metricType := ginContext.Param(constants.URLParamMetricType)
 	switch metricType {
@@ -51,30 +57,29 @@ func (h *GetMetricHandler) Get(ginContext *gin.Context) {
 }
 
 func (h *GetMetricHandler) handleGetCounterMetric(ginContext *gin.Context) {
-	ctx := ginContext.Request.Context()
-
-	rawMetricID := ginContext.Param(constants.URLParamMetricName)
-
-	metricID, err := enum.ParseMetricID(rawMetricID)
-	if err != nil {
-		h.Log.Error(err.Error())
-		ginContext.String(http.StatusNotFound, "Metric name is unsupported")
+	metricModel, ok := h.findSeries(ginContext, constants.CounterMetricType)
+	if !ok {
 		return
 	}
 
-	metricModel, found := h.Storage.GetMetric(ctx, metricID)
+	ginContext.Header("Content-Type", "text/html")
+	ginContext.String(http.StatusOK, strconv.FormatInt(*metricModel.Delta, 10))
+}
 
-	if !found {
-		h.Log.Warn(fmt.Sprintf("The counter_metric name=%v not found", metricID))
-		ginContext.String(http.StatusNotFound, "Metric not found")
+func (h *GetMetricHandler) handleGetGaugeMetric(ginContext *gin.Context) {
+	gaugeModel, ok := h.findSeries(ginContext, constants.GaugeMetricType)
+	if !ok {
 		return
 	}
 
 	ginContext.Header("Content-Type", "text/html")
-	ginContext.String(http.StatusOK, strconv.FormatInt(*metricModel.Delta, 10))
+	ginContext.String(http.StatusOK, strconv.FormatFloat(*gaugeModel.Value, 'f', -1, 64))
 }
 
-func (h *GetMetricHandler) handleGetGaugeMetric(ginContext *gin.Context) {
+// findSeries находит единственную серию метрики metricType с именем из URL, удовлетворяющую
+// условиям match. Без условий предпочитается серия без меток.
+// Если серия не найдена, ответ с ошибкой уже записан и возвращается false.
+func (h *GetMetricHandler) findSeries(ginContext *gin.Context, metricType string) (*model.Metrics, bool) {
 	ctx := ginContext.Request.Context()
 
 	rawMetricID := ginContext.Param(constants.URLParamMetricName)
@@ -83,17 +88,33 @@ func (h *GetMetricHandler) handleGetGaugeMetric(ginContext *gin.Context) {
 	if err != nil {
 		h.Log.Error(err.Error())
 		ginContext.String(http.StatusNotFound, "Metric name is unsupported")
-		return
+		return nil, false
 	}
 
-	gaugeModel, found := h.Storage.GetMetric(ctx, metricID)
+	matchers, err := model.ParseLabelMatchers(ginContext.QueryArray(constants.QueryParamLabelMatcher))
+	if err != nil {
+		h.Log.Warn(err.Error())
+		ginContext.String(http.StatusBadRequest, err.Error())
+		return nil, false
+	}
 
-	if !found {
-		h.Log.Warn(fmt.Sprintf("The gauge_metric name=%v not found", metricID))
-		ginContext.String(http.StatusNotFound, "Metric not found")
-		return
+	metric, err := lookup.Find(ctx, h.Storage, metricType, metricID, nil, matchers, h.Log)
+	if err != nil {
+		status, message := lookupErrorResponse(err, "match parameters")
+		h.Log.Warn(fmt.Sprintf("The %v_metric name=%v: %v", metricType, metricID, err))
+		ginContext.String(status, message)
+		return nil, false
 	}
 
-	ginContext.Header("Content-Type", "text/html")
-	ginContext.String(http.StatusOK, strconv.FormatFloat(*gaugeModel.Value, 'f', -1, 64))
+	return metric, true
+}
+
+// lookupErrorResponse сопоставляет ошибку поиска серии HTTP-статусу и тексту ответа.
+// narrowBy подсказывает, чем уточнить запрос, если ему соответствует несколько серий.
+func lookupErrorResponse(err error, narrowBy string) (int, string) {
+	var ambiguous *lookup.AmbiguousError
+	if errors.As(err, &ambiguous) {
+		return http.StatusConflict, fmt.Sprintf("Metric matches %d series, narrow it down with %s", ambiguous.Count, narrowBy)
+	}
+	return http.StatusNotFound, "Metric not found"
 }
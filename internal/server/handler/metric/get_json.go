@@ -6,6 +6,7 @@ import (
 	"github.com/mailru/easyjson"
 	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/model"
 	"github.com/ruslanDantsov/osmetrics-server/internal/server/constants"
+	"github.com/ruslanDantsov/osmetrics-server/internal/server/lookup"
 	"net/http"
 	"strings"
 )
@@ -15,8 +16,12 @@ import (
 // Принимает JSON-объект с параметрами метрики, извлекает из хранилища соответствующую метрику
 // и возвращает её в формате JSON. В случае ошибок возвращает соответствующие HTTP-статусы:
 //
+// Серия ищется так же, как в Get: метки из запроса обязательны, остальные метки серии могут быть любыми.
+// Запрос без меток находит серию, записанную агентом с метками host и agent, если она единственная.
+//
 // - 400: если JSON некорректен;
-// - 404: если метрика не найдена или произошла ошибка сериализации.
+// - 404: если метрика не найдена или произошла ошибка сериализации;
+// - 409: если запросу соответствует несколько серий.
 func (h *GetMetricHandler) GetJSON(ginContext *gin.Context) {
 	ctx := ginContext.Request.Context()
 
@@ -28,21 +33,23 @@ func (h *GetMetricHandler) GetJSON(ginContext *gin.Context) {
 		return
 	}
 
-	if strings.ToLower(metricRequest.MType) != constants.GaugeMetricType && strings.ToLower(metricRequest.MType) != constants.CounterMetricType {
+	metricType := strings.ToLower(metricRequest.MType)
+	if metricType != constants.GaugeMetricType && metricType != constants.CounterMetricType {
 		h.Log.Warn(fmt.Sprintf("Metric type=%v is unsupported", metricRequest.MType))
 	}
 
-	existingMetric, found := h.Storage.GetMetric(ctx, metricRequest.ID)
-	if !found {
-		h.Log.Warn(fmt.Sprintf("The metric ID=%v not found", metricRequest.ID))
-		ginContext.JSON(http.StatusNotFound, gin.H{"error": "Metric not found"})
+	existingMetric, err := lookup.Find(ctx, h.Storage, metricType, metricRequest.ID, metricRequest.Labels, nil, h.Log)
+	if err != nil {
+		status, message := lookupErrorResponse(err, "labels")
+		h.Log.Warn(fmt.Sprintf("The metric ID=%v: %v", metricRequest.ID, err))
+		ginContext.JSON(status, gin.H{"error": message})
 		return
 	}
 
 	ginContext.Header("Content-Type", "application/json")
 	ginContext.Writer.WriteHeader(http.StatusOK)
 
-	_, err := easyjson.MarshalToWriter(existingMetric, ginContext.Writer)
+	_, err = easyjson.MarshalToWriter(existingMetric, ginContext.Writer)
 
 	if existingMetric.MType == constants.CounterMetricType {
 		h.Log.Debug(fmt.Sprintf("Return: Metric ID=%v Value=%v", existingMetric.ID, existingMetric.Delta))
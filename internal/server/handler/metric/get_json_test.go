@@ -2,12 +2,14 @@ package metric
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/mailru/easyjson"
 	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/model"
 	"github.com/ruslanDantsov/osmetrics-server/internal/server/constants"
-	"go.uber.org/zap/zaptest"
+	"github.com/ruslanDantsov/osmetrics-server/internal/server/repository/memory"
+	"go.uber.org/zap"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -18,7 +20,7 @@ func ExampleGetMetricHandler_GetJSON_gauge() {
 	gin.SetMode(gin.TestMode)
 	r := gin.New()
 
-	handler := NewGetMetricHandler(&MockStorage{}, *zaptest.NewLogger(nil))
+	handler := NewGetMetricHandler(&MockStorage{}, *zap.NewNop())
 	r.POST("/value/", handler.GetJSON)
 
 	metricReq := model.Metrics{
@@ -47,7 +49,7 @@ func ExampleGetMetricHandler_GetJSON_counter() {
 	gin.SetMode(gin.TestMode)
 	r := gin.New()
 
-	handler := NewGetMetricHandler(&MockStorage{}, *zaptest.NewLogger(nil))
+	handler := NewGetMetricHandler(&MockStorage{}, *zap.NewNop())
 	r.POST("/value/", handler.GetJSON)
 
 	metricReq := model.Metrics{
@@ -76,7 +78,7 @@ func ExampleGetMetricHandler_GetJSON_notFound() {
 	gin.SetMode(gin.TestMode)
 	r := gin.New()
 
-	handler := NewGetMetricHandler(&MockStorage{}, *zaptest.NewLogger(nil))
+	handler := NewGetMetricHandler(&MockStorage{}, *zap.NewNop())
 	r.POST("/value/", handler.GetJSON)
 
 	metricReq := model.Metrics{
@@ -105,7 +107,7 @@ func ExampleGetMetricHandler_GetJSON_badJSON() {
 	gin.SetMode(gin.TestMode)
 	r := gin.New()
 
-	handler := NewGetMetricHandler(&MockStorage{}, *zaptest.NewLogger(nil))
+	handler := NewGetMetricHandler(&MockStorage{}, *zap.NewNop())
 	r.POST("/value/", handler.GetJSON)
 
 	badBody := strings.NewReader(`{ this is invalid json }`)
@@ -124,3 +126,44 @@ func ExampleGetMetricHandler_GetJSON_badJSON() {
 	// 400
 	// true
 }
+
+func ExampleGetMetricHandler_GetJSON_labeledSeries() {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+
+	storage := memory.NewMemStorage(*zap.NewNop(), 0)
+	handler := NewGetMetricHandler(storage, *zap.NewNop())
+	r.POST("/value/", handler.GetJSON)
+
+	read := func(body string) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/value/", strings.NewReader(body)))
+
+		var metric model.Metrics
+		if w.Code != http.StatusOK || easyjson.Unmarshal(w.Body.Bytes(), &metric) != nil {
+			fmt.Println(w.Code, w.Body.String())
+			return
+		}
+		fmt.Println(w.Code, *metric.Value, metric.Labels["agent"])
+	}
+	save := func(agent string, value float64) {
+		_, _ = storage.SaveMetric(context.Background(), &model.Metrics{
+			ID:     "Alloc",
+			MType:  "gauge",
+			Value:  &value,
+			Labels: map[string]string{"host": "web-1", "agent": agent},
+		})
+	}
+
+	save("a1", 1.5)
+	read(`{"id":"Alloc","type":"gauge"}`)
+
+	save("a2", 2.5)
+	read(`{"id":"Alloc","type":"gauge"}`)
+	read(`{"id":"Alloc","type":"gauge","labels":{"agent":"a2"}}`)
+
+	// Output:
+	// 200 1.5 a1
+	// 409 {"error":"Metric matches 2 series, narrow it down with labels"}
+	// 200 2.5 a2
+}
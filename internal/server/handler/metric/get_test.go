@@ -5,8 +5,8 @@ import (
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/model"
-	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/model/enum"
-	"go.uber.org/zap/zaptest"
+	"github.com/ruslanDantsov/osmetrics-server/internal/server/repository/memory"
+	"go.uber.org/zap"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -16,16 +16,16 @@ import (
 // mockStorage реализует интерфейс MetricGetter.
 type MockStorage struct{}
 
-func (m *MockStorage) GetMetric(_ context.Context, metricID enum.MetricID) (*model.Metrics, bool) {
-	switch metricID {
-	case "Alloc":
+func (m *MockStorage) GetMetric(_ context.Context, key model.MetricKey) (*model.Metrics, bool) {
+	switch key {
+	case model.NewMetricKey("gauge", "Alloc"):
 		v := 123.45
 		return &model.Metrics{
 			ID:    "Alloc",
 			MType: "gauge",
 			Value: &v,
 		}, true
-	case "PollCount":
+	case model.NewMetricKey("counter", "PollCount"):
 		d := int64(42)
 		return &model.Metrics{
 			ID:    "PollCount",
@@ -37,15 +37,18 @@ func (m *MockStorage) GetMetric(_ context.Context, metricID enum.MetricID) (*mod
 	}
 }
 
-func (m *MockStorage) GetKnownMetrics(_ context.Context) []string {
-	return []string{"Alloc", "PollCount"}
+func (m *MockStorage) GetKnownMetrics(_ context.Context) []model.MetricKey {
+	return []model.MetricKey{
+		model.NewMetricKey("gauge", "Alloc"),
+		model.NewMetricKey("counter", "PollCount"),
+	}
 }
 
 func ExampleGetMetricHandler_Get_gauge() {
 	gin.SetMode(gin.TestMode)
 	r := gin.New()
 
-	handler := NewGetMetricHandler(&MockStorage{}, *zaptest.NewLogger(nil))
+	handler := NewGetMetricHandler(&MockStorage{}, *zap.NewNop())
 	r.GET("/value/:type/:name", handler.Get)
 
 	req := httptest.NewRequest(http.MethodGet, "/value/gauge/Alloc", nil)
@@ -62,7 +65,7 @@ func ExampleGetMetricHandler_Get_counter() {
 	gin.SetMode(gin.TestMode)
 	r := gin.New()
 
-	handler := NewGetMetricHandler(&MockStorage{}, *zaptest.NewLogger(nil))
+	handler := NewGetMetricHandler(&MockStorage{}, *zap.NewNop())
 	r.GET("/value/:type/:name", handler.Get)
 
 	req := httptest.NewRequest(http.MethodGet, "/value/counter/PollCount", nil)
@@ -74,3 +77,32 @@ func ExampleGetMetricHandler_Get_counter() {
 	fmt.Println(w.Code)
 	fmt.Println(strings.TrimSpace(string(body)))
 }
+
+func ExampleGetMetricHandler_Get_labelMatchers() {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+
+	storage := memory.NewMemStorage(*zap.NewNop(), 0)
+	for host, value := range map[string]float64{"web-1": 1.5, "web-2": 2.5} {
+		v := value
+		_, _ = storage.SaveMetric(context.Background(), &model.Metrics{
+			ID:     "Alloc",
+			MType:  "gauge",
+			Value:  &v,
+			Labels: map[string]string{"host": host},
+		})
+	}
+
+	handler := NewGetMetricHandler(storage, *zap.NewNop())
+	r.GET("/value/:type/:name", handler.Get)
+
+	for _, url := range []string{"/value/gauge/Alloc?match=host=web-2", "/value/gauge/Alloc"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, url, nil))
+		fmt.Println(w.Code, w.Body.String())
+	}
+
+	// Output:
+	// 200 2.5
+	// 409 Metric matches 2 series, narrow it down with match parameters
+}
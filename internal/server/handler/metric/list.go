@@ -4,12 +4,14 @@ import (
 	"context"
 	"fmt"
 	"github.com/gin-gonic/gin"
+	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/model"
+	"html"
 	"net/http"
 )
 
 // KnownMetricsGetter предоставляет интерфейс для получения списка известных метрик.
 type KnownMetricsGetter interface {
-	GetKnownMetrics(ctx context.Context) []string
+	GetKnownMetrics(ctx context.Context) []model.MetricKey
 }
 
 // List обрабатывает HTTP-запрос для получения списка всех известных метрик.
@@ -18,13 +20,17 @@ type KnownMetricsGetter interface {
 func (h *GetMetricHandler) List(ginContext *gin.Context) {
 	ctx := ginContext.Request.Context()
 
-	var metricNames = h.Storage.GetKnownMetrics(ctx)
+	var metricKeys = h.Storage.GetKnownMetrics(ctx)
 	htmlContent := "<html><head><title>Список метрик</title></head><body>"
 	htmlContent += "<h1>List of known metrics:</h1>"
 	htmlContent += "<ul>"
 
-	for _, metricName := range metricNames {
-		htmlContent += fmt.Sprintf("<li>%s</li>", metricName)
+	for _, metricKey := range metricKeys {
+		name := string(metricKey.ID)
+		if metricKey.Labels != "" {
+			name += "{" + metricKey.Labels + "}"
+		}
+		htmlContent += fmt.Sprintf("<li>%s</li>", html.EscapeString(name))
 	}
 
 	htmlContent += "</ul>"
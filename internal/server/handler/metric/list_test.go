@@ -3,7 +3,7 @@ package metric
 import (
 	"fmt"
 	"github.com/gin-gonic/gin"
-	"go.uber.org/zap/zaptest"
+	"go.uber.org/zap"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -14,7 +14,7 @@ func ExampleGetMetricHandler_List() {
 	gin.SetMode(gin.TestMode)
 	r := gin.New()
 
-	handler := NewGetMetricHandler(&MockStorage{}, *zaptest.NewLogger(nil))
+	handler := NewGetMetricHandler(&MockStorage{}, *zap.NewNop())
 	r.GET("/", handler.List)
 
 	req := httptest.NewRequest(http.MethodGet, "/", nil)
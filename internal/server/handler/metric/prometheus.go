@@ -0,0 +1,157 @@
+package metric
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/model"
+	"github.com/ruslanDantsov/osmetrics-server/internal/server/constants"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PrometheusContentType — тип содержимого текстового формата Prometheus.
+const PrometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// prometheusFamily — семейство метрик Prometheus: все серии одного имени и типа.
+type prometheusFamily struct {
+	name    string
+	mType   string
+	help    string
+	samples []string
+}
+
+// Prometheus обрабатывает HTTP-запрос GET /metrics и выводит все метрики хранилища
+// в текстовом формате Prometheus.
+//
+// gauge выводятся как gauge, counter — как counter с суффиксом _total. Имена метрик и меток
+// приводятся к допустимым в Prometheus: недопустимые символы заменяются на "_".
+func (h *GetMetricHandler) Prometheus(ginContext *gin.Context) {
+	ctx := ginContext.Request.Context()
+
+	families := make(map[string]*prometheusFamily)
+	for _, key := range h.Storage.GetKnownMetrics(ctx) {
+		metric, found := h.Storage.GetMetric(ctx, key)
+		if !found {
+			continue
+		}
+
+		name, value, ok := prometheusSample(metric)
+		if !ok {
+			continue
+		}
+
+		family, exists := families[name]
+		if !exists {
+			family = &prometheusFamily{
+				name:  name,
+				mType: metric.MType,
+				help:  fmt.Sprintf("osmetrics %s metric %s", metric.MType, metric.ID),
+			}
+			families[name] = family
+		}
+		if family.mType != metric.MType {
+			h.Log.Warn(fmt.Sprintf("Skip series %s: Prometheus name %s is already used by a %s metric", key, name, family.mType))
+			continue
+		}
+
+		family.samples = append(family.samples, name+prometheusLabels(metric.Labels)+" "+value)
+	}
+
+	names := make([]string, 0, len(families))
+	for name := range families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var body strings.Builder
+	for _, name := range names {
+		family := families[name]
+		sort.Strings(family.samples)
+
+		fmt.Fprintf(&body, "# HELP %s %s\n", family.name, escapePrometheusHelp(family.help))
+		fmt.Fprintf(&body, "# TYPE %s %s\n", family.name, family.mType)
+		for _, sample := range family.samples {
+			body.WriteString(sample)
+			body.WriteByte('\n')
+		}
+	}
+
+	ginContext.Data(http.StatusOK, PrometheusContentType, []byte(body.String()))
+}
+
+// prometheusSample возвращает имя семейства и значение метрики в формате Prometheus.
+func prometheusSample(metric *model.Metrics) (string, string, bool) {
+	name := sanitizePrometheusName(string(metric.ID), true)
+
+	switch metric.MType {
+	case constants.GaugeMetricType:
+		if metric.Value == nil {
+			return "", "", false
+		}
+		return name, strconv.FormatFloat(*metric.Value, 'g', -1, 64), true
+	case constants.CounterMetricType:
+		if metric.Delta == nil {
+			return "", "", false
+		}
+		if !strings.HasSuffix(name, "_total") {
+			name += "_total"
+		}
+		return name, strconv.FormatInt(*metric.Delta, 10), true
+	default:
+		return "", "", false
+	}
+}
+
+// prometheusLabels форматирует метки в виде {name="value",...} в порядке имён.
+func prometheusLabels(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		parts = append(parts, fmt.Sprintf(`%s="%s"`, sanitizePrometheusName(name, false), escapePrometheusLabelValue(labels[name])))
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+// sanitizePrometheusName заменяет недопустимые символы имени на "_".
+// Имя метрики может содержать ":", имя метки — нет; ни одно из них не может начинаться с цифры.
+func sanitizePrometheusName(name string, allowColon bool) string {
+	var result strings.Builder
+	for i, r := range name {
+		valid := r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') ||
+			(allowColon && r == ':') || (i > 0 && r >= '0' && r <= '9')
+		if valid {
+			result.WriteRune(r)
+			continue
+		}
+		if i == 0 && r >= '0' && r <= '9' {
+			result.WriteByte('_')
+			result.WriteRune(r)
+			continue
+		}
+		result.WriteByte('_')
+	}
+
+	if result.Len() == 0 {
+		return "_"
+	}
+	return result.String()
+}
+
+func escapePrometheusHelp(help string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help)
+}
+
+func escapePrometheusLabelValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`).Replace(value)
+}
@@ -0,0 +1,104 @@
+package metric
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/model"
+	"github.com/ruslanDantsov/osmetrics-server/internal/server/middleware"
+	"github.com/ruslanDantsov/osmetrics-server/internal/server/repository/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newPrometheusTestStorage() *memory.MemStorage {
+	storage := memory.NewMemStorage(*zap.NewNop(), 0)
+	ctx := context.Background()
+
+	alloc := 1.5
+	pollCount := int64(3)
+	webValue := 0.25
+	_, _ = storage.SaveMetric(ctx, &model.Metrics{ID: "Alloc", MType: "gauge", Value: &alloc})
+	_, _ = storage.SaveMetric(ctx, &model.Metrics{ID: "PollCount", MType: "counter", Delta: &pollCount})
+	_, _ = storage.SaveMetric(ctx, &model.Metrics{
+		ID:     "CPUutilization1",
+		MType:  "gauge",
+		Value:  &webValue,
+		Labels: map[string]string{"host": `web "1"`, "data-center": "eu"},
+	})
+	return storage
+}
+
+func ExampleGetMetricHandler_Prometheus() {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+
+	handler := NewGetMetricHandler(newPrometheusTestStorage(), *zap.NewNop())
+	r.GET("/metrics", handler.Prometheus)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	fmt.Println(w.Code, w.Header().Get("Content-Type"))
+	fmt.Print(w.Body.String())
+
+	// Output:
+	// 200 text/plain; version=0.0.4; charset=utf-8
+	// # HELP Alloc osmetrics gauge metric Alloc
+	// # TYPE Alloc gauge
+	// Alloc 1.5
+	// # HELP CPUutilization1 osmetrics gauge metric CPUutilization1
+	// # TYPE CPUutilization1 gauge
+	// CPUutilization1{data_center="eu",host="web \"1\""} 0.25
+	// # HELP PollCount_total osmetrics counter metric PollCount
+	// # TYPE PollCount_total counter
+	// PollCount_total 3
+}
+
+func TestGetMetricHandler_Prometheus_Gzip(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middleware.NewGzipCompressionMiddleware())
+
+	handler := NewGetMetricHandler(newPrometheusTestStorage(), *zap.NewNop())
+	r.GET("/metrics", handler.Prometheus)
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+
+	reader, err := gzip.NewReader(w.Body)
+	require.NoError(t, err)
+	body, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), "# TYPE PollCount_total counter\nPollCount_total 3\n")
+}
+
+func TestSanitizePrometheusName(t *testing.T) {
+	tests := []struct {
+		name       string
+		allowColon bool
+		want       string
+	}{
+		{name: "HeapAlloc", allowColon: true, want: "HeapAlloc"},
+		{name: "disk.used-bytes", allowColon: true, want: "disk_used_bytes"},
+		{name: "1minute", allowColon: true, want: "_1minute"},
+		{name: "job:rate", allowColon: true, want: "job:rate"},
+		{name: "job:rate", allowColon: false, want: "job_rate"},
+		{name: "", allowColon: false, want: "_"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, sanitizePrometheusName(tt.name, tt.allowColon), tt.name)
+	}
+}
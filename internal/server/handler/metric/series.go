@@ -0,0 +1,129 @@
+package metric
+
+import (
+	"context"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/model"
+	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/model/enum"
+	"github.com/ruslanDantsov/osmetrics-server/internal/server/constants"
+	"github.com/ruslanDantsov/osmetrics-server/internal/server/lookup"
+	"go.uber.org/zap"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// SeriesReader определяет интерфейс для чтения истории серий метрик.
+type SeriesReader interface {
+	GetKnownMetrics(ctx context.Context) []model.MetricKey
+	GetSeries(ctx context.Context, key model.MetricKey, from, to time.Time) ([]model.Sample, error)
+}
+
+// SeriesHandler представляет обработчик HTTP-запросов для получения истории метрик.
+type SeriesHandler struct {
+	Storage SeriesReader
+	Log     zap.Logger
+}
+
+// SeriesResponse — история одной серии метрики.
+type SeriesResponse struct {
+	ID     enum.MetricID     `json:"id"`
+	MType  string            `json:"type"`
+	Labels map[string]string `json:"labels,omitempty"`
+	Points []model.Sample    `json:"points"`
+}
+
+// NewSeriesHandler создаёт новый экземпляр SeriesHandler.
+func NewSeriesHandler(storage SeriesReader, log zap.Logger) *SeriesHandler {
+	return &SeriesHandler{
+		Storage: storage,
+		Log:     log,
+	}
+}
+
+// Get обрабатывает запрос GET /api/v1/series/:type/:name и возвращает JSON-массив серий метрики
+// с точками за интервал [from, to].
+//
+// Границы from и to задаются в формате RFC 3339 или числом секунд Unix; отсутствующая граница
+// не ограничивает интервал. Серии можно отобрать по меткам параметрами match, как в Get.
+// Возвращает 400 при некорректных параметрах и 404, если подходящих серий нет.
+func (h *SeriesHandler) Get(ginContext *gin.Context) {
+	ctx := ginContext.Request.Context()
+
+	metricType := ginContext.Param(constants.URLParamMetricType)
+	if metricType != constants.GaugeMetricType && metricType != constants.CounterMetricType {
+		h.Log.Error(fmt.Sprintf("Metric type=%v is unsupported", metricType))
+		ginContext.JSON(http.StatusBadRequest, gin.H{"error": "Metric type is unsupported"})
+		return
+	}
+
+	metricID, err := enum.ParseMetricID(ginContext.Param(constants.URLParamMetricName))
+	if err != nil {
+		h.Log.Error(err.Error())
+		ginContext.JSON(http.StatusNotFound, gin.H{"error": "Metric name is unsupported"})
+		return
+	}
+
+	from, err := parseTimeParam(ginContext.Query(constants.QueryParamFrom))
+	if err != nil {
+		ginContext.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid %s: %v", constants.QueryParamFrom, err)})
+		return
+	}
+	to, err := parseTimeParam(ginContext.Query(constants.QueryParamTo))
+	if err != nil {
+		ginContext.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid %s: %v", constants.QueryParamTo, err)})
+		return
+	}
+	if !from.IsZero() && !to.IsZero() && from.After(to) {
+		ginContext.JSON(http.StatusBadRequest, gin.H{"error": "from is after to"})
+		return
+	}
+
+	matchers, err := model.ParseLabelMatchers(ginContext.QueryArray(constants.QueryParamLabelMatcher))
+	if err != nil {
+		h.Log.Warn(err.Error())
+		ginContext.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	matched := lookup.Match(h.Storage.GetKnownMetrics(ctx), metricType, metricID, matchers, h.Log)
+	if len(matched) == 0 {
+		h.Log.Warn(fmt.Sprintf("The %v_metric name=%v not found", metricType, metricID))
+		ginContext.JSON(http.StatusNotFound, gin.H{"error": "Metric not found"})
+		return
+	}
+
+	result := make([]SeriesResponse, 0, len(matched))
+	for _, key := range matched {
+		points, err := h.Storage.GetSeries(ctx, key, from, to)
+		if err != nil {
+			h.Log.Error(fmt.Sprintf("Error on reading series %s: %v", key, err))
+			ginContext.JSON(http.StatusInternalServerError, gin.H{"error": "failed to read series"})
+			return
+		}
+
+		labels, _ := model.ParseCanonicalLabels(key.Labels)
+		result = append(result, SeriesResponse{
+			ID:     key.ID,
+			MType:  key.MType,
+			Labels: labels,
+			Points: points,
+		})
+	}
+
+	ginContext.JSON(http.StatusOK, result)
+}
+
+// parseTimeParam разбирает границу интервала: RFC 3339 или секунды Unix. Пустая строка даёт нулевое время.
+func parseTimeParam(raw string) (time.Time, error) {
+	if raw == "" {
+		return time.Time{}, nil
+	}
+
+	if seconds, err := strconv.ParseInt(raw, 10, 64); err == nil {
+		return time.Unix(seconds, 0), nil
+	}
+
+	return time.Parse(time.RFC3339, raw)
+}
@@ -0,0 +1,102 @@
+package metric
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/model"
+	"github.com/ruslanDantsov/osmetrics-server/internal/server/repository/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func ExampleSeriesHandler_Get() {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+
+	storage := memory.NewMemStorage(*zap.NewNop(), 10)
+	for _, value := range []float64{1.5, 2.5, 3.5} {
+		v := value
+		_, _ = storage.SaveMetric(context.Background(), &model.Metrics{ID: "HeapAlloc", MType: "gauge", Value: &v})
+	}
+
+	handler := NewSeriesHandler(storage, *zap.NewNop())
+	r.GET("/api/v1/series/:type/:name", handler.Get)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/series/gauge/HeapAlloc", nil))
+
+	var series []SeriesResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &series)
+	fmt.Println(w.Code, series[0].ID)
+	for _, point := range series[0].Points {
+		fmt.Println(*point.Value)
+	}
+
+	// Output:
+	// 200 HeapAlloc
+	// 1.5
+	// 2.5
+	// 3.5
+}
+
+func TestSeriesHandler_Get(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+
+	storage := memory.NewMemStorage(*zap.NewNop(), 10)
+	for _, host := range []string{"web-1", "web-2"} {
+		delta := int64(5)
+		_, err := storage.SaveMetric(context.Background(), &model.Metrics{
+			ID: "PollCount", MType: "counter", Delta: &delta, Labels: map[string]string{"host": host},
+		})
+		require.NoError(t, err)
+	}
+
+	handler := NewSeriesHandler(storage, *zap.NewNop())
+	r.GET("/api/v1/series/:type/:name", handler.Get)
+
+	future := time.Now().Add(time.Hour).Format(time.RFC3339)
+
+	tests := []struct {
+		name       string
+		url        string
+		wantCode   int
+		wantSeries int
+		wantPoints int
+	}{
+		{name: "all series", url: "/api/v1/series/counter/PollCount", wantCode: http.StatusOK, wantSeries: 2, wantPoints: 1},
+		{name: "label matcher", url: "/api/v1/series/counter/PollCount?match=host=web-2", wantCode: http.StatusOK, wantSeries: 1, wantPoints: 1},
+		{name: "empty interval", url: "/api/v1/series/counter/PollCount?from=" + future, wantCode: http.StatusOK, wantSeries: 2, wantPoints: 0},
+		{name: "unix seconds", url: "/api/v1/series/counter/PollCount?from=0&to=" + fmt.Sprint(time.Now().Add(time.Minute).Unix()), wantCode: http.StatusOK, wantSeries: 2, wantPoints: 1},
+		{name: "unknown series", url: "/api/v1/series/gauge/PollCount", wantCode: http.StatusNotFound},
+		{name: "invalid type", url: "/api/v1/series/histogram/PollCount", wantCode: http.StatusBadRequest},
+		{name: "invalid from", url: "/api/v1/series/counter/PollCount?from=yesterday", wantCode: http.StatusBadRequest},
+		{name: "from after to", url: "/api/v1/series/counter/PollCount?from=20&to=10", wantCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.url, nil))
+
+			require.Equal(t, tt.wantCode, w.Code)
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+
+			var series []SeriesResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &series))
+			assert.Len(t, series, tt.wantSeries)
+			for _, s := range series {
+				assert.Len(t, s.Points, tt.wantPoints)
+			}
+		})
+	}
+}
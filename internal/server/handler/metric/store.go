@@ -52,7 +52,8 @@ func (h *StoreMetricHandler) Store(ginContext *gin.Context) {
 		metricRequest, err = model.NewMetricWithRawValues(constants.GaugeMetricType, metricName, metricValue)
 	default:
 		h.Log.Warn(fmt.Sprintf("Metric type=%v is unsupported", metricType))
-		metricRequest, err = model.NewMetricWithRawValues(metricType, metricName, metricValue)
+		ginContext.String(http.StatusBadRequest, fmt.Sprintf("metric type %v is unsupported", metricType))
+		return
 	}
 
 	if err != nil {
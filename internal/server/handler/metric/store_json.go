@@ -77,6 +77,7 @@ func (h *StoreMetricHandler) StoreBatchJSON(ginContext *gin.Context) {
 			ginContext.String(http.StatusBadRequest, "Error on model construction")
 			return
 		}
+		rawMmetric.Labels = metric.Labels
 
 		metricsList = append(metricsList, *rawMmetric)
 	}
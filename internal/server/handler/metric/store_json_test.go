@@ -2,18 +2,23 @@ package metric
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"github.com/gin-gonic/gin"
-	"go.uber.org/zap/zaptest"
+	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
 	"net/http"
 	"net/http/httptest"
+	"testing"
 )
 
 func ExampleStoreMetricHandler_StoreJSON() {
 	gin.SetMode(gin.TestMode)
 	r := gin.New()
 
-	handler := NewStoreMetricHandler(&MockSaver{}, *zaptest.NewLogger(nil))
+	handler := NewStoreMetricHandler(&MockSaver{}, *zap.NewNop())
 	r.POST("/update/", handler.StoreJSON)
 
 	// JSON тела запроса
@@ -39,7 +44,7 @@ func ExampleStoreMetricHandler_StoreJSON_invalidJSON() {
 	gin.SetMode(gin.TestMode)
 	r := gin.New()
 
-	handler := NewStoreMetricHandler(&MockSaver{}, *zaptest.NewLogger(nil))
+	handler := NewStoreMetricHandler(&MockSaver{}, *zap.NewNop())
 	r.POST("/update/", handler.StoreJSON)
 
 	req := httptest.NewRequest(http.MethodPost, "/update/", bytes.NewBufferString("not valid json"))
@@ -58,7 +63,7 @@ func ExampleStoreMetricHandler_StoreBatchJSON() {
 	gin.SetMode(gin.TestMode)
 	r := gin.New()
 
-	handler := NewStoreMetricHandler(&MockSaver{}, *zaptest.NewLogger(nil))
+	handler := NewStoreMetricHandler(&MockSaver{}, *zap.NewNop())
 	r.POST("/updates/", handler.StoreBatchJSON)
 
 	body := `[
@@ -90,7 +95,7 @@ func ExampleStoreMetricHandler_StoreBatchJSON_invalidBody() {
 	gin.SetMode(gin.TestMode)
 	r := gin.New()
 
-	handler := NewStoreMetricHandler(&MockSaver{}, *zaptest.NewLogger(nil))
+	handler := NewStoreMetricHandler(&MockSaver{}, *zap.NewNop())
 	r.POST("/updates/", handler.StoreBatchJSON)
 
 	req := httptest.NewRequest(http.MethodPost, "/updates/", bytes.NewBufferString("not an array"))
@@ -104,3 +109,33 @@ func ExampleStoreMetricHandler_StoreBatchJSON_invalidBody() {
 	// Output:
 	// 400
 }
+
+type recordingSaver struct {
+	MockSaver
+	saved model.MetricsList
+}
+
+func (s *recordingSaver) SaveAllMetrics(_ context.Context, metricList model.MetricsList) (model.MetricsList, error) {
+	s.saved = append(s.saved, metricList...)
+	return metricList, nil
+}
+
+func TestStoreMetricHandler_StoreBatchJSON_keepsLabels(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+
+	saver := &recordingSaver{}
+	handler := NewStoreMetricHandler(saver, *zap.NewNop())
+	r.POST("/updates/", handler.StoreBatchJSON)
+
+	body := `[{"id": "DiskFree", "type": "gauge", "value": 1, "labels": {"mountpoint": "/", "agent": "a1"}}]`
+	req := httptest.NewRequest(http.MethodPost, "/updates/", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	r.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Len(t, saver.saved, 1)
+	assert.Equal(t, map[string]string{"mountpoint": "/", "agent": "a1"}, saver.saved[0].Labels)
+}
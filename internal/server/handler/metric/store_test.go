@@ -5,7 +5,7 @@ import (
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/model"
-	"go.uber.org/zap/zaptest"
+	"go.uber.org/zap"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -26,7 +26,7 @@ func ExampleStoreMetricHandler_Store_gauge() {
 	gin.SetMode(gin.TestMode)
 	r := gin.New()
 
-	handler := NewStoreMetricHandler(&MockSaver{}, *zaptest.NewLogger(nil))
+	handler := NewStoreMetricHandler(&MockSaver{}, *zap.NewNop())
 	r.POST("/update/:type/:name/:value", handler.Store)
 
 	req := httptest.NewRequest(http.MethodPost, "/update/gauge/Alloc/123.45", nil)
@@ -44,7 +44,7 @@ func ExampleStoreMetricHandler_Store_counter() {
 	gin.SetMode(gin.TestMode)
 	r := gin.New()
 
-	handler := NewStoreMetricHandler(&MockSaver{}, *zaptest.NewLogger(nil))
+	handler := NewStoreMetricHandler(&MockSaver{}, *zap.NewNop())
 	r.POST("/update/:type/:name/:value", handler.Store)
 
 	req := httptest.NewRequest(http.MethodPost, "/update/counter/PollCount/42", nil)
@@ -62,7 +62,7 @@ func ExampleStoreMetricHandler_Store_invalidType() {
 	gin.SetMode(gin.TestMode)
 	r := gin.New()
 
-	handler := NewStoreMetricHandler(&MockSaver{}, *zaptest.NewLogger(nil))
+	handler := NewStoreMetricHandler(&MockSaver{}, *zap.NewNop())
 	r.POST("/update/:type/:name/:value", handler.Store)
 
 	req := httptest.NewRequest(http.MethodPost, "/update/unknown/Any/42", nil)
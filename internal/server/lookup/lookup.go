@@ -0,0 +1,91 @@
+// Package lookup находит серии метрик в хранилище по имени, типу и меткам.
+//
+// Используется HTTP- и gRPC-обработчиками чтения, чтобы метрика без указанных меток
+// находилась одинаково независимо от способа запроса.
+package lookup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/model"
+	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/model/enum"
+	"go.uber.org/zap"
+)
+
+// ErrNotFound возвращается Find, если подходящей серии нет.
+var ErrNotFound = errors.New("metric not found")
+
+// AmbiguousError возвращается Find, если условиям соответствует несколько серий.
+type AmbiguousError struct {
+	Count int
+}
+
+// Error реализует интерфейс error.
+func (e *AmbiguousError) Error() string {
+	return fmt.Sprintf("metric matches %d series", e.Count)
+}
+
+// Reader определяет методы хранилища, необходимые для поиска серий.
+type Reader interface {
+	GetMetric(ctx context.Context, key model.MetricKey) (*model.Metrics, bool)
+	GetKnownMetrics(ctx context.Context) []model.MetricKey
+}
+
+// Find находит единственную серию метрики metricType с именем metricID.
+//
+// Метки labels задают обязательные значения меток, matchers — дополнительные условия.
+// Если условий matchers нет, сначала проверяется серия ровно с метками labels (без меток, если labels пуст);
+// иначе выбирается единственная серия, удовлетворяющая всем условиям. Если таких серий несколько,
+// возвращается *AmbiguousError, если ни одной — ErrNotFound.
+func Find(ctx context.Context, storage Reader, metricType string, metricID enum.MetricID, labels map[string]string, matchers model.LabelMatchers, log zap.Logger) (*model.Metrics, error) {
+	if len(matchers) == 0 {
+		if metric, found := storage.GetMetric(ctx, model.NewMetricKey(metricType, metricID).WithLabels(labels)); found {
+			return metric, nil
+		}
+	}
+
+	matched := Match(storage.GetKnownMetrics(ctx), metricType, metricID, append(equalityMatchers(labels), matchers...), log)
+	switch len(matched) {
+	case 0:
+		return nil, ErrNotFound
+	case 1:
+	default:
+		return nil, &AmbiguousError{Count: len(matched)}
+	}
+
+	metric, found := storage.GetMetric(ctx, matched[0])
+	if !found {
+		return nil, ErrNotFound
+	}
+	return metric, nil
+}
+
+// Match отбирает из keys серии метрики metricType с именем metricID, метки которых
+// удовлетворяют matchers.
+func Match(keys []model.MetricKey, metricType string, metricID enum.MetricID, matchers model.LabelMatchers, log zap.Logger) []model.MetricKey {
+	var matched []model.MetricKey
+	for _, key := range keys {
+		if key.MType != metricType || key.ID != metricID {
+			continue
+		}
+		labels, err := model.ParseCanonicalLabels(key.Labels)
+		if err != nil {
+			log.Warn(fmt.Sprintf("Skip series %s: %v", key, err))
+			continue
+		}
+		if matchers.Matches(labels) {
+			matched = append(matched, key)
+		}
+	}
+	return matched
+}
+
+// equalityMatchers преобразует метки в условия равенства.
+func equalityMatchers(labels map[string]string) model.LabelMatchers {
+	matchers := make(model.LabelMatchers, 0, len(labels))
+	for name, value := range labels {
+		matchers = append(matchers, &model.LabelMatcher{Name: name, Type: model.MatchEqual, Value: value})
+	}
+	return matchers
+}
@@ -0,0 +1,52 @@
+package lookup
+
+import (
+	"context"
+	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/model"
+	"github.com/ruslanDantsov/osmetrics-server/internal/server/repository/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"testing"
+)
+
+func TestFind(t *testing.T) {
+	ctx := context.Background()
+	storage := memory.NewMemStorage(*zap.NewNop(), 0)
+	save := func(labels map[string]string, value float64) {
+		_, err := storage.SaveMetric(ctx, &model.Metrics{ID: "Alloc", MType: "gauge", Value: &value, Labels: labels})
+		require.NoError(t, err)
+	}
+	save(map[string]string{"host": "web-1"}, 1)
+	save(map[string]string{"host": "web-1", "disk": "sda"}, 2)
+	save(map[string]string{"host": "web-2"}, 3)
+
+	hostMatcher, err := model.ParseLabelMatcher("host=~web-.*")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		labels   map[string]string
+		matchers model.LabelMatchers
+		want     float64
+		wantErr  error
+	}{
+		{name: "exact labels win over supersets", labels: map[string]string{"host": "web-1"}, want: 1},
+		{name: "unique subset match", labels: map[string]string{"disk": "sda"}, want: 2},
+		{name: "ambiguous without labels", wantErr: &AmbiguousError{Count: 3}},
+		{name: "ambiguous matchers", matchers: model.LabelMatchers{hostMatcher}, wantErr: &AmbiguousError{Count: 3}},
+		{name: "not found", labels: map[string]string{"host": "web-3"}, wantErr: ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metric, err := Find(ctx, storage, "gauge", "Alloc", tt.labels, tt.matchers, *zap.NewNop())
+			if tt.wantErr != nil {
+				assert.Equal(t, tt.wantErr, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, *metric.Value)
+		})
+	}
+}
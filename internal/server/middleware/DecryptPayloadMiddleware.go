@@ -6,12 +6,17 @@ import (
 	"encoding/base64"
 	"github.com/gin-gonic/gin"
 	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/crypto"
+	"github.com/ruslanDantsov/osmetrics-server/internal/server/telemetry"
 	"go.uber.org/zap"
 	"io"
 	"net/http"
 )
 
-func NewDecryptPayloadMiddleware(privKey *rsa.PrivateKey, logger *zap.Logger) gin.HandlerFunc {
+// NewDecryptPayloadMiddleware возвращает middleware для Gin, расшифровывающий тело запроса закрытым ключом privKey.
+//
+// Тело ожидается в Base64 и содержит конверт RSA-OAEP + AES-GCM.
+// Если allowLegacy включён, принимается также устаревший формат RSA PKCS#1 v1.5.
+func NewDecryptPayloadMiddleware(privKey *rsa.PrivateKey, allowLegacy bool, logger *zap.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		if privKey == nil {
 			c.Next()
@@ -35,14 +40,16 @@ func NewDecryptPayloadMiddleware(privKey *rsa.PrivateKey, logger *zap.Logger) gi
 		cipherData, err := base64.StdEncoding.DecodeString(string(encryptedBody))
 		if err != nil {
 			logger.Error("failed to decode base64 payload", zap.Error(err))
+			markRejected(c, telemetry.StageDecrypt)
 			c.AbortWithStatus(http.StatusBadRequest)
 			return
 		}
 
-		// расшифровываем RSA
-		plain, err := crypto.DecryptRSA(privKey, cipherData)
+		// расшифровываем конверт или, если разрешено, устаревший формат
+		plain, err := crypto.DecryptPayload(privKey, cipherData, allowLegacy)
 		if err != nil {
 			logger.Error("failed to decrypt payload", zap.Error(err))
+			markRejected(c, telemetry.StageDecrypt)
 			c.AbortWithStatus(http.StatusBadRequest)
 			return
 		}
@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/ruslanDantsov/osmetrics-server/internal/server/constants"
+	"net/http"
+)
+
+// AgentTracker отмечает активность агентов.
+type AgentTracker interface {
+	Seen(id, address string)
+}
+
+// NewAgentSeenMiddleware возвращает middleware для Gin, который после успешной обработки запроса
+// отмечает в tracker активность агента из заголовка X-Agent-ID. Адрес агента берётся
+// из заголовка X-Real-IP, а при его отсутствии — из адреса клиента.
+func NewAgentSeenMiddleware(tracker AgentTracker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		agentID := c.GetHeader(constants.AgentIDHeaderName)
+		if agentID == "" || c.Writer.Status() >= http.StatusBadRequest {
+			return
+		}
+
+		address := c.GetHeader(constants.RealIPHeaderName)
+		if address == "" {
+			address = c.ClientIP()
+		}
+		tracker.Seen(agentID, address)
+	}
+}
@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/ruslanDantsov/osmetrics-server/internal/server/agents"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAgentSeenMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	registry := agents.NewRegistry()
+
+	r := gin.New()
+	r.Use(NewAgentSeenMiddleware(registry))
+	r.POST("/update", func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.POST("/broken", func(c *gin.Context) { c.Status(http.StatusBadRequest) })
+
+	send := func(path, agentID, realIP string) {
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		if agentID != "" {
+			req.Header.Set("X-Agent-ID", agentID)
+		}
+		if realIP != "" {
+			req.Header.Set("X-Real-IP", realIP)
+		}
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	send("/update", "web-1", "10.0.0.1")
+	send("/update", "web-2", "")
+	send("/update", "", "10.0.0.3")
+	send("/broken", "web-4", "10.0.0.4")
+
+	records := registry.List()
+	require.Len(t, records, 2)
+	assert.Equal(t, "web-1", records[0].ID)
+	assert.Equal(t, "10.0.0.1", records[0].Address)
+	assert.Equal(t, "web-2", records[1].ID)
+	assert.Equal(t, "192.0.2.1", records[1].Address)
+}
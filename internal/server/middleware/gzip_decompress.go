@@ -3,6 +3,7 @@ package middleware
 import (
 	"compress/gzip"
 	"github.com/gin-gonic/gin"
+	"github.com/ruslanDantsov/osmetrics-server/internal/server/telemetry"
 	"io"
 	"net/http"
 	"strings"
@@ -17,6 +18,7 @@ func NewGzipDecompressionMiddleware() gin.HandlerFunc {
 		if strings.Contains(contentEncoding, "gzip") {
 			gzipReader, err := gzip.NewReader(c.Request.Body)
 			if err != nil {
+				markRejected(c, telemetry.StageGzip)
 				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
 					"error": "Invalid gzip data",
 				})
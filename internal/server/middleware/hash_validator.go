@@ -7,6 +7,7 @@ import (
 	"encoding/hex"
 	"github.com/gin-gonic/gin"
 	"github.com/ruslanDantsov/osmetrics-server/internal/server/constants"
+	"github.com/ruslanDantsov/osmetrics-server/internal/server/telemetry"
 	"go.uber.org/zap"
 	"io"
 	"net/http"
@@ -59,6 +60,7 @@ func HashCheckerMiddleware(hashSecretKey string, logger *zap.Logger) gin.Handler
 					zap.String("expected", serverHash),
 					zap.String("received", agentHash),
 				)
+				markRejected(c, telemetry.StageHash)
 				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
 					"error": "Invalid request signature",
 				})
@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/ruslanDantsov/osmetrics-server/internal/server/telemetry"
+	"time"
+)
+
+// rejectedStageKey — ключ контекста Gin, под которым middleware сохраняет стадию, на которой запрос был отклонён.
+const rejectedStageKey = "osmetrics.rejected_stage"
+
+// unmatchedRoute — значение метки route для запросов, не попавших ни в один маршрут.
+const unmatchedRoute = "unmatched"
+
+// NewMetricsMiddleware возвращает middleware для Gin, который учитывает в telemetry
+// длительность, статус и размеры каждого запроса, а также отказы остальных middleware.
+//
+// Должен подключаться первым, чтобы охватывать остальные middleware.
+func NewMetricsMiddleware(t *telemetry.Telemetry) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+
+		responseSize := int64(c.Writer.Size())
+		if responseSize < 0 {
+			responseSize = 0
+		}
+
+		t.ObserveRequest(route, c.Request.Method, c.Writer.Status(), time.Since(start), c.Request.ContentLength, responseSize)
+
+		if stage := c.GetString(rejectedStageKey); stage != "" {
+			t.IncRejected(stage)
+		}
+	}
+}
+
+// markRejected отмечает, что запрос отклонён на стадии stage (см. telemetry.Stage*).
+func markRejected(c *gin.Context, stage string) {
+	c.Set(rejectedStageKey, stage)
+}
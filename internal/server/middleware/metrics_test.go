@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"bytes"
+	"github.com/gin-gonic/gin"
+	"github.com/ruslanDantsov/osmetrics-server/internal/server/telemetry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func scrape(t *testing.T, tele *telemetry.Telemetry) string {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	tele.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	body, err := io.ReadAll(w.Body)
+	require.NoError(t, err)
+	return string(body)
+}
+
+func TestMetricsMiddleware_ObservesRequestsByRoute(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tele := telemetry.New()
+
+	r := gin.New()
+	r.Use(NewMetricsMiddleware(tele))
+	r.POST("/update/:type/:name/:value", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/update/gauge/Alloc/1", bytes.NewBufferString("body")))
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/missing", nil))
+
+	metrics := scrape(t, tele)
+	assert.Contains(t, metrics, `osmetrics_http_request_duration_seconds_count{method="POST",route="/update/:type/:name/:value",status="200"} 1`)
+	assert.Contains(t, metrics, `osmetrics_http_request_duration_seconds_count{method="GET",route="unmatched",status="404"} 1`)
+	assert.Contains(t, metrics, `osmetrics_http_request_size_bytes_sum{route="/update/:type/:name/:value"} 4`)
+	assert.Contains(t, metrics, `osmetrics_http_response_size_bytes_sum{route="/update/:type/:name/:value"} 2`)
+}
+
+func TestMetricsMiddleware_CountsRejectedRequests(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tele := telemetry.New()
+
+	r := gin.New()
+	r.Use(NewMetricsMiddleware(tele))
+	r.Use(NewGzipDecompressionMiddleware())
+	r.POST("/updates/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	req := httptest.NewRequest(http.MethodPost, "/updates/", bytes.NewBufferString("not gzip"))
+	req.Header.Set("Content-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, scrape(t, tele), `osmetrics_http_rejected_requests_total{stage="gzip"} 1`)
+}
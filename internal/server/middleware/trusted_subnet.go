@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/ruslanDantsov/osmetrics-server/internal/server/constants"
+	"github.com/ruslanDantsov/osmetrics-server/internal/server/telemetry"
+	"go.uber.org/zap"
+	"net"
+	"net/http"
+)
+
+// NewTrustedSubnetMiddleware возвращает middleware для Gin, который пропускает запрос
+// только если IP-адрес из заголовка X-Real-IP входит в доверенную подсеть trustedNet.
+//
+// Если trustedNet равен nil, проверка не выполняется.
+// Если заголовок отсутствует, некорректен или адрес не входит в подсеть, возвращается 403 Forbidden.
+func NewTrustedSubnetMiddleware(trustedNet *net.IPNet, logger *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if trustedNet == nil {
+			c.Next()
+			return
+		}
+
+		realIP := c.GetHeader(constants.RealIPHeaderName)
+		ip := net.ParseIP(realIP)
+		if ip == nil || !trustedNet.Contains(ip) {
+			logger.Warn("Request from untrusted address",
+				zap.String("method", c.Request.Method),
+				zap.String("path", c.Request.URL.Path),
+				zap.String("real_ip", realIP),
+			)
+			markRejected(c, telemetry.StageTrustedSubnet)
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error": "Forbidden",
+			})
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTrustedSubnetMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	_, trustedNet, _ := net.ParseCIDR("192.168.1.0/24")
+
+	tests := []struct {
+		name       string
+		trustedNet *net.IPNet
+		realIP     string
+		remoteAddr string
+		wantStatus int
+	}{
+		{name: "address inside subnet", trustedNet: trustedNet, realIP: "192.168.1.10", wantStatus: http.StatusOK},
+		{name: "address outside subnet", trustedNet: trustedNet, realIP: "10.0.0.1", wantStatus: http.StatusForbidden},
+		{name: "missing header", trustedNet: trustedNet, wantStatus: http.StatusForbidden},
+		{name: "missing header from trusted remote address", trustedNet: trustedNet, remoteAddr: "192.168.1.10:5000", wantStatus: http.StatusForbidden},
+		{name: "invalid header", trustedNet: trustedNet, realIP: "not-an-ip", wantStatus: http.StatusForbidden},
+		{name: "subnet is not set", realIP: "10.0.0.1", wantStatus: http.StatusOK},
+		{name: "subnet is not set and header is missing", wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			r.Use(NewTrustedSubnetMiddleware(tt.trustedNet, zap.NewNop()))
+			r.POST("/update", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			req := httptest.NewRequest(http.MethodPost, "/update", nil)
+			if tt.realIP != "" {
+				req.Header.Set("X-Real-IP", tt.realIP)
+			}
+			if tt.remoteAddr != "" {
+				req.RemoteAddr = tt.remoteAddr
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+		})
+	}
+}
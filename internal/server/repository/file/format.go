@@ -0,0 +1,157 @@
+package file
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/model"
+	"strconv"
+	"strings"
+)
+
+// formatVersion — текущая версия формата файла метрик.
+//
+// Версия 1 (без поля version) — JSON-объект {"имя": метрика}: имя было единственным ключом,
+// поэтому gauge и counter с одинаковым именем не различались.
+// Версия 2 — {"version": 2, "metrics": [...]}, ключом метрики служит пара (тип, имя).
+// Версия 3 — тело версии 2 с "version": 3, перед которым идёт строка заголовка
+// с длиной и контрольной суммой SHA-256 тела (см. snapshotHeaderPrefix).
+const formatVersion = 3
+
+// snapshotHeaderPrefix начинает строку заголовка файла версии 3:
+// "osmetrics-snapshot 3 <длина тела> <sha256 тела в hex>\n".
+const snapshotHeaderPrefix = "osmetrics-snapshot "
+
+// snapshot — содержимое файла метрик в текущем формате.
+type snapshot struct {
+	Version int              `json:"version"`
+	Metrics []*model.Metrics `json:"metrics"`
+
+	// WALGeneration — поколение журнала предзаписи, записи которого уже учтены в снимке.
+	WALGeneration int64 `json:"wal_generation,omitempty"`
+}
+
+// decodedSnapshot — результат разбора файла метрик.
+type decodedSnapshot struct {
+	metrics       map[model.MetricKey]*model.Metrics
+	version       int
+	walGeneration int64
+}
+
+// newSnapshot формирует содержимое файла из метрик хранилища.
+func newSnapshot(metrics []*model.Metrics) snapshot {
+	if metrics == nil {
+		metrics = []*model.Metrics{}
+	}
+	return snapshot{Version: formatVersion, Metrics: metrics}
+}
+
+// encodeSnapshot сериализует снимок в файл текущей версии: заголовок с контрольной суммой и JSON-тело.
+func encodeSnapshot(s snapshot) ([]byte, error) {
+	body, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(body)
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%s%d %d %s\n", snapshotHeaderPrefix, formatVersion, len(body), hex.EncodeToString(sum[:]))
+	buf.Write(body)
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+// decodeSnapshot разбирает файл метрик любой поддерживаемой версии
+// и возвращает метрики, сгруппированные по ключу (тип, имя), а также версию исходного файла.
+// Для версии 3 проверяются длина и контрольная сумма тела.
+func decodeSnapshot(data []byte) (decodedSnapshot, error) {
+	if bytes.HasPrefix(data, []byte(snapshotHeaderPrefix)) {
+		body, err := verifySnapshot(data)
+		if err != nil {
+			return decodedSnapshot{}, err
+		}
+
+		var s snapshot
+		if err := json.Unmarshal(body, &s); err != nil {
+			return decodedSnapshot{}, err
+		}
+		if s.Version != formatVersion {
+			return decodedSnapshot{}, fmt.Errorf("unsupported metrics file version: %d", s.Version)
+		}
+		return decodedSnapshot{metrics: indexMetrics(s.Metrics), version: s.Version, walGeneration: s.WALGeneration}, nil
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return decodedSnapshot{}, err
+	}
+
+	var version int
+	if raw, ok := fields["version"]; ok && json.Unmarshal(raw, &version) == nil {
+		if version != 2 {
+			return decodedSnapshot{}, fmt.Errorf("unsupported metrics file version without header: %d", version)
+		}
+
+		var s snapshot
+		if err := json.Unmarshal(data, &s); err != nil {
+			return decodedSnapshot{}, err
+		}
+		return decodedSnapshot{metrics: indexMetrics(s.Metrics), version: version}, nil
+	}
+
+	legacy := make(map[string]*model.Metrics, len(fields))
+	if err := json.Unmarshal(data, &legacy); err != nil {
+		return decodedSnapshot{}, err
+	}
+
+	metrics := make([]*model.Metrics, 0, len(legacy))
+	for _, metric := range legacy {
+		metrics = append(metrics, metric)
+	}
+	return decodedSnapshot{metrics: indexMetrics(metrics), version: 1}, nil
+}
+
+// verifySnapshot разбирает заголовок файла версии 3 и возвращает тело,
+// если его длина и контрольная сумма совпадают с указанными в заголовке.
+func verifySnapshot(data []byte) ([]byte, error) {
+	headerEnd := bytes.IndexByte(data, '\n')
+	if headerEnd < 0 {
+		return nil, errors.New("truncated snapshot header")
+	}
+
+	fields := strings.Fields(strings.TrimPrefix(string(data[:headerEnd]), snapshotHeaderPrefix))
+	if len(fields) != 3 {
+		return nil, fmt.Errorf("malformed snapshot header: %q", data[:headerEnd])
+	}
+	if fields[0] != strconv.Itoa(formatVersion) {
+		return nil, fmt.Errorf("unsupported metrics file version: %s", fields[0])
+	}
+	length, err := strconv.Atoi(fields[1])
+	if err != nil || length < 0 {
+		return nil, fmt.Errorf("malformed snapshot length: %q", fields[1])
+	}
+
+	body := bytes.TrimSuffix(data[headerEnd+1:], []byte("\n"))
+	if len(body) != length {
+		return nil, fmt.Errorf("snapshot body has %d bytes, header declares %d", len(body), length)
+	}
+	sum := sha256.Sum256(body)
+	if hex.EncodeToString(sum[:]) != fields[2] {
+		return nil, errors.New("snapshot checksum mismatch")
+	}
+	return body, nil
+}
+
+func indexMetrics(metrics []*model.Metrics) map[model.MetricKey]*model.Metrics {
+	storage := make(map[model.MetricKey]*model.Metrics, len(metrics))
+	for _, metric := range metrics {
+		if metric == nil || metric.MType == "" {
+			continue
+		}
+		storage[metric.Key()] = metric
+	}
+	return storage
+}
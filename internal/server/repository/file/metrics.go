@@ -2,22 +2,28 @@ package file
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/model"
-	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/model/enum"
 	"github.com/ruslanDantsov/osmetrics-server/internal/server/repository/memory"
 	"go.uber.org/zap"
 	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
 // MemoryStorager определяет интерфейс для работы с метриками в памяти.
 type MemoryStorager interface {
-	// GetKnownMetrics возвращает список известных имён метрик.
-	GetKnownMetrics(ctx context.Context) []string
+	// GetKnownMetrics возвращает ключи известных метрик.
+	GetKnownMetrics(ctx context.Context) []model.MetricKey
 
-	// GetMetric возвращает метрику по её идентификатору.
-	GetMetric(ctx context.Context, metricID enum.MetricID) (*model.Metrics, bool)
+	// GetMetric возвращает метрику по ключу серии.
+	GetMetric(ctx context.Context, key model.MetricKey) (*model.Metrics, bool)
+
+	// GetSeries возвращает точки истории серии за интервал [from, to].
+	GetSeries(ctx context.Context, key model.MetricKey, from, to time.Time) ([]model.Sample, error)
 
 	// SaveMetric сохраняет одну метрику
 	SaveMetric(ctx context.Context, metric *model.Metrics) (*model.Metrics, error)
@@ -33,28 +39,63 @@ type MemoryStorager interface {
 }
 
 // PersistentStorage реализует персистентное хранилище метрик.
+//
+// Снимок записывается во временный файл и атомарно переименовывается в filePath;
+// keepSnapshots предыдущих снимков хранятся рядом как filePath.1 (самый новый), filePath.2 и т.д.
+//
+// Если включён журнал предзаписи (filePath.wal), каждое принятое обновление сначала дописывается в него,
+// а каждый снимок очищает журнал. При восстановлении журнал применяется поверх снимка.
 type PersistentStorage struct {
 	base          MemoryStorager
 	filePath      string
+	keepSnapshots int
+	saveMu        sync.Mutex
 	logger        zap.Logger
 	ticker        *time.Ticker
 	quit          chan struct{}
+	done          chan struct{}
+	shutdownOnce  sync.Once
 	storeInterval time.Duration
 	isRestore     bool
+
+	// wal — журнал предзаписи; nil, если журнал не используется.
+	wal *writeAheadLog
+	// walMu удерживается на чтение обновлениями (запись в журнал и применение)
+	// и на запись снимком, чтобы очистка журнала не теряла и не дублировала обновления.
+	walMu sync.RWMutex
+
+	snapshotObserver atomic.Pointer[SnapshotObserver]
+}
+
+// SnapshotObserver получает длительность и результат каждой записи снимка метрик в файл.
+type SnapshotObserver interface {
+	ObserveSnapshot(duration time.Duration, err error)
 }
 
 // NewPersistentStorage создаёт новое персистентное хранилище.
-func NewPersistentStorage(base MemoryStorager, filePath string, storeInterval time.Duration, logger zap.Logger, isRestore bool) *PersistentStorage {
+// keepSnapshots задаёт число хранимых предыдущих снимков; 0 оставляет только текущий.
+// useWAL включает журнал предзаписи; он имеет смысл только при storeInterval > 0,
+// так как иначе снимок записывается после каждого обновления. Если журнал не используется,
+// журнал прошлого запуска применяется при восстановлении и удаляется (см. retireWAL).
+func NewPersistentStorage(base MemoryStorager, filePath string, storeInterval time.Duration, logger zap.Logger, isRestore bool, keepSnapshots int, useWAL bool) *PersistentStorage {
 	ps := &PersistentStorage{
 		base:          base,
 		filePath:      filePath,
+		keepSnapshots: keepSnapshots,
 		logger:        logger,
 		quit:          make(chan struct{}),
+		done:          make(chan struct{}),
 		storeInterval: storeInterval,
 		isRestore:     isRestore,
 	}
+	var walGeneration int64
 	if isRestore {
-		ps.loadFromFile()
+		walGeneration = ps.loadFromFile()
+	}
+	if useWAL && storeInterval > 0 {
+		ps.openWAL(walGeneration)
+	} else {
+		ps.retireWAL(walGeneration)
 	}
 
 	if storeInterval > 0 {
@@ -63,14 +104,21 @@ func NewPersistentStorage(base MemoryStorager, filePath string, storeInterval ti
 	return ps
 }
 
+// SetSnapshotObserver задаёт получателя сведений о записи снимков.
+func (ps *PersistentStorage) SetSnapshotObserver(observer SnapshotObserver) {
+	ps.snapshotObserver.Store(&observer)
+}
+
 // HealthCheck проверяет состояние персистентного хранилища.
 func (ps *PersistentStorage) HealthCheck(ctx context.Context) error {
 	return nil
 }
 
-// Close закрывает персистентное хранилище.
+// Close закрывает персистентное хранилище: выполняет финальное сохранение в файл
+// и закрывает базовое хранилище.
 func (ps *PersistentStorage) Close() {
-	//For this type of storage we don't need implementation
+	ps.Shutdown()
+	ps.base.Close()
 }
 
 // StartAutoSave запускает периодическое автосохранение данных с заданным интервалом.
@@ -78,13 +126,13 @@ func (ps *PersistentStorage) StartAutoSave(interval time.Duration) {
 	ps.ticker = time.NewTicker(interval)
 
 	go func() {
+		defer close(ps.done)
 		for {
 			select {
 			case <-ps.ticker.C:
 				ps.saveToFile()
 			case <-ps.quit:
 				ps.ticker.Stop()
-				ps.saveToFile()
 				return
 			}
 		}
@@ -92,15 +140,35 @@ func (ps *PersistentStorage) StartAutoSave(interval time.Duration) {
 }
 
 // Shutdown корректно завершает работу автосохранения и сохраняет данные в файл.
+// Дожидается остановки автосохранения, поэтому финальное сохранение выполняется ровно один раз.
+// Повторные вызовы ничего не делают.
 func (ps *PersistentStorage) Shutdown() {
-	if ps.storeInterval > 0 {
-		close(ps.quit)
-	}
-	ps.saveToFile()
+	ps.shutdownOnce.Do(func() {
+		if ps.ticker != nil {
+			close(ps.quit)
+			<-ps.done
+		}
+		ps.saveToFile()
+
+		if ps.wal != nil {
+			if err := ps.wal.Close(); err != nil {
+				ps.logger.Error("Failed to close WAL", zap.Error(err))
+			}
+		}
+	})
 }
 
 // SaveMetric сохраняет одну метрику в базовое хранилище и при необходимости сохраняет данные в файл.
 func (ps *PersistentStorage) SaveMetric(ctx context.Context, m *model.Metrics) (*model.Metrics, error) {
+	if ps.wal != nil {
+		ps.walMu.RLock()
+		defer ps.walMu.RUnlock()
+
+		if err := ps.wal.Append(model.MetricsList{*m}); err != nil {
+			return nil, err
+		}
+	}
+
 	result, err := ps.base.SaveMetric(ctx, m)
 	if err != nil {
 		return nil, err
@@ -115,6 +183,15 @@ func (ps *PersistentStorage) SaveMetric(ctx context.Context, m *model.Metrics) (
 
 // SaveAllMetrics сохраняет список метрик в базовое хранилище и при необходимости сохраняет данные в файл.
 func (ps *PersistentStorage) SaveAllMetrics(ctx context.Context, metricList model.MetricsList) (model.MetricsList, error) {
+	if ps.wal != nil {
+		ps.walMu.RLock()
+		defer ps.walMu.RUnlock()
+
+		if err := ps.wal.Append(metricList); err != nil {
+			return nil, err
+		}
+	}
+
 	result, err := ps.base.SaveAllMetrics(ctx, metricList)
 	if err != nil {
 		return nil, err
@@ -127,65 +204,207 @@ func (ps *PersistentStorage) SaveAllMetrics(ctx context.Context, metricList mode
 	return result, nil
 }
 
-// GetMetric возвращает метрику из базового хранилища по идентификатору.
-func (ps *PersistentStorage) GetMetric(ctx context.Context, metricID enum.MetricID) (*model.Metrics, bool) {
-	return ps.base.GetMetric(ctx, metricID)
+// GetMetric возвращает метрику из базового хранилища по ключу серии.
+func (ps *PersistentStorage) GetMetric(ctx context.Context, key model.MetricKey) (*model.Metrics, bool) {
+	return ps.base.GetMetric(ctx, key)
 }
 
-// GetKnownMetrics возвращает список известных метрик из базового хранилища.
-func (ps *PersistentStorage) GetKnownMetrics(ctx context.Context) []string {
+// GetKnownMetrics возвращает ключи известных метрик из базового хранилища.
+func (ps *PersistentStorage) GetKnownMetrics(ctx context.Context) []model.MetricKey {
 	return ps.base.GetKnownMetrics(ctx)
 }
 
+// GetSeries возвращает точки истории серии из базового хранилища. История в файл не сохраняется.
+func (ps *PersistentStorage) GetSeries(ctx context.Context, key model.MetricKey, from, to time.Time) ([]model.Sample, error) {
+	return ps.base.GetSeries(ctx, key, from, to)
+}
+
 func (ps *PersistentStorage) saveToFile() {
+	start := time.Now()
+	err := ps.writeSnapshot()
+	if observer := ps.snapshotObserver.Load(); observer != nil {
+		(*observer).ObserveSnapshot(time.Since(start), err)
+	}
+
+	if err != nil {
+		ps.logger.Error("Failed to store metrics data", zap.Error(err))
+		return
+	}
+	ps.logger.Info("Metrics data have been stored")
+}
+
+// writeSnapshot записывает текущие метрики базового хранилища в файл.
+//
+// Снимок формируется из копий метрик хранилища, затем записывается во временный файл
+// в той же директории, сбрасывается на диск и атомарно переименовывается в filePath.
+// Прежний снимок перед этим сдвигается в историю (см. rotateSnapshots).
+// При включённом журнале обновления на время записи снимка приостанавливаются, а после неё журнал очищается.
+func (ps *PersistentStorage) writeSnapshot() error {
 	memStorage, ok := ps.base.(*memory.MemStorage)
 	if !ok {
-		ps.logger.Error("saveToFile: base is not MemStorage, skipping file save")
-		return
+		return errors.New("base is not MemStorage, skipping file save")
 	}
 
-	memStorage.Mu.RLock()
-	defer memStorage.Mu.RUnlock()
+	ps.saveMu.Lock()
+	defer ps.saveMu.Unlock()
+
+	if ps.wal != nil {
+		ps.walMu.Lock()
+		defer ps.walMu.Unlock()
+	}
 
-	file, err := os.Create(ps.filePath)
+	s := newSnapshot(memStorage.Snapshot())
+	if ps.wal != nil {
+		s.WALGeneration = ps.wal.Generation()
+	}
+	data, err := encodeSnapshot(s)
 	if err != nil {
-		ps.logger.Error("Failed to create save file", zap.Error(err))
-		return
+		return fmt.Errorf("failed to encode metrics: %w", err)
 	}
-	defer file.Close()
 
-	if err := json.NewEncoder(file).Encode(memStorage.Storage); err != nil {
-		ps.logger.Error("Failed to encode metrics", zap.Error(err))
+	tmpPath, err := writeTempFile(ps.filePath, data)
+	if err != nil {
+		return err
 	}
-	ps.logger.Info("Metrics data have been stored")
+	if err := rotateSnapshots(ps.filePath, ps.keepSnapshots); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Rename(tmpPath, ps.filePath); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to replace save file: %w", err)
+	}
+	if err := syncDir(filepath.Dir(ps.filePath)); err != nil {
+		return err
+	}
+
+	if ps.wal != nil {
+		if err := ps.wal.Reset(nextWALGeneration(s.WALGeneration)); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-func (ps *PersistentStorage) loadFromFile() {
+// loadFromFile восстанавливает метрики из самого нового корректного снимка:
+// сначала из filePath, затем по очереди из предыдущих снимков.
+// Возвращает поколение журнала предзаписи, учтённое в восстановленном снимке.
+func (ps *PersistentStorage) loadFromFile() int64 {
 	memStorage, ok := ps.base.(*memory.MemStorage)
 	if !ok {
 		ps.logger.Error("loadFromFile: base is not MemStorage, skipping file load")
-		return
+		return 0
 	}
 
-	file, err := os.Open(ps.filePath)
+	candidates, err := snapshotFiles(ps.filePath)
 	if err != nil {
-		if os.IsNotExist(err) {
-			ps.logger.Info("No restore file found; starting fresh")
-			return
+		ps.logger.Error("Failed to list restore files", zap.Error(err))
+		return 0
+	}
+	if len(candidates) == 0 {
+		ps.logger.Info("No restore file found; starting fresh")
+		return 0
+	}
+
+	for _, path := range candidates {
+		restored, err := readSnapshot(path)
+		if err != nil {
+			ps.logger.Warn("Skipping invalid metrics snapshot", zap.String("path", path), zap.Error(err))
+			continue
+		}
+		if path != ps.filePath {
+			ps.logger.Warn("Metrics restored from a previous snapshot", zap.String("path", path))
+		}
+		if restored.version < formatVersion {
+			ps.logger.Info("Metrics file has an old format and will be upgraded on next save",
+				zap.Int("version", restored.version), zap.Int("target_version", formatVersion))
+		}
+
+		memStorage.Replace(restored.metrics)
+		ps.logger.Info("Metrics restored from file", zap.String("path", path), zap.Int("count", len(restored.metrics)))
+		return restored.walGeneration
+	}
+
+	ps.logger.Error("No valid metrics snapshot found; starting fresh", zap.Int("candidates", len(candidates)))
+	return 0
+}
+
+// openWAL открывает журнал предзаписи. При восстановлении применяет записи журнала, если они
+// новее снимка с поколением snapshotGeneration, и продолжает этот журнал; иначе начинает новый.
+// Если журнал открыть не удалось, хранилище работает без него.
+func (ps *PersistentStorage) openWAL(snapshotGeneration int64) {
+	path := ps.filePath + walSuffix
+	generation, size := nextWALGeneration(snapshotGeneration), int64(0)
+
+	if ps.isRestore {
+		contents, err := readWAL(path)
+		switch {
+		case err != nil:
+			ps.logger.Warn("Skipping unreadable WAL", zap.String("path", path), zap.Error(err))
+		case contents.generation > snapshotGeneration:
+			ps.replayWAL(contents)
+			generation, size = contents.generation, contents.size
+		case len(contents.records) > 0:
+			ps.logger.Info("WAL is already included in the snapshot; discarding it", zap.Int("records", len(contents.records)))
 		}
-		ps.logger.Error("Failed to open restore file", zap.Error(err))
+	}
+
+	wal, err := openWAL(path, generation, size)
+	if err != nil {
+		ps.logger.Error("Failed to open WAL; updates are persisted only by snapshots", zap.Error(err))
+		return
+	}
+	ps.wal = wal
+}
+
+// retireWAL обрабатывает журнал, оставшийся от запуска с включённым журналом, когда он выключен.
+//
+// При восстановлении записи журнала, новее снимка с поколением snapshotGeneration, применяются
+// и сразу записываются в снимок, после чего журнал удаляется. Без восстановления журнал относится
+// к отброшенному состоянию и удаляется, чтобы позже не быть применённым поверх более нового снимка.
+// Если журнал не удалось прочитать или снимок не записался, файл журнала сохраняется.
+func (ps *PersistentStorage) retireWAL(snapshotGeneration int64) {
+	path := ps.filePath + walSuffix
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
 		return
 	}
-	defer file.Close()
 
-	data := make(map[string]*model.Metrics)
-	if err := json.NewDecoder(file).Decode(&data); err != nil {
-		ps.logger.Error("Failed to decode metrics file", zap.Error(err))
+	if ps.isRestore {
+		contents, err := readWAL(path)
+		if err != nil {
+			ps.logger.Error("Failed to read WAL left by a previous run; it is kept", zap.String("path", path), zap.Error(err))
+			return
+		}
+		if contents.generation > snapshotGeneration && len(contents.records) > 0 {
+			ps.replayWAL(contents)
+			if err := ps.writeSnapshot(); err != nil {
+				ps.logger.Error("Failed to store metrics restored from WAL; WAL is kept", zap.Error(err))
+				return
+			}
+		}
+	}
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		ps.logger.Error("Failed to remove WAL", zap.String("path", path), zap.Error(err))
 		return
 	}
+	ps.logger.Info("WAL is disabled; removed WAL left by a previous run", zap.String("path", path))
+}
 
-	memStorage.Mu.Lock()
-	defer memStorage.Mu.Unlock()
-	memStorage.Storage = data
-	ps.logger.Info("Metrics restored from file", zap.Int("count", len(data)))
+// replayWAL применяет записи журнала к базовому хранилищу.
+func (ps *PersistentStorage) replayWAL(contents walContents) {
+	ctx := context.Background()
+	applied := 0
+	for _, record := range contents.records {
+		if _, err := ps.base.SaveAllMetrics(ctx, record); err != nil {
+			ps.logger.Error("Failed to replay WAL record", zap.Error(err))
+			continue
+		}
+		applied += len(record)
+	}
+
+	if contents.torn {
+		ps.logger.Warn("WAL ends with an incomplete record; it is discarded")
+	}
+	ps.logger.Info("Metrics restored from WAL", zap.Int("records", len(contents.records)), zap.Int("metrics", applied))
 }
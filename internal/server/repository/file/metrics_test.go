@@ -5,28 +5,38 @@ import (
 	"errors"
 	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/model"
 	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/model/enum"
+	"github.com/ruslanDantsov/osmetrics-server/internal/server/repository/memory"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 type MockMemoryStorager struct {
 	mock.Mock
-	Storage map[string]*model.Metrics
+	Storage map[model.MetricKey]*model.Metrics
 }
 
-func (m *MockMemoryStorager) GetKnownMetrics(ctx context.Context) []string {
+func (m *MockMemoryStorager) GetKnownMetrics(ctx context.Context) []model.MetricKey {
 	args := m.Called(ctx)
-	return args.Get(0).([]string)
+	return args.Get(0).([]model.MetricKey)
 }
 
-func (m *MockMemoryStorager) GetMetric(ctx context.Context, metricID enum.MetricID) (*model.Metrics, bool) {
-	args := m.Called(ctx, metricID)
+func (m *MockMemoryStorager) GetMetric(ctx context.Context, key model.MetricKey) (*model.Metrics, bool) {
+	args := m.Called(ctx, key)
 	return args.Get(0).(*model.Metrics), args.Bool(1)
 }
 
+func (m *MockMemoryStorager) GetSeries(ctx context.Context, key model.MetricKey, from, to time.Time) ([]model.Sample, error) {
+	args := m.Called(ctx, key, from, to)
+	return args.Get(0).([]model.Sample), args.Error(1)
+}
+
 func (m *MockMemoryStorager) SaveMetric(ctx context.Context, metric *model.Metrics) (*model.Metrics, error) {
 	args := m.Called(ctx, metric)
 	return args.Get(0).(*model.Metrics), args.Error(1)
@@ -56,7 +66,7 @@ func TestPersistentStorage_SaveMetric(t *testing.T) {
 	metric := &model.Metrics{ID: "Alloc", MType: "gauge", Value: floatPointer(123.4)}
 	mockStorage.On("SaveMetric", ctx, metric).Return(metric, nil)
 
-	ps := NewPersistentStorage(mockStorage, filePath, 0, *logger, false)
+	ps := NewPersistentStorage(mockStorage, filePath, 0, *logger, false, 0, false)
 
 	result, err := ps.SaveMetric(ctx, metric)
 	assert.NoError(t, err)
@@ -76,7 +86,7 @@ func TestPersistentStorage_SaveMetric_Error(t *testing.T) {
 	mockErr := errors.New("save failed")
 	mockStorage.On("SaveMetric", ctx, metric).Return(metric, mockErr)
 
-	ps := NewPersistentStorage(mockStorage, filePath, 0, *logger, false)
+	ps := NewPersistentStorage(mockStorage, filePath, 0, *logger, false, 0, false)
 
 	result, err := ps.SaveMetric(ctx, metric)
 	assert.Nil(t, result)
@@ -97,7 +107,7 @@ func TestPersistentStorage_SaveAllMetrics(t *testing.T) {
 	}
 	mockStorage.On("SaveAllMetrics", ctx, metrics).Return(metrics, nil)
 
-	ps := NewPersistentStorage(mockStorage, filePath, 0, *logger, false)
+	ps := NewPersistentStorage(mockStorage, filePath, 0, *logger, false, 0, false)
 
 	result, err := ps.SaveAllMetrics(ctx, metrics)
 	assert.NoError(t, err)
@@ -119,7 +129,7 @@ func TestPersistentStorage_SaveAllMetrics_Error(t *testing.T) {
 	mockErr := errors.New("bulk save failed")
 	mockStorage.On("SaveAllMetrics", ctx, metrics).Return(metrics, mockErr)
 
-	ps := NewPersistentStorage(mockStorage, filePath, 0, *logger, false)
+	ps := NewPersistentStorage(mockStorage, filePath, 0, *logger, false, 0, false)
 
 	result, err := ps.SaveAllMetrics(ctx, metrics)
 	assert.Nil(t, result)
@@ -135,11 +145,11 @@ func TestPersistentStorage_GetMetric(t *testing.T) {
 	ctx := context.Background()
 	metricID := enum.MetricID("GC")
 	expected := &model.Metrics{ID: "GC", MType: "gauge", Value: floatPointer(12)}
-	mockStorage.On("GetMetric", ctx, metricID).Return(expected, true)
+	mockStorage.On("GetMetric", ctx, model.NewMetricKey("gauge", metricID)).Return(expected, true)
 
-	ps := NewPersistentStorage(mockStorage, "", 0, *logger, false)
+	ps := NewPersistentStorage(mockStorage, "", 0, *logger, false, 0, false)
 
-	result, ok := ps.GetMetric(ctx, metricID)
+	result, ok := ps.GetMetric(ctx, model.NewMetricKey("gauge", metricID))
 	assert.True(t, ok)
 	assert.Equal(t, expected, result)
 
@@ -151,10 +161,10 @@ func TestPersistentStorage_GetKnownMetrics(t *testing.T) {
 	logger := zap.NewNop()
 
 	ctx := context.Background()
-	expected := []string{"Alloc", "Heap"}
+	expected := []model.MetricKey{model.NewMetricKey("gauge", "Alloc"), model.NewMetricKey("gauge", "Heap")}
 	mockStorage.On("GetKnownMetrics", ctx).Return(expected)
 
-	ps := NewPersistentStorage(mockStorage, "", 0, *logger, false)
+	ps := NewPersistentStorage(mockStorage, "", 0, *logger, false, 0, false)
 
 	result := ps.GetKnownMetrics(ctx)
 	assert.Equal(t, expected, result)
@@ -162,6 +172,170 @@ func TestPersistentStorage_GetKnownMetrics(t *testing.T) {
 	mockStorage.AssertExpectations(t)
 }
 
+func TestPersistentStorage_Close_FlushesPendingMetrics(t *testing.T) {
+	logger := zap.NewNop()
+	filePath := filepath.Join(t.TempDir(), "metrics.json")
+	ctx := context.Background()
+
+	ps := NewPersistentStorage(memory.NewMemStorage(*logger, 0), filePath, time.Hour, *logger, false, 0, false)
+	_, err := ps.SaveMetric(ctx, &model.Metrics{ID: "Alloc", MType: "gauge", Value: floatPointer(42)})
+	assert.NoError(t, err)
+
+	ps.Close()
+	ps.Close()
+
+	restored := NewPersistentStorage(memory.NewMemStorage(*logger, 0), filePath, 0, *logger, true, 0, false)
+	metric, ok := restored.GetMetric(ctx, model.NewMetricKey("gauge", "Alloc"))
+	assert.True(t, ok)
+	assert.Equal(t, 42.0, *metric.Value)
+}
+
+func TestPersistentStorage_SameNameDifferentTypes(t *testing.T) {
+	logger := zap.NewNop()
+	filePath := filepath.Join(t.TempDir(), "metrics.json")
+	ctx := context.Background()
+	delta := int64(7)
+
+	ps := NewPersistentStorage(memory.NewMemStorage(*logger, 0), filePath, 0, *logger, false, 0, false)
+	_, err := ps.SaveMetric(ctx, &model.Metrics{ID: "Foo", MType: "gauge", Value: floatPointer(1.5)})
+	assert.NoError(t, err)
+	_, err = ps.SaveMetric(ctx, &model.Metrics{ID: "Foo", MType: "counter", Delta: &delta})
+	assert.NoError(t, err)
+
+	restored := NewPersistentStorage(memory.NewMemStorage(*logger, 0), filePath, 0, *logger, true, 0, false)
+
+	gauge, ok := restored.GetMetric(ctx, model.NewMetricKey("gauge", "Foo"))
+	assert.True(t, ok)
+	assert.Equal(t, 1.5, *gauge.Value)
+
+	counter, ok := restored.GetMetric(ctx, model.NewMetricKey("counter", "Foo"))
+	assert.True(t, ok)
+	assert.Equal(t, int64(7), *counter.Delta)
+}
+
+func TestPersistentStorage_RestoresLegacyFileFormat(t *testing.T) {
+	logger := zap.NewNop()
+	filePath := filepath.Join(t.TempDir(), "metrics.json")
+	ctx := context.Background()
+
+	legacy := `{"Alloc":{"id":"Alloc","type":"gauge","value":3.5},"PollCount":{"id":"PollCount","type":"counter","delta":4}}`
+	assert.NoError(t, os.WriteFile(filePath, []byte(legacy), 0600))
+
+	ps := NewPersistentStorage(memory.NewMemStorage(*logger, 0), filePath, 0, *logger, true, 0, false)
+
+	alloc, ok := ps.GetMetric(ctx, model.NewMetricKey("gauge", "Alloc"))
+	assert.True(t, ok)
+	assert.Equal(t, 3.5, *alloc.Value)
+
+	pollCount, ok := ps.GetMetric(ctx, model.NewMetricKey("counter", "PollCount"))
+	assert.True(t, ok)
+	assert.Equal(t, int64(4), *pollCount.Delta)
+
+	ps.Close()
+
+	data, err := os.ReadFile(filePath)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(data), snapshotHeaderPrefix))
+	assert.Contains(t, string(data), `"version":3`)
+}
+
+func TestPersistentStorage_RestoresHeaderlessV2File(t *testing.T) {
+	logger := zap.NewNop()
+	filePath := filepath.Join(t.TempDir(), "metrics.json")
+
+	v2 := `{"version":2,"metrics":[{"id":"Alloc","type":"gauge","value":3.5}]}`
+	assert.NoError(t, os.WriteFile(filePath, []byte(v2), 0600))
+
+	ps := NewPersistentStorage(memory.NewMemStorage(*logger, 0), filePath, 0, *logger, true, 0, false)
+
+	alloc, ok := ps.GetMetric(context.Background(), model.NewMetricKey("gauge", "Alloc"))
+	assert.True(t, ok)
+	assert.Equal(t, 3.5, *alloc.Value)
+}
+
+func TestPersistentStorage_KeepsPreviousSnapshots(t *testing.T) {
+	logger := zap.NewNop()
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "metrics.json")
+	ctx := context.Background()
+
+	ps := NewPersistentStorage(memory.NewMemStorage(*logger, 0), filePath, 0, *logger, false, 2, false)
+	for _, value := range []float64{1, 2, 3, 4} {
+		_, err := ps.SaveMetric(ctx, &model.Metrics{ID: "Alloc", MType: "gauge", Value: floatPointer(value)})
+		require.NoError(t, err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	assert.ElementsMatch(t, []string{"metrics.json", "metrics.json.1", "metrics.json.2"}, names)
+
+	for path, expected := range map[string]float64{filePath: 4, filePath + ".1": 3, filePath + ".2": 2} {
+		restored, err := readSnapshot(path)
+		require.NoError(t, err, path)
+		assert.Equal(t, expected, *restored.metrics[model.NewMetricKey("gauge", "Alloc")].Value, path)
+	}
+}
+
+func TestPersistentStorage_RestoresNewestValidSnapshot(t *testing.T) {
+	logger := zap.NewNop()
+	filePath := filepath.Join(t.TempDir(), "metrics.json")
+	ctx := context.Background()
+
+	ps := NewPersistentStorage(memory.NewMemStorage(*logger, 0), filePath, 0, *logger, false, 2, false)
+	for _, value := range []float64{1, 2, 3} {
+		_, err := ps.SaveMetric(ctx, &model.Metrics{ID: "Alloc", MType: "gauge", Value: floatPointer(value)})
+		require.NoError(t, err)
+	}
+
+	// Текущий снимок обрезан, в первом предыдущем испорчен один байт тела.
+	current, err := os.ReadFile(filePath)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filePath, current[:len(current)/2], 0600))
+
+	previous, err := os.ReadFile(filePath + ".1")
+	require.NoError(t, err)
+	previous[len(previous)-5] ^= 0x01
+	require.NoError(t, os.WriteFile(filePath+".1", previous, 0600))
+
+	restored := NewPersistentStorage(memory.NewMemStorage(*logger, 0), filePath, 0, *logger, true, 2, false)
+
+	metric, ok := restored.GetMetric(ctx, model.NewMetricKey("gauge", "Alloc"))
+	require.True(t, ok)
+	assert.Equal(t, 1.0, *metric.Value)
+}
+
+func TestPersistentStorage_NoValidSnapshotStartsEmpty(t *testing.T) {
+	logger := zap.NewNop()
+	filePath := filepath.Join(t.TempDir(), "metrics.json")
+	require.NoError(t, os.WriteFile(filePath, []byte(snapshotHeaderPrefix+"3 10 abc\n{}"), 0600))
+
+	ps := NewPersistentStorage(memory.NewMemStorage(*logger, 0), filePath, 0, *logger, true, 2, false)
+
+	assert.Empty(t, ps.GetKnownMetrics(context.Background()))
+}
+
+func TestDecodeSnapshot_DetectsCorruption(t *testing.T) {
+	data, err := encodeSnapshot(newSnapshot([]*model.Metrics{
+		{ID: "Alloc", MType: "gauge", Value: floatPointer(1)},
+	}))
+	require.NoError(t, err)
+
+	decoded, err := decodeSnapshot(data)
+	require.NoError(t, err)
+	assert.Equal(t, formatVersion, decoded.version)
+
+	corrupted := []byte(strings.Replace(string(data), `"value":1`, `"value":7`, 1))
+	_, err = decodeSnapshot(corrupted)
+	assert.ErrorContains(t, err, "checksum mismatch")
+
+	_, err = decodeSnapshot(data[:len(data)-10])
+	assert.Error(t, err)
+}
+
 func floatPointer(v float64) *float64 {
 	return &v
 }
@@ -0,0 +1,153 @@
+package file
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// writeTempFile записывает data во временный файл рядом с path и сбрасывает его на диск.
+// Возвращает путь временного файла; при ошибке файл удаляется.
+func writeTempFile(path string, data []byte) (string, error) {
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp file: %w", err)
+	}
+	tmpPath := file.Name()
+
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		os.Remove(tmpPath)
+		return "", fmt.Errorf("failed to write temp file: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		os.Remove(tmpPath)
+		return "", fmt.Errorf("failed to sync temp file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(tmpPath)
+		return "", fmt.Errorf("failed to close temp file: %w", err)
+	}
+	return tmpPath, nil
+}
+
+// rotateSnapshots сдвигает историю снимков: path.(keep-1) → path.keep, …, path → path.1.
+// Снимки с номером больше keep удаляются. При keep = 0 история не ведётся.
+func rotateSnapshots(path string, keep int) error {
+	previous, err := previousSnapshots(path)
+	if err != nil {
+		return err
+	}
+	for n, name := range previous {
+		if n >= keep {
+			if err := os.Remove(name); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to remove old snapshot: %w", err)
+			}
+		}
+	}
+
+	for n := keep; n > 0; n-- {
+		from := snapshotName(path, n-1)
+		if err := os.Rename(from, snapshotName(path, n)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to rotate snapshot %s: %w", from, err)
+		}
+	}
+	return nil
+}
+
+// snapshotFiles возвращает существующие снимки от самого нового к самому старому: path, path.1, path.2, ….
+func snapshotFiles(path string) ([]string, error) {
+	previous, err := previousSnapshots(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	if _, err := os.Stat(path); err == nil {
+		files = append(files, path)
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	numbers := make([]int, 0, len(previous))
+	for n := range previous {
+		numbers = append(numbers, n)
+	}
+	sort.Ints(numbers)
+	for _, n := range numbers {
+		files = append(files, previous[n])
+	}
+	return files, nil
+}
+
+// previousSnapshots находит в директории path файлы вида path.N и возвращает их по номеру N.
+func previousSnapshots(path string) (map[int]string, error) {
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to list snapshots: %w", err)
+	}
+
+	prefix := filepath.Base(path) + "."
+	previous := make(map[int]string)
+	for _, entry := range entries {
+		suffix, ok := strings.CutPrefix(entry.Name(), prefix)
+		if !ok || entry.IsDir() {
+			continue
+		}
+		n, err := strconv.Atoi(suffix)
+		if err != nil || n <= 0 || strconv.Itoa(n) != suffix {
+			continue
+		}
+		previous[n] = filepath.Join(filepath.Dir(path), entry.Name())
+	}
+	return previous, nil
+}
+
+// snapshotName возвращает имя n-го предыдущего снимка; n = 0 соответствует текущему файлу.
+func snapshotName(path string, n int) string {
+	if n == 0 {
+		return path
+	}
+	return path + "." + strconv.Itoa(n)
+}
+
+// readSnapshot читает и разбирает файл снимка.
+func readSnapshot(path string) (decodedSnapshot, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return decodedSnapshot{}, err
+	}
+	defer file.Close()
+
+	raw, err := io.ReadAll(file)
+	if err != nil {
+		return decodedSnapshot{}, err
+	}
+	if len(raw) == 0 {
+		return decodedSnapshot{}, errors.New("empty snapshot file")
+	}
+	return decodeSnapshot(raw)
+}
+
+// syncDir сбрасывает на диск директорию, чтобы переименование файла пережило сбой питания.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("failed to open storage directory: %w", err)
+	}
+	defer d.Close()
+
+	if err := d.Sync(); err != nil {
+		return fmt.Errorf("failed to sync storage directory: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,249 @@
+package file
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/model"
+	"hash/crc32"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// walSuffix — суффикс файла журнала предзаписи рядом с файлом снимка.
+const walSuffix = ".wal"
+
+// walHeaderPrefix начинает строку заголовка журнала: "osmetrics-wal <поколение>\n".
+//
+// Поколение журнала меняется при каждом снимке. Снимок хранит поколение журнала, записи которого
+// в нём уже учтены, поэтому журнал, который не удалось очистить после снимка, не применяется повторно.
+const walHeaderPrefix = "osmetrics-wal "
+
+// writeAheadLog — журнал предзаписи принятых обновлений метрик.
+//
+// Каждая запись — строка "<crc32 тела в hex> <JSON-список метрик>\n". Записи сбрасываются на диск
+// группами: вызывающий Append ждёт fsync, а один fsync покрывает все записи, добавленные к его началу.
+type writeAheadLog struct {
+	mu         sync.Mutex
+	cond       *sync.Cond
+	file       *os.File
+	buf        *bufio.Writer
+	generation int64
+	appended   uint64
+	synced     uint64
+	syncing    bool
+}
+
+// walContents — содержимое журнала, прочитанное при восстановлении.
+type walContents struct {
+	generation int64
+	records    []model.MetricsList
+	// size — длина корректной части файла; всё, что дальше, — недописанная запись.
+	size int64
+	torn bool
+}
+
+// openWAL открывает журнал path. Если size больше нуля, журнал продолжается с этой позиции
+// (хвост после неё отбрасывается); иначе журнал очищается и начинается с поколения generation.
+func openWAL(path string, generation int64, size int64) (*writeAheadLog, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open WAL: %w", err)
+	}
+
+	w := &writeAheadLog{file: file, buf: bufio.NewWriter(file), generation: generation}
+	w.cond = sync.NewCond(&w.mu)
+
+	if size > 0 {
+		if err := file.Truncate(size); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to truncate WAL: %w", err)
+		}
+		if _, err := file.Seek(size, io.SeekStart); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to seek WAL: %w", err)
+		}
+		return w, nil
+	}
+
+	if err := w.reset(generation); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// Generation возвращает текущее поколение журнала.
+func (w *writeAheadLog) Generation() int64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.generation
+}
+
+// Append добавляет запись и возвращается после того, как она сброшена на диск.
+func (w *writeAheadLog) Append(metrics model.MetricsList) error {
+	body, err := json.Marshal(metrics)
+	if err != nil {
+		return fmt.Errorf("failed to encode WAL record: %w", err)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if _, err := fmt.Fprintf(w.buf, "%08x %s\n", crc32.ChecksumIEEE(body), body); err != nil {
+		return fmt.Errorf("failed to write WAL record: %w", err)
+	}
+	w.appended++
+	seq := w.appended
+
+	for w.synced < seq {
+		if w.syncing {
+			w.cond.Wait()
+			continue
+		}
+
+		// Этот вызов становится ведущим: сбрасывает на диск все накопленные записи,
+		// пока остальные ждут или добавляют записи в буфер для следующей группы.
+		w.syncing = true
+		target := w.appended
+		err := w.buf.Flush()
+		if err == nil {
+			w.mu.Unlock()
+			err = w.file.Sync()
+			w.mu.Lock()
+		}
+		w.syncing = false
+		w.cond.Broadcast()
+		if err != nil {
+			return fmt.Errorf("failed to sync WAL: %w", err)
+		}
+		w.synced = target
+	}
+	return nil
+}
+
+// Reset очищает журнал и начинает новое поколение generation.
+// Вызывается после того, как записи журнала попали в снимок.
+func (w *writeAheadLog) Reset(generation int64) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for w.syncing {
+		w.cond.Wait()
+	}
+	return w.reset(generation)
+}
+
+func (w *writeAheadLog) reset(generation int64) error {
+	w.buf.Reset(w.file)
+	if err := w.file.Truncate(0); err != nil {
+		return fmt.Errorf("failed to truncate WAL: %w", err)
+	}
+	if _, err := w.file.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to seek WAL: %w", err)
+	}
+	if _, err := fmt.Fprintf(w.file, "%s%d\n", walHeaderPrefix, generation); err != nil {
+		return fmt.Errorf("failed to write WAL header: %w", err)
+	}
+	if err := w.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync WAL: %w", err)
+	}
+	w.generation = generation
+	w.synced = w.appended
+	return nil
+}
+
+// Close сбрасывает буфер и закрывает файл журнала.
+func (w *writeAheadLog) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for w.syncing {
+		w.cond.Wait()
+	}
+	return errors.Join(w.buf.Flush(), w.file.Sync(), w.file.Close())
+}
+
+// readWAL читает журнал path. Отсутствующий файл соответствует пустому журналу.
+// Чтение останавливается на первой недописанной или повреждённой записи.
+func readWAL(path string) (walContents, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return walContents{}, nil
+		}
+		return walContents{}, err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	header, err := reader.ReadString('\n')
+	if err != nil {
+		if err == io.EOF && header == "" {
+			return walContents{}, nil
+		}
+		return walContents{}, fmt.Errorf("truncated WAL header: %w", err)
+	}
+	generation, err := strconv.ParseInt(strings.TrimSuffix(strings.TrimPrefix(header, walHeaderPrefix), "\n"), 10, 64)
+	if err != nil || !strings.HasPrefix(header, walHeaderPrefix) {
+		return walContents{}, fmt.Errorf("malformed WAL header: %q", header)
+	}
+
+	contents := walContents{generation: generation, size: int64(len(header))}
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			contents.torn = len(line) > 0
+			return contents, nil
+		}
+		if err != nil {
+			return contents, err
+		}
+
+		record, ok := decodeWALRecord(line)
+		if !ok {
+			contents.torn = true
+			return contents, nil
+		}
+		contents.records = append(contents.records, record)
+		contents.size += int64(len(line))
+	}
+}
+
+// decodeWALRecord разбирает строку записи журнала и проверяет её контрольную сумму.
+func decodeWALRecord(line []byte) (model.MetricsList, bool) {
+	line = bytes.TrimSuffix(line, []byte("\n"))
+	if len(line) < 10 || line[8] != ' ' {
+		return nil, false
+	}
+	sum, err := strconv.ParseUint(string(line[:8]), 16, 32)
+	if err != nil {
+		return nil, false
+	}
+	body := line[9:]
+	if crc32.ChecksumIEEE(body) != uint32(sum) {
+		return nil, false
+	}
+
+	var metrics model.MetricsList
+	if err := json.Unmarshal(body, &metrics); err != nil {
+		return nil, false
+	}
+	return metrics, true
+}
+
+// nextWALGeneration возвращает поколение журнала, большее current. Используется время,
+// чтобы новый журнал был новее снимков, оставшихся от прошлых запусков без восстановления.
+func nextWALGeneration(current int64) int64 {
+	generation := time.Now().UnixNano()
+	if generation <= current {
+		generation = current + 1
+	}
+	return generation
+}
@@ -0,0 +1,158 @@
+package file
+
+import (
+	"context"
+	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/model"
+	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/model/enum"
+	"github.com/ruslanDantsov/osmetrics-server/internal/server/repository/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newWALStorage(t *testing.T, filePath string, restore bool) *PersistentStorage {
+	t.Helper()
+	logger := zap.NewNop()
+	return NewPersistentStorage(memory.NewMemStorage(*logger, 0), filePath, time.Hour, *logger, restore, 1, true)
+}
+
+func counterValue(t *testing.T, ps *PersistentStorage, id string) int64 {
+	t.Helper()
+	metric, ok := ps.GetMetric(context.Background(), model.NewMetricKey("counter", enum.MetricID(id)))
+	require.True(t, ok, id)
+	return *metric.Delta
+}
+
+func TestPersistentStorage_WALReplaysUpdatesAfterSnapshot(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "metrics.json")
+	ctx := context.Background()
+
+	ps := newWALStorage(t, filePath, false)
+	_, err := ps.SaveMetric(ctx, counter("PollCount", 5))
+	require.NoError(t, err)
+	ps.saveToFile()
+
+	_, err = ps.SaveAllMetrics(ctx, model.MetricsList{*counter("PollCount", 3), {ID: "Alloc", MType: "gauge", Value: floatPointer(9)}})
+	require.NoError(t, err)
+
+	// Хранилище не закрывается: имитируется аварийное завершение между снимками.
+	restored := newWALStorage(t, filePath, true)
+	defer restored.Close()
+
+	assert.Equal(t, int64(8), counterValue(t, restored, "PollCount"))
+	alloc, ok := restored.GetMetric(ctx, model.NewMetricKey("gauge", "Alloc"))
+	require.True(t, ok)
+	assert.Equal(t, 9.0, *alloc.Value)
+}
+
+func TestPersistentStorage_WALIsNotReplayedTwice(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "metrics.json")
+	ctx := context.Background()
+
+	ps := newWALStorage(t, filePath, false)
+	_, err := ps.SaveMetric(ctx, counter("PollCount", 5))
+	require.NoError(t, err)
+
+	walBeforeSnapshot, err := os.ReadFile(filePath + walSuffix)
+	require.NoError(t, err)
+	ps.saveToFile()
+
+	// Журнал, который не удалось очистить после снимка, уже учтён в снимке.
+	require.NoError(t, os.WriteFile(filePath+walSuffix, walBeforeSnapshot, 0600))
+
+	restored := newWALStorage(t, filePath, true)
+	defer restored.Close()
+
+	assert.Equal(t, int64(5), counterValue(t, restored, "PollCount"))
+}
+
+func TestPersistentStorage_WALDiscardsTornRecord(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "metrics.json")
+	ctx := context.Background()
+
+	ps := newWALStorage(t, filePath, false)
+	_, err := ps.SaveMetric(ctx, counter("PollCount", 2))
+	require.NoError(t, err)
+
+	file, err := os.OpenFile(filePath+walSuffix, os.O_APPEND|os.O_WRONLY, 0600)
+	require.NoError(t, err)
+	_, err = file.WriteString(`0badc0de [{"id":"PollCount","type":"coun`)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	restored := newWALStorage(t, filePath, true)
+	assert.Equal(t, int64(2), counterValue(t, restored, "PollCount"))
+
+	// После отброшенного хвоста журнал продолжается и новые записи читаются.
+	_, err = restored.SaveMetric(ctx, counter("PollCount", 4))
+	require.NoError(t, err)
+
+	contents, err := readWAL(filePath + walSuffix)
+	require.NoError(t, err)
+	assert.False(t, contents.torn)
+	assert.Len(t, contents.records, 2)
+	restored.Close()
+}
+
+func TestPersistentStorage_WALConcurrentAppends(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "metrics.json")
+	ctx := context.Background()
+
+	ps := newWALStorage(t, filePath, false)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := ps.SaveMetric(ctx, counter("PollCount", 1))
+			assert.NoError(t, err)
+		}()
+		if i == 25 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				ps.saveToFile()
+			}()
+		}
+	}
+	wg.Wait()
+
+	restored := newWALStorage(t, filePath, true)
+	defer restored.Close()
+
+	assert.Equal(t, int64(50), counterValue(t, restored, "PollCount"))
+}
+
+func counter(id string, delta int64) *model.Metrics {
+	return &model.Metrics{ID: enum.MetricID(id), MType: "counter", Delta: &delta}
+}
+
+func TestPersistentStorage_WALIsReplayedWhenDisabledOnRestart(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "metrics.json")
+	ctx := context.Background()
+
+	ps := newWALStorage(t, filePath, false)
+	_, err := ps.SaveMetric(ctx, counter("PollCount", 5))
+	require.NoError(t, err)
+	ps.saveToFile()
+	_, err = ps.SaveMetric(ctx, counter("PollCount", 3))
+	require.NoError(t, err)
+
+	// Аварийное завершение между снимками, затем перезапуск с выключенным журналом.
+	logger := zap.NewNop()
+	restored := NewPersistentStorage(memory.NewMemStorage(*logger, 0), filePath, time.Hour, *logger, true, 1, false)
+	assert.Equal(t, int64(8), counterValue(t, restored, "PollCount"))
+	assert.NoFileExists(t, filePath+walSuffix)
+	restored.Close()
+
+	// Обновления из журнала попали в снимок и переживают следующий перезапуск.
+	again := NewPersistentStorage(memory.NewMemStorage(*logger, 0), filePath, 0, *logger, true, 1, false)
+	defer again.Close()
+	assert.Equal(t, int64(8), counterValue(t, again, "PollCount"))
+}
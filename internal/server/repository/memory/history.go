@@ -0,0 +1,47 @@
+package memory
+
+import (
+	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/model"
+	"time"
+)
+
+// sampleRing — кольцевой буфер точек истории одной серии.
+// Буфер растёт по мере добавления точек до capacity, после чего
+// новая точка вытесняет самую старую.
+type sampleRing struct {
+	samples  []model.Sample
+	capacity int
+	start    int
+}
+
+func newSampleRing(capacity int) *sampleRing {
+	return &sampleRing{capacity: capacity}
+}
+
+// add добавляет точку в конец буфера.
+func (r *sampleRing) add(sample model.Sample) {
+	if len(r.samples) < r.capacity {
+		r.samples = append(r.samples, sample)
+		return
+	}
+
+	r.samples[r.start] = sample
+	r.start = (r.start + 1) % r.capacity
+}
+
+// between возвращает точки с from <= Timestamp <= to в порядке добавления.
+// Нулевые from и to означают отсутствие соответствующей границы.
+func (r *sampleRing) between(from, to time.Time) []model.Sample {
+	result := make([]model.Sample, 0, len(r.samples))
+	for i := 0; i < len(r.samples); i++ {
+		sample := r.samples[(r.start+i)%len(r.samples)]
+		if !from.IsZero() && sample.Timestamp.Before(from) {
+			continue
+		}
+		if !to.IsZero() && sample.Timestamp.After(to) {
+			continue
+		}
+		result = append(result, sample)
+	}
+	return result
+}
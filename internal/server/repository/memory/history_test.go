@@ -0,0 +1,70 @@
+package memory
+
+import (
+	"context"
+	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"testing"
+	"time"
+)
+
+func TestSampleRing_KeepsLatestSamples(t *testing.T) {
+	ring := newSampleRing(3)
+	start := time.Unix(1000, 0)
+	for i := 0; i < 5; i++ {
+		value := float64(i)
+		ring.add(model.Sample{Timestamp: start.Add(time.Duration(i) * time.Second), Value: &value})
+	}
+
+	samples := ring.between(time.Time{}, time.Time{})
+	require.Len(t, samples, 3)
+	assert.Equal(t, 2.0, *samples[0].Value)
+	assert.Equal(t, 4.0, *samples[2].Value)
+
+	samples = ring.between(start.Add(3*time.Second), start.Add(3*time.Second))
+	require.Len(t, samples, 1)
+	assert.Equal(t, 3.0, *samples[0].Value)
+}
+
+func TestSampleRing_GrowsUpToCapacity(t *testing.T) {
+	ring := newSampleRing(1000)
+	value := 1.0
+	ring.add(model.Sample{Timestamp: time.Unix(1000, 0), Value: &value})
+
+	assert.Len(t, ring.samples, 1)
+	assert.Less(t, cap(ring.samples), 1000)
+	assert.Len(t, ring.between(time.Time{}, time.Time{}), 1)
+}
+
+func TestMemStorage_GetSeries_RecordsCounterTotals(t *testing.T) {
+	storage := NewMemStorage(*zap.NewNop(), 10)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		delta := int64(2)
+		_, err := storage.SaveMetric(ctx, &model.Metrics{ID: "PollCount", MType: "counter", Delta: &delta})
+		require.NoError(t, err)
+	}
+
+	samples, err := storage.GetSeries(ctx, model.NewMetricKey("counter", "PollCount"), time.Time{}, time.Time{})
+	require.NoError(t, err)
+	require.Len(t, samples, 3)
+	assert.Equal(t, int64(2), *samples[0].Delta)
+	assert.Equal(t, int64(4), *samples[1].Delta)
+	assert.Equal(t, int64(6), *samples[2].Delta)
+}
+
+func TestMemStorage_GetSeries_HistoryDisabled(t *testing.T) {
+	storage := NewMemStorage(*zap.NewNop(), 0)
+	ctx := context.Background()
+
+	value := 1.0
+	_, err := storage.SaveMetric(ctx, &model.Metrics{ID: "Alloc", MType: "gauge", Value: &value})
+	require.NoError(t, err)
+
+	samples, err := storage.GetSeries(ctx, model.NewMetricKey("gauge", "Alloc"), time.Time{}, time.Time{})
+	require.NoError(t, err)
+	assert.Empty(t, samples)
+}
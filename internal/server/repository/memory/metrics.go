@@ -5,25 +5,68 @@ import (
 	"context"
 	"fmt"
 	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/model"
-	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/model/enum"
 	"github.com/ruslanDantsov/osmetrics-server/internal/server/constants"
 	"go.uber.org/zap"
+	"hash/maphash"
 	"sync"
+	"time"
 )
 
+// shardCount — число сегментов MemStorage. Обновления разных серий, попавших в разные сегменты,
+// не конкурируют за одну блокировку.
+const shardCount = 32
+
 // MemStorage реализация хранения метрик в памяти с потокобезопасным доступом.
+//
+// Серии распределены по shardCount сегментам по хешу ключа, у каждого сегмента своя блокировка.
+// Методы чтения возвращают копии метрик, поэтому вызывающий код может свободно их изменять.
+//
+// Кроме текущих значений хранит историю: последние historySize принятых точек каждой серии.
 type MemStorage struct {
-	Mu      sync.RWMutex
-	Storage map[string]*model.Metrics
-	Log     zap.Logger
+	shards      [shardCount]*storageShard
+	seed        maphash.Seed
+	Log         zap.Logger
+	historySize int
+}
+
+// storageShard — сегмент хранилища: текущие значения и история части серий.
+type storageShard struct {
+	mu      sync.RWMutex
+	metrics map[model.MetricKey]*model.Metrics
+	history map[model.MetricKey]*sampleRing
 }
 
 // NewMemStorage создает и возвращает новый экземпляр хранилища в памяти.
-func NewMemStorage(log zap.Logger) *MemStorage {
-	return &MemStorage{
-		Storage: make(map[string]*model.Metrics),
-		Log:     log,
+// historySize задаёт число хранимых точек истории на серию; 0 отключает историю.
+func NewMemStorage(log zap.Logger, historySize int) *MemStorage {
+	s := &MemStorage{
+		seed:        maphash.MakeSeed(),
+		Log:         log,
+		historySize: historySize,
 	}
+	for i := range s.shards {
+		s.shards[i] = newStorageShard()
+	}
+	return s
+}
+
+func newStorageShard() *storageShard {
+	return &storageShard{
+		metrics: make(map[model.MetricKey]*model.Metrics),
+		history: make(map[model.MetricKey]*sampleRing),
+	}
+}
+
+// shard возвращает сегмент, в котором хранится серия key.
+func (s *MemStorage) shard(key model.MetricKey) *storageShard {
+	var h maphash.Hash
+	h.SetSeed(s.seed)
+	h.WriteString(key.MType)
+	h.WriteByte(0)
+	h.WriteString(string(key.ID))
+	h.WriteByte(0)
+	h.WriteString(key.Labels)
+	return s.shards[h.Sum64()%shardCount]
 }
 
 // HealthCheck проверяет состояние MemStorage.
@@ -36,58 +79,154 @@ func (s *MemStorage) Close() {
 	//For this type of storage we don't need implementation
 }
 
-// GetKnownMetrics возвращает список всех известных метрик
-func (s *MemStorage) GetKnownMetrics(ctx context.Context) []string {
-	metricNames := make([]string, 0, len(s.Storage))
-	for name := range s.Storage {
-		metricNames = append(metricNames, name)
+// GetKnownMetrics возвращает ключи всех известных метрик
+func (s *MemStorage) GetKnownMetrics(ctx context.Context) []model.MetricKey {
+	var metricKeys []model.MetricKey
+	for _, shard := range s.shards {
+		shard.mu.RLock()
+		for key := range shard.metrics {
+			metricKeys = append(metricKeys, key)
+		}
+		shard.mu.RUnlock()
+	}
+	if metricKeys == nil {
+		metricKeys = []model.MetricKey{}
 	}
-	return metricNames
+	return metricKeys
 }
 
-// GetMetric возвращает метрику по заданному идентификатору.
-func (s *MemStorage) GetMetric(ctx context.Context, metricID enum.MetricID) (*model.Metrics, bool) {
-	if val, found := s.Storage[metricID.String()]; found {
-		if val.MType == constants.CounterMetricType {
-			s.Log.Info(fmt.Sprintf("Get metric name=%v type=%v delta=%v", val.ID, val.MType, *val.Delta))
-		}
+// GetMetric возвращает копию метрики по ключу серии.
+func (s *MemStorage) GetMetric(ctx context.Context, key model.MetricKey) (*model.Metrics, bool) {
+	shard := s.shard(key)
+	shard.mu.RLock()
+	val, found := shard.metrics[key]
+	if found {
+		val = val.Clone()
+	}
+	shard.mu.RUnlock()
 
-		if val.MType == constants.GaugeMetricType {
-			s.Log.Info(fmt.Sprintf("Get metric name=%v type=%v value=%v", val.ID, val.MType, *val.Value))
-		}
-		return val, true
+	if !found {
+		return nil, false
 	}
 
-	return nil, false
+	if val.MType == constants.CounterMetricType {
+		s.Log.Info(fmt.Sprintf("Get metric name=%v type=%v delta=%v", val.ID, val.MType, *val.Delta))
+	}
+
+	if val.MType == constants.GaugeMetricType {
+		s.Log.Info(fmt.Sprintf("Get metric name=%v type=%v value=%v", val.ID, val.MType, *val.Value))
+	}
+	return val, true
 }
 
-// SaveMetric сохраняет или обновляет одну метрику в хранилище.
+// SaveMetric сохраняет или обновляет одну метрику в хранилище и возвращает копию сохранённого значения.
+// Хранилище не удерживает ссылку на metric.
 func (s *MemStorage) SaveMetric(ctx context.Context, metric *model.Metrics) (*model.Metrics, error) {
-	s.Mu.Lock()
-	defer s.Mu.Unlock()
+	key := metric.Key()
+	shard := s.shard(key)
 
-	key := metric.ID.String()
-	existing, found := s.Storage[key]
+	shard.mu.Lock()
+	saved, updated := shard.save(key, metric)
+	if s.historySize > 0 {
+		shard.recordSample(key, saved, s.historySize)
+	}
+	saved = saved.Clone()
+	shard.mu.Unlock()
 
-	if metric.MType == constants.CounterMetricType {
-		if found && existing.Delta != nil && metric.Delta != nil {
-			*existing.Delta += *metric.Delta
-			s.Log.Info(fmt.Sprintf("UPDATE counter_metric name=%v delta=%v", metric.ID, *existing.Delta))
-			return existing, nil
-		}
+	if updated {
+		s.Log.Info(fmt.Sprintf("UPDATE counter_metric name=%v delta=%v", saved.ID, *saved.Delta))
+		return saved, nil
 	}
 
-	s.Storage[key] = metric
+	if saved.MType == constants.CounterMetricType {
+		s.Log.Info(fmt.Sprintf("SAVE %v metric id=%v delta=%v", saved.MType, saved.ID, *saved.Delta))
+	}
 
-	if metric.MType == constants.CounterMetricType {
-		s.Log.Info(fmt.Sprintf("SAVE %v metric id=%v delta=%v", metric.MType, metric.ID, *metric.Delta))
+	if saved.MType == constants.GaugeMetricType {
+		s.Log.Info(fmt.Sprintf("SAVE %v metric id=%v value=%v", saved.MType, saved.ID, *saved.Value))
 	}
 
-	if metric.MType == constants.GaugeMetricType {
-		s.Log.Info(fmt.Sprintf("SAVE %v metric id=%v value=%v", metric.MType, metric.ID, *metric.Value))
+	return saved, nil
+}
+
+// save применяет metric к серии key: counter прибавляется к накопленному значению,
+// остальные метрики заменяют прежнее. Возвращает хранимое значение и признак прибавления counter.
+// Вызывается под shard.mu.
+func (shard *storageShard) save(key model.MetricKey, metric *model.Metrics) (*model.Metrics, bool) {
+	existing, found := shard.metrics[key]
+	if metric.MType == constants.CounterMetricType && found && existing.Delta != nil && metric.Delta != nil {
+		*existing.Delta += *metric.Delta
+		return existing, true
 	}
 
-	return metric, nil
+	stored := metric.Clone()
+	shard.metrics[key] = stored
+	return stored, false
+}
+
+// GetSeries возвращает точки истории серии key с from <= Timestamp <= to в порядке приёма.
+// Нулевые from и to означают отсутствие соответствующей границы.
+func (s *MemStorage) GetSeries(ctx context.Context, key model.MetricKey, from, to time.Time) ([]model.Sample, error) {
+	shard := s.shard(key)
+	shard.mu.RLock()
+	defer shard.mu.RUnlock()
+
+	ring, found := shard.history[key]
+	if !found {
+		return []model.Sample{}, nil
+	}
+	return ring.between(from, to), nil
+}
+
+// Snapshot возвращает копии всех метрик хранилища. Все сегменты блокируются на чтение одновременно,
+// поэтому результат соответствует одному моменту времени.
+func (s *MemStorage) Snapshot() []*model.Metrics {
+	for _, shard := range s.shards {
+		shard.mu.RLock()
+	}
+	defer func() {
+		for _, shard := range s.shards {
+			shard.mu.RUnlock()
+		}
+	}()
+
+	var metrics []*model.Metrics
+	for _, shard := range s.shards {
+		for _, metric := range shard.metrics {
+			metrics = append(metrics, metric.Clone())
+		}
+	}
+	return metrics
+}
+
+// Replace заменяет содержимое хранилища копиями метрик metrics. История серий очищается.
+func (s *MemStorage) Replace(metrics map[model.MetricKey]*model.Metrics) {
+	for _, shard := range s.shards {
+		shard.mu.Lock()
+	}
+	defer func() {
+		for _, shard := range s.shards {
+			shard.mu.Unlock()
+		}
+	}()
+
+	for _, shard := range s.shards {
+		shard.metrics = make(map[model.MetricKey]*model.Metrics)
+		shard.history = make(map[model.MetricKey]*sampleRing)
+	}
+	for key, metric := range metrics {
+		s.shard(key).metrics[key] = metric.Clone()
+	}
+}
+
+// recordSample добавляет текущее значение метрики в историю серии. Вызывается под shard.mu.
+func (shard *storageShard) recordSample(key model.MetricKey, metric *model.Metrics, historySize int) {
+	ring, found := shard.history[key]
+	if !found {
+		ring = newSampleRing(historySize)
+		shard.history[key] = ring
+	}
+	ring.add(model.NewSample(metric, time.Now()))
 }
 
 // SaveAllMetrics сохраняет список метрик в хранилище.
@@ -0,0 +1,126 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/model"
+	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/model/enum"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMemStorage_GetMetric_ReturnsCopy(t *testing.T) {
+	storage := NewMemStorage(*zap.NewNop(), 0)
+	ctx := context.Background()
+	delta := int64(3)
+
+	saved, err := storage.SaveMetric(ctx, &model.Metrics{ID: "PollCount", MType: "counter", Delta: &delta, Labels: map[string]string{"host": "a"}})
+	require.NoError(t, err)
+	*saved.Delta = 100
+	delta = 200
+
+	key := model.NewMetricKey("counter", "PollCount").WithLabels(map[string]string{"host": "a"})
+	metric, ok := storage.GetMetric(ctx, key)
+	require.True(t, ok)
+	*metric.Delta = 300
+	metric.Labels["host"] = "b"
+
+	metric, ok = storage.GetMetric(ctx, key)
+	require.True(t, ok)
+	assert.Equal(t, int64(3), *metric.Delta)
+	assert.Equal(t, map[string]string{"host": "a"}, metric.Labels)
+}
+
+func TestMemStorage_ConcurrentReadersAndWriters(t *testing.T) {
+	storage := NewMemStorage(*zap.NewNop(), 5)
+	ctx := context.Background()
+
+	const writers, updates, series = 8, 200, 10
+
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < updates; i++ {
+				delta := int64(1)
+				id := enum.MetricID(fmt.Sprintf("Counter%d", i%series))
+				_, err := storage.SaveMetric(ctx, &model.Metrics{ID: id, MType: "counter", Delta: &delta})
+				assert.NoError(t, err)
+			}
+		}()
+	}
+
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < updates; i++ {
+				for _, key := range storage.GetKnownMetrics(ctx) {
+					if metric, ok := storage.GetMetric(ctx, key); ok {
+						_ = *metric.Delta
+					}
+					_, err := storage.GetSeries(ctx, key, time.Time{}, time.Time{})
+					assert.NoError(t, err)
+				}
+				_ = storage.Snapshot()
+			}
+		}()
+	}
+	wg.Wait()
+
+	var total int64
+	for _, metric := range storage.Snapshot() {
+		total += *metric.Delta
+	}
+	assert.Equal(t, int64(writers*updates), total)
+	assert.Len(t, storage.GetKnownMetrics(ctx), series)
+}
+
+func TestMemStorage_Replace(t *testing.T) {
+	storage := NewMemStorage(*zap.NewNop(), 5)
+	ctx := context.Background()
+	value := 1.5
+
+	_, err := storage.SaveMetric(ctx, &model.Metrics{ID: "Old", MType: "gauge", Value: &value})
+	require.NoError(t, err)
+
+	restored := &model.Metrics{ID: "Alloc", MType: "gauge", Value: &value}
+	storage.Replace(map[model.MetricKey]*model.Metrics{restored.Key(): restored})
+	value = 2
+
+	keys := storage.GetKnownMetrics(ctx)
+	require.Equal(t, []model.MetricKey{restored.Key()}, keys)
+	metric, ok := storage.GetMetric(ctx, restored.Key())
+	require.True(t, ok)
+	assert.Equal(t, 1.5, *metric.Value)
+
+	samples, err := storage.GetSeries(ctx, model.NewMetricKey("gauge", "Old"), time.Time{}, time.Time{})
+	require.NoError(t, err)
+	assert.Empty(t, samples)
+}
+
+func BenchmarkMemStorage_SaveMetricParallel(b *testing.B) {
+	storage := NewMemStorage(*zap.NewNop(), 0)
+	ctx := context.Background()
+
+	ids := make([]enum.MetricID, 256)
+	for i := range ids {
+		ids[i] = enum.MetricID(fmt.Sprintf("Counter%d", i))
+	}
+
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			delta := int64(1)
+			if _, err := storage.SaveMetric(ctx, &model.Metrics{ID: ids[i%len(ids)], MType: "counter", Delta: &delta}); err != nil {
+				b.Fatal(err)
+			}
+			i++
+		}
+	})
+}
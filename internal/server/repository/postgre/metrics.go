@@ -15,18 +15,28 @@ import (
 	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/model"
 	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/model/enum"
 	"github.com/ruslanDantsov/osmetrics-server/internal/server/constants"
+	"github.com/ruslanDantsov/osmetrics-server/internal/server/db/migrations"
 	"github.com/ruslanDantsov/osmetrics-server/internal/server/repository/postgre/sqlqueries"
 	"go.uber.org/zap"
+	"sort"
+	"sync"
+	"time"
 )
 
 // PostgreStorage представляет реализацию хранилища метрик на базе PostgreSQL.
 type PostgreStorage struct {
 	conn *pgxpool.Pool
 	Log  zap.Logger
+
+	quit      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
 }
 
 // NewPostgreStorage создает новый экземпляр PostgreStorage.
-func NewPostgreStorage(log zap.Logger, connectionString string) (*PostgreStorage, error) {
+// Если historyRetention больше нуля, точки истории старше этого срока
+// удаляются каждые constants.HistoryCleanupInterval до вызова Close.
+func NewPostgreStorage(log zap.Logger, connectionString string, historyRetention time.Duration) (*PostgreStorage, error) {
 	if err := applyMigrations(connectionString, log); err != nil {
 		return nil, err
 	}
@@ -37,27 +47,70 @@ func NewPostgreStorage(log zap.Logger, connectionString string) (*PostgreStorage
 		return nil, fmt.Errorf("unable to create connection pool: %w", err)
 	}
 
-	return &PostgreStorage{
+	storage := &PostgreStorage{
 		conn: conn,
 		Log:  log,
-	}, nil
+	}
+	if historyRetention > 0 {
+		storage.startHistoryCleanup(historyRetention, constants.HistoryCleanupInterval)
+	}
+	return storage, nil
+}
 
+// DeleteExpiredSamples удаляет из metric_samples точки старше retention и возвращает их число.
+func (s *PostgreStorage) DeleteExpiredSamples(ctx context.Context, retention time.Duration) (int64, error) {
+	tag, err := s.conn.Exec(ctx, sqlqueries.DeleteExpiredSamples, retention.Seconds())
+	if err != nil {
+		return 0, fmt.Errorf("could not delete expired samples: %w", err)
+	}
+	return tag.RowsAffected(), nil
 }
 
-// GetMetric возвращает метрику по её идентификатору.
-func (s *PostgreStorage) GetMetric(ctx context.Context, metricID enum.MetricID) (*model.Metrics, bool) {
+// startHistoryCleanup запускает периодическое удаление устаревших точек истории.
+func (s *PostgreStorage) startHistoryCleanup(retention, interval time.Duration) {
+	s.quit = make(chan struct{})
+	s.done = make(chan struct{})
+
+	go func() {
+		defer close(s.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				deleted, err := s.DeleteExpiredSamples(context.Background(), retention)
+				if err != nil {
+					s.Log.Error("Failed to delete expired history samples", zap.Error(err))
+					continue
+				}
+				if deleted > 0 {
+					s.Log.Info("Deleted expired history samples", zap.Int64("count", deleted))
+				}
+			case <-s.quit:
+				return
+			}
+		}
+	}()
+}
+
+// GetMetric возвращает метрику по ключу серии.
+func (s *PostgreStorage) GetMetric(ctx context.Context, key model.MetricKey) (*model.Metrics, bool) {
 	var (
-		existingID    string
-		existingType  string
-		existingDelta sql.NullInt64
-		existingValue sql.NullFloat64
+		existingID     string
+		existingType   string
+		existingLabels map[string]string
+		existingDelta  sql.NullInt64
+		existingValue  sql.NullFloat64
 	)
 
 	err := s.conn.QueryRow(
 		ctx,
-		sqlqueries.SelectMetricByID,
-		metricID).
-		Scan(&existingID, &existingType, &existingDelta, &existingValue)
+		sqlqueries.SelectMetricByKey,
+		key.MType,
+		key.ID,
+		key.Labels).
+		Scan(&existingID, &existingType, &existingLabels, &existingDelta, &existingValue)
 
 	if err != nil {
 		return nil, false
@@ -70,6 +123,9 @@ func (s *PostgreStorage) GetMetric(ctx context.Context, metricID enum.MetricID)
 		MType: existingType,
 	}
 
+	if len(existingLabels) > 0 {
+		metric.Labels = existingLabels
+	}
 	if existingDelta.Valid {
 		val := existingDelta.Int64
 		metric.Delta = &val
@@ -82,217 +138,234 @@ func (s *PostgreStorage) GetMetric(ctx context.Context, metricID enum.MetricID)
 	return metric, true
 }
 
-// GetKnownMetrics возвращает список всех известных идентификаторов метрик,
+// GetKnownMetrics возвращает ключи всех известных метрик,
 // хранящихся в базе данных.
-func (s *PostgreStorage) GetKnownMetrics(ctx context.Context) []string {
-	var metricNames []string
+func (s *PostgreStorage) GetKnownMetrics(ctx context.Context) []model.MetricKey {
+	var metricKeys []model.MetricKey
 
-	rows, err := s.conn.Query(ctx, sqlqueries.SelectAllMetricIDs)
+	rows, err := s.conn.Query(ctx, sqlqueries.SelectAllMetricKeys)
 
 	if err != nil {
-		return metricNames
+		return metricKeys
 	}
 	defer rows.Close()
 
 	for rows.Next() {
-		var existingID string
-		if err := rows.Scan(&existingID); err != nil {
-			return metricNames
+		var existingType, existingID, existingLabelsKey string
+		if err := rows.Scan(&existingType, &existingID, &existingLabelsKey); err != nil {
+			return metricKeys
 		}
 
-		metricNames = append(metricNames, existingID)
+		metricKeys = append(metricKeys, model.MetricKey{
+			MType:  existingType,
+			ID:     enum.MetricID(existingID),
+			Labels: existingLabelsKey,
+		})
 	}
 
-	return metricNames
+	return metricKeys
 
 }
 
-// SaveAllMetrics сохраняет список метрик в базу данных
-func (s *PostgreStorage) SaveAllMetrics(ctx context.Context, metricList model.MetricsList) (model.MetricsList, error) {
-	tx, err := s.conn.Begin(ctx)
+// GetSeries возвращает точки истории серии key из таблицы metric_samples с from <= ts <= to.
+// Нулевые from и to означают отсутствие соответствующей границы.
+func (s *PostgreStorage) GetSeries(ctx context.Context, key model.MetricKey, from, to time.Time) ([]model.Sample, error) {
+	rows, err := s.conn.Query(ctx, sqlqueries.SelectSeriesSamples, key.MType, key.ID, key.Labels, nullTime(from), nullTime(to))
 	if err != nil {
-		return nil, fmt.Errorf("could not begin transaction: %w", err)
+		return nil, fmt.Errorf("could not select samples of %s: %w", key, err)
 	}
+	defer rows.Close()
 
-	defer func() {
-		if err != nil {
-			err = tx.Rollback(ctx)
-			s.Log.Error(err.Error())
+	samples := []model.Sample{}
+	for rows.Next() {
+		var (
+			ts    time.Time
+			delta sql.NullInt64
+			value sql.NullFloat64
+		)
+		if err := rows.Scan(&ts, &delta, &value); err != nil {
+			return nil, fmt.Errorf("could not scan sample of %s: %w", key, err)
 		}
-	}()
 
-	for _, metric := range metricList {
-		switch metric.MType {
-		case constants.CounterMetricType:
-			err = s.saveCounterMetricTx(ctx, tx, &metric)
-		case constants.GaugeMetricType:
-			err = s.saveGaugeMetricTx(ctx, tx, &metric)
-		default:
-			err = fmt.Errorf("unsupported metric type: %s", metric.MType)
+		sample := model.Sample{Timestamp: ts}
+		if delta.Valid {
+			sample.Delta = &delta.Int64
 		}
-
-		if err != nil {
-			return nil, err
+		if value.Valid {
+			sample.Value = &value.Float64
 		}
+		samples = append(samples, sample)
 	}
 
-	if err = tx.Commit(ctx); err != nil {
-		return nil, fmt.Errorf("could not commit transaction: %w", err)
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("could not read samples of %s: %w", key, err)
 	}
-
-	return metricList, nil
+	return samples, nil
 }
 
-// SaveMetric сохраняет одну метрику в базу данных.
-func (s *PostgreStorage) SaveMetric(ctx context.Context, metric *model.Metrics) (*model.Metrics, error) {
-	switch metric.MType {
-	case constants.CounterMetricType:
-		return s.saveCounterMetric(ctx, metric)
-	case constants.GaugeMetricType:
-		return s.saveGaugeMetric(ctx, metric)
-	default:
-		return nil, fmt.Errorf("unsupported metric type: %s", metric.MType)
+// SaveAllMetrics сохраняет список метрик в базу данных одной транзакцией.
+//
+// Повторы одной серии сначала сводятся (см. aggregateBatch), затем все команды отправляются
+// одним pgx.Batch. Возвращает сведённые метрики; для counter — с накопленными значениями.
+func (s *PostgreStorage) SaveAllMetrics(ctx context.Context, metricList model.MetricsList) (model.MetricsList, error) {
+	metrics, err := aggregateBatch(metricList)
+	if err != nil {
+		return nil, err
 	}
-}
-
-func (s *PostgreStorage) saveCounterMetric(ctx context.Context, metric *model.Metrics) (*model.Metrics, error) {
-	if metric.Delta == nil {
-		zero := int64(0)
-		metric.Delta = &zero
+	if len(metrics) == 0 {
+		return metrics, nil
 	}
 
-	var existingDelta sql.NullInt64
-
 	tx, err := s.conn.Begin(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("could not begin transaction: %w", err)
 	}
-
 	defer func() {
-		err = tx.Rollback(ctx)
-		if err != nil {
+		if err := tx.Rollback(ctx); err != nil && !errors.Is(err, pgx.ErrTxClosed) {
 			s.Log.Error(err.Error())
 		}
 	}()
 
-	err = tx.QueryRow(
-		ctx,
-		sqlqueries.SelectMetricByID,
-		metric.ID).
-		Scan(new(string), new(string), &existingDelta, new(sql.NullFloat64))
-
-	switch {
-	case errors.Is(err, sql.ErrNoRows):
-		existingDelta.Int64 = 0
-	case err != nil:
-		return nil, err
+	batch := &pgx.Batch{}
+	for i := range metrics {
+		metric := &metrics[i]
+		switch metric.MType {
+		case constants.CounterMetricType:
+			batch.Queue(sqlqueries.UpsertCounterMetric,
+				metric.ID,
+				metric.MType,
+				model.CanonicalLabels(metric.Labels),
+				labelsOrEmpty(metric.Labels),
+				*metric.Delta).
+				QueryRow(func(row pgx.Row) error {
+					return row.Scan(metric.Delta)
+				})
+		case constants.GaugeMetricType:
+			batch.Queue(sqlqueries.InsertOrUpdateGaugeMetric,
+				metric.ID,
+				metric.MType,
+				model.CanonicalLabels(metric.Labels),
+				labelsOrEmpty(metric.Labels),
+				*metric.Value)
+		}
 	}
 
-	*metric.Delta += existingDelta.Int64
-
-	_, err = tx.Exec(ctx,
-		sqlqueries.InsertOrUpdateCounterMetric,
-		metric.ID,
-		metric.MType,
-		metric.Delta)
-
-	if err != nil {
+	if err := tx.SendBatch(ctx, batch).Close(); err != nil {
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) {
-			switch pgErr.Code {
-			case pgerrcode.UniqueViolation:
-				return nil, fmt.Errorf("unique constraint violation when saving metric: %w", err)
-			default:
-				return nil, fmt.Errorf("postgresql error when saving metric (code %s): %w", pgErr.Code, err)
-			}
+			return nil, fmt.Errorf("postgresql error when saving metrics batch (code %s): %w", pgErr.Code, err)
 		}
+		return nil, fmt.Errorf("could not save metrics batch: %w", err)
 	}
 
 	if err := tx.Commit(ctx); err != nil {
 		return nil, fmt.Errorf("could not commit transaction: %w", err)
 	}
 
-	return metric, nil
+	return metrics, nil
 }
 
-func (s *PostgreStorage) saveCounterMetricTx(ctx context.Context, tx pgx.Tx, metric *model.Metrics) error {
-	if metric.Delta == nil {
-		zero := int64(0)
-		metric.Delta = &zero
-	}
-
-	var existingDelta sql.NullInt64
-	err := tx.QueryRow(
-		ctx,
-		sqlqueries.SelectMetricByID,
-		metric.ID,
-	).Scan(new(string), new(string), &existingDelta, new(sql.NullFloat64))
+// aggregateBatch сводит повторы одной серии в пакете: значения counter складываются,
+// для gauge остаётся последнее значение. Отсутствующие значения считаются нулевыми.
+//
+// Результат упорядочен по ключу серии, чтобы параллельные пакеты блокировали строки
+// в одном порядке и не взаимоблокировались. Исходные метрики не изменяются.
+func aggregateBatch(metricList model.MetricsList) (model.MetricsList, error) {
+	index := make(map[model.MetricKey]int, len(metricList))
+	aggregated := make(model.MetricsList, 0, len(metricList))
 
-	if err != nil && !errors.Is(err, sql.ErrNoRows) {
-		return fmt.Errorf("select counter metric failed: %w", err)
-	}
-
-	if existingDelta.Valid {
-		*metric.Delta += existingDelta.Int64
-	}
+	for _, metric := range metricList {
+		switch metric.MType {
+		case constants.CounterMetricType, constants.GaugeMetricType:
+		default:
+			return nil, fmt.Errorf("unsupported metric type: %s", metric.MType)
+		}
 
-	_, err = tx.Exec(ctx,
-		sqlqueries.InsertOrUpdateCounterMetric,
-		metric.ID,
-		metric.MType,
-		metric.Delta)
-	if err != nil {
-		var pgErr *pgconn.PgError
-		if errors.As(err, &pgErr) {
-			switch pgErr.Code {
-			case pgerrcode.UniqueViolation:
-				return fmt.Errorf("unique constraint violation when saving metric: %w", err)
-			default:
-				return fmt.Errorf("postgresql error when saving metric (code %s): %w", pgErr.Code, err)
+		key := metric.Key()
+		i, found := index[key]
+		if !found {
+			clone := metric.Clone()
+			if clone.MType == constants.CounterMetricType && clone.Delta == nil {
+				clone.Delta = new(int64)
+			}
+			if clone.MType == constants.GaugeMetricType && clone.Value == nil {
+				clone.Value = new(float64)
 			}
+			index[key] = len(aggregated)
+			aggregated = append(aggregated, *clone)
+			continue
+		}
+
+		existing := &aggregated[i]
+		switch {
+		case metric.MType == constants.CounterMetricType && metric.Delta != nil:
+			*existing.Delta += *metric.Delta
+		case metric.MType == constants.GaugeMetricType && metric.Value != nil:
+			*existing.Value = *metric.Value
+		case metric.MType == constants.GaugeMetricType:
+			*existing.Value = 0
 		}
 	}
 
-	return nil
+	sort.Slice(aggregated, func(i, j int) bool {
+		return aggregated[i].Key().String() < aggregated[j].Key().String()
+	})
+	return aggregated, nil
 }
 
-func (s *PostgreStorage) saveGaugeMetric(ctx context.Context, metric *model.Metrics) (*model.Metrics, error) {
-	if metric.Value == nil {
-		zero := float64(0)
-		metric.Value = &zero
+// SaveMetric сохраняет одну метрику в базу данных.
+func (s *PostgreStorage) SaveMetric(ctx context.Context, metric *model.Metrics) (*model.Metrics, error) {
+	switch metric.MType {
+	case constants.CounterMetricType:
+		return s.saveCounterMetric(ctx, metric)
+	case constants.GaugeMetricType:
+		return s.saveGaugeMetric(ctx, metric)
+	default:
+		return nil, fmt.Errorf("unsupported metric type: %s", metric.MType)
 	}
+}
 
-	_, err := s.conn.Exec(ctx,
-		sqlqueries.InsertOrUpdateGaugeMetric,
+// saveCounterMetric прибавляет delta метрики к counter в базе и записывает в metric накопленное значение.
+func (s *PostgreStorage) saveCounterMetric(ctx context.Context, metric *model.Metrics) (*model.Metrics, error) {
+	if metric.Delta == nil {
+		zero := int64(0)
+		metric.Delta = &zero
+	}
+
+	var total int64
+	err := s.conn.QueryRow(ctx,
+		sqlqueries.UpsertCounterMetric,
 		metric.ID,
 		metric.MType,
-		*metric.Value)
+		model.CanonicalLabels(metric.Labels),
+		labelsOrEmpty(metric.Labels),
+		*metric.Delta).
+		Scan(&total)
 
 	if err != nil {
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) {
-			switch pgErr.Code {
-			case pgerrcode.UniqueViolation:
-				return nil, fmt.Errorf("unique constraint violation when saving metric: %w", err)
-			default:
-				return nil, fmt.Errorf("postgresql error when saving metric (code %s): %w", pgErr.Code, err)
-			}
+			return nil, fmt.Errorf("postgresql error when saving metric (code %s): %w", pgErr.Code, err)
 		}
+		return nil, fmt.Errorf("could not save counter metric %s: %w", metric.ID, err)
 	}
 
+	*metric.Delta = total
 	return metric, nil
 }
 
-func (s *PostgreStorage) saveGaugeMetricTx(ctx context.Context, tx pgx.Tx, metric *model.Metrics) error {
+func (s *PostgreStorage) saveGaugeMetric(ctx context.Context, metric *model.Metrics) (*model.Metrics, error) {
 	if metric.Value == nil {
 		zero := float64(0)
 		metric.Value = &zero
 	}
 
-	_, err := tx.Exec(ctx,
+	_, err := s.conn.Exec(ctx,
 		sqlqueries.InsertOrUpdateGaugeMetric,
 		metric.ID,
 		metric.MType,
+		model.CanonicalLabels(metric.Labels),
+		labelsOrEmpty(metric.Labels),
 		*metric.Value)
 
 	if err != nil {
@@ -300,14 +373,14 @@ func (s *PostgreStorage) saveGaugeMetricTx(ctx context.Context, tx pgx.Tx, metri
 		if errors.As(err, &pgErr) {
 			switch pgErr.Code {
 			case pgerrcode.UniqueViolation:
-				return fmt.Errorf("unique constraint violation when saving metric: %w", err)
+				return nil, fmt.Errorf("unique constraint violation when saving metric: %w", err)
 			default:
-				return fmt.Errorf("postgresql error when saving metric (code %s): %w", pgErr.Code, err)
+				return nil, fmt.Errorf("postgresql error when saving metric (code %s): %w", pgErr.Code, err)
 			}
 		}
 	}
 
-	return nil
+	return metric, nil
 }
 
 // HealthCheck проверяет доступность базы данных.
@@ -322,13 +395,38 @@ func (s *PostgreStorage) HealthCheck(ctx context.Context) error {
 	return nil
 }
 
-// Close закрывает соединение с базой данных, если оно было установлено.
+// Stat возвращает статистику пула соединений с базой данных.
+func (s *PostgreStorage) Stat() *pgxpool.Stat {
+	return s.conn.Stat()
+}
+
+// Close останавливает удаление устаревших точек истории и закрывает соединение с базой данных,
+// если оно было установлено.
 func (s *PostgreStorage) Close() {
+	s.closeOnce.Do(func() {
+		if s.quit != nil {
+			close(s.quit)
+			<-s.done
+		}
+	})
 	if s.conn != nil {
 		s.conn.Close()
 	}
 }
 
+// labelsOrEmpty возвращает непустую карту меток: столбец labels не допускает NULL.
+func labelsOrEmpty(labels map[string]string) map[string]string {
+	if labels == nil {
+		return map[string]string{}
+	}
+	return labels
+}
+
+// nullTime преобразует нулевое время в NULL, означающий отсутствие границы интервала.
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
+
 func applyMigrations(connectionString string, log zap.Logger) error {
 	sqlDB, err := sql.Open("pgx", connectionString)
 	if err != nil {
@@ -340,8 +438,9 @@ func applyMigrations(connectionString string, log zap.Logger) error {
 		}
 	}()
 
+	goose.SetBaseFS(migrations.FS)
 	if err := goose.SetDialect("postgres"); err != nil {
 		return err
 	}
-	return goose.Up(sqlDB, "internal/server/db/migrations")
+	return goose.Up(sqlDB, ".")
 }
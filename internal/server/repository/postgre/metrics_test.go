@@ -0,0 +1,237 @@
+package postgre
+
+import (
+	"context"
+	"fmt"
+	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/model"
+	"github.com/ruslanDantsov/osmetrics-server/internal/pkg/shared/model/enum"
+	"github.com/ruslanDantsov/osmetrics-server/internal/server/repository/postgre/sqlqueries"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestStorage подключается к базе из TEST_DATABASE_DSN; без неё тест пропускается.
+func newTestStorage(t testing.TB) *PostgreStorage {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN is not set")
+	}
+
+	storage, err := NewPostgreStorage(*zap.NewNop(), dsn, 0)
+	require.NoError(t, err)
+	t.Cleanup(storage.Close)
+	return storage
+}
+
+func TestPostgreStorage_ConcurrentCounterUpdates(t *testing.T) {
+	storage := newTestStorage(t)
+	ctx := context.Background()
+
+	id := enum.MetricID(fmt.Sprintf("ConcurrentCounter%d", time.Now().UnixNano()))
+	labels := map[string]string{"test": "concurrency"}
+	t.Cleanup(func() {
+		_, _ = storage.conn.Exec(ctx, "DELETE FROM metrics WHERE id = $1", id)
+		_, _ = storage.conn.Exec(ctx, "DELETE FROM metric_samples WHERE id = $1", id)
+	})
+
+	const workers, updates = 16, 50
+
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		totals = make(map[int64]bool)
+	)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(batch bool) {
+			defer wg.Done()
+			for i := 0; i < updates; i++ {
+				delta := int64(1)
+				metric := model.Metrics{ID: id, MType: "counter", Delta: &delta, Labels: labels}
+
+				if batch {
+					_, err := storage.SaveAllMetrics(ctx, model.MetricsList{metric})
+					assert.NoError(t, err)
+					continue
+				}
+
+				saved, err := storage.SaveMetric(ctx, &metric)
+				if assert.NoError(t, err) {
+					mu.Lock()
+					totals[*saved.Delta] = true
+					mu.Unlock()
+				}
+			}
+		}(w%2 == 0)
+	}
+	wg.Wait()
+
+	metric, ok := storage.GetMetric(ctx, model.NewMetricKey("counter", id).WithLabels(labels))
+	require.True(t, ok)
+	assert.Equal(t, int64(workers*updates), *metric.Delta)
+
+	// Каждое одиночное обновление видит своё накопленное значение.
+	assert.Len(t, totals, workers/2*updates)
+}
+
+func TestAggregateBatch(t *testing.T) {
+	one, two, three := int64(1), int64(2), int64(3)
+	first, last := 1.5, 2.5
+	labels := map[string]string{"host": "a"}
+
+	input := model.MetricsList{
+		{ID: "PollCount", MType: "counter", Delta: &one},
+		{ID: "Alloc", MType: "gauge", Value: &first},
+		{ID: "PollCount", MType: "counter", Delta: &two},
+		{ID: "PollCount", MType: "counter", Delta: &three, Labels: labels},
+		{ID: "Alloc", MType: "gauge", Value: &last},
+		{ID: "PollCount", MType: "counter"},
+	}
+
+	aggregated, err := aggregateBatch(input)
+	require.NoError(t, err)
+	require.Len(t, aggregated, 3)
+
+	byKey := make(map[model.MetricKey]model.Metrics)
+	for _, metric := range aggregated {
+		byKey[metric.Key()] = metric
+	}
+	assert.Equal(t, int64(3), *byKey[model.NewMetricKey("counter", "PollCount")].Delta)
+	assert.Equal(t, int64(3), *byKey[model.NewMetricKey("counter", "PollCount").WithLabels(labels)].Delta)
+	assert.Equal(t, 2.5, *byKey[model.NewMetricKey("gauge", "Alloc")].Value)
+
+	// Исходные метрики не изменяются.
+	assert.Equal(t, int64(1), one)
+	assert.Equal(t, 1.5, first)
+	assert.Nil(t, input[5].Delta)
+}
+
+func TestAggregateBatch_UnsupportedType(t *testing.T) {
+	_, err := aggregateBatch(model.MetricsList{{ID: "Alloc", MType: "histogram"}})
+
+	assert.Error(t, err)
+}
+
+func TestPostgreStorage_SaveAllMetrics_ReturnsCounterTotals(t *testing.T) {
+	storage := newTestStorage(t)
+	ctx := context.Background()
+
+	id := enum.MetricID(fmt.Sprintf("BatchCounter%d", time.Now().UnixNano()))
+	t.Cleanup(func() {
+		_, _ = storage.conn.Exec(ctx, "DELETE FROM metrics WHERE id = $1", id)
+		_, _ = storage.conn.Exec(ctx, "DELETE FROM metric_samples WHERE id = $1", id)
+	})
+
+	two, three := int64(2), int64(3)
+	batch := model.MetricsList{
+		{ID: id, MType: "counter", Delta: &two},
+		{ID: id, MType: "counter", Delta: &three},
+	}
+
+	saved, err := storage.SaveAllMetrics(ctx, batch)
+	require.NoError(t, err)
+	require.Len(t, saved, 1)
+	assert.Equal(t, int64(5), *saved[0].Delta)
+
+	saved, err = storage.SaveAllMetrics(ctx, batch)
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), *saved[0].Delta)
+}
+
+func TestPostgreStorage_DeleteExpiredSamples(t *testing.T) {
+	storage := newTestStorage(t)
+	ctx := context.Background()
+
+	id := fmt.Sprintf("Retention%d", time.Now().UnixNano())
+	t.Cleanup(func() {
+		_, _ = storage.conn.Exec(ctx, "DELETE FROM metric_samples WHERE id = $1", id)
+	})
+
+	insert := "INSERT INTO metric_samples (type, id, ts, value) VALUES ('gauge', $1, $2, 1)"
+	_, err := storage.conn.Exec(ctx, insert, id, time.Now().Add(-2*time.Hour))
+	require.NoError(t, err)
+	_, err = storage.conn.Exec(ctx, insert, id, time.Now())
+	require.NoError(t, err)
+
+	_, err = storage.DeleteExpiredSamples(ctx, time.Hour)
+	require.NoError(t, err)
+
+	samples, err := storage.GetSeries(ctx, model.NewMetricKey("gauge", enum.MetricID(id)), time.Time{}, time.Time{})
+	require.NoError(t, err)
+	assert.Len(t, samples, 1)
+}
+
+// benchmarkBatch возвращает пакет, похожий на отправляемый агентом: 28 gauge и 2 counter.
+func benchmarkBatch(prefix string) model.MetricsList {
+	batch := make(model.MetricsList, 0, 30)
+	for i := 0; i < 28; i++ {
+		value := float64(i)
+		batch = append(batch, model.Metrics{ID: enum.MetricID(fmt.Sprintf("%sGauge%d", prefix, i)), MType: "gauge", Value: &value})
+	}
+	for i := 0; i < 2; i++ {
+		delta := int64(1)
+		batch = append(batch, model.Metrics{ID: enum.MetricID(fmt.Sprintf("%sCounter%d", prefix, i)), MType: "counter", Delta: &delta})
+	}
+	return batch
+}
+
+// saveSequentially сохраняет пакет прежним способом: по отдельной команде на метрику в одной транзакции.
+func saveSequentially(ctx context.Context, s *PostgreStorage, metricList model.MetricsList) error {
+	tx, err := s.conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	for _, metric := range metricList {
+		metric := metric.Clone()
+		switch metric.MType {
+		case "counter":
+			err = tx.QueryRow(ctx, sqlqueries.UpsertCounterMetric,
+				metric.ID, metric.MType, model.CanonicalLabels(metric.Labels), labelsOrEmpty(metric.Labels), *metric.Delta).
+				Scan(metric.Delta)
+		case "gauge":
+			_, err = tx.Exec(ctx, sqlqueries.InsertOrUpdateGaugeMetric,
+				metric.ID, metric.MType, model.CanonicalLabels(metric.Labels), labelsOrEmpty(metric.Labels), *metric.Value)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+func BenchmarkPostgreStorage_SaveAllMetrics(b *testing.B) {
+	storage := newTestStorage(b)
+	ctx := context.Background()
+
+	prefix := fmt.Sprintf("Bench%d", time.Now().UnixNano()%1000000)
+	b.Cleanup(func() {
+		_, _ = storage.conn.Exec(ctx, "DELETE FROM metrics WHERE id LIKE $1", prefix+"%")
+		_, _ = storage.conn.Exec(ctx, "DELETE FROM metric_samples WHERE id LIKE $1", prefix+"%")
+	})
+	batch := benchmarkBatch(prefix)
+
+	b.Run("sequential", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if err := saveSequentially(ctx, storage, batch); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("batch", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := storage.SaveAllMetrics(ctx, batch); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -2,24 +2,50 @@
 package sqlqueries
 
 const (
-	SelectAllMetricIDs = `
-	SELECT id FROM metrics;
+	SelectAllMetricKeys = `
+	SELECT type, id, labels_key FROM metrics;
 `
 
 	InsertOrUpdateGaugeMetric = `
-		INSERT INTO metrics (id, type, value)
-		VALUES ($1, $2, $3)
-		ON CONFLICT (id) DO UPDATE SET value = $3;
+		WITH saved AS (
+			INSERT INTO metrics (id, type, labels_key, labels, value)
+			VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (type, id, labels_key) DO UPDATE SET value = $5
+			RETURNING type, id, labels_key, delta, value
+		)
+		INSERT INTO metric_samples (type, id, labels_key, delta, value)
+		SELECT type, id, labels_key, delta, value FROM saved;
 	`
 
-	SelectMetricByID = `
-	SELECT id, type, delta, value FROM metrics
-	WHERE id = $1;
+	SelectMetricByKey = `
+	SELECT id, type, labels, delta, value FROM metrics
+	WHERE type = $1 AND id = $2 AND labels_key = $3;
 `
 
-	InsertOrUpdateCounterMetric = `
-		INSERT INTO metrics (id, type, delta)
-		VALUES ($1, $2, $3)
-		ON CONFLICT (id) DO UPDATE SET delta = $3;
+	// UpsertCounterMetric прибавляет $5 к counter одной командой: строка блокируется конфликтующей вставкой,
+	// поэтому параллельные обновления одного counter не теряются. Возвращает накопленное значение.
+	UpsertCounterMetric = `
+		WITH saved AS (
+			INSERT INTO metrics (id, type, labels_key, labels, delta)
+			VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (type, id, labels_key) DO UPDATE SET delta = COALESCE(metrics.delta, 0) + EXCLUDED.delta
+			RETURNING type, id, labels_key, delta, value
+		)
+		INSERT INTO metric_samples (type, id, labels_key, delta, value)
+		SELECT type, id, labels_key, delta, value FROM saved
+		RETURNING delta;
 	`
+
+	SelectSeriesSamples = `
+	SELECT ts, delta, value FROM metric_samples
+	WHERE type = $1 AND id = $2 AND labels_key = $3
+		AND ($4::timestamptz IS NULL OR ts >= $4)
+		AND ($5::timestamptz IS NULL OR ts <= $5)
+	ORDER BY ts;
+`
+
+	// DeleteExpiredSamples удаляет точки истории старше $1 секунд.
+	DeleteExpiredSamples = `
+	DELETE FROM metric_samples WHERE ts < now() - make_interval(secs => $1);
+`
 )
@@ -0,0 +1,168 @@
+// Package telemetry собирает собственные метрики сервера и отдаёт их Prometheus на служебном адресе.
+package telemetry
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const namespace = "osmetrics"
+
+// Стадии обработки запроса, отказы на которых учитываются отдельно.
+const (
+	StageGzip          = "gzip"
+	StageDecrypt       = "decrypt"
+	StageHash          = "hash"
+	StageTrustedSubnet = "trusted_subnet"
+)
+
+// sizeBuckets — границы гистограмм размеров тел запросов и ответов: от 64 байт до 4 МБ.
+var sizeBuckets = prometheus.ExponentialBuckets(64, 4, 9)
+
+// Telemetry хранит метрики самого сервера в отдельном реестре, не смешивая их с метриками агентов.
+type Telemetry struct {
+	registry *prometheus.Registry
+
+	requestDuration    *prometheus.HistogramVec
+	requestSize        *prometheus.HistogramVec
+	responseSize       *prometheus.HistogramVec
+	middlewareFailures *prometheus.CounterVec
+	storageDuration    *prometheus.HistogramVec
+	storageErrors      *prometheus.CounterVec
+	ingestedMetrics    *prometheus.CounterVec
+	snapshotDuration   prometheus.Histogram
+	snapshotFailures   prometheus.Counter
+}
+
+// New создаёт Telemetry и регистрирует метрики сервера, а также стандартные метрики Go-рантайма и процесса.
+func New() *Telemetry {
+	t := &Telemetry{
+		registry: prometheus.NewRegistry(),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "request_duration_seconds",
+			Help:      "HTTP request latency by route, method and status.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method", "status"}),
+		requestSize: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "request_size_bytes",
+			Help:      "HTTP request body size by route.",
+			Buckets:   sizeBuckets,
+		}, []string{"route"}),
+		responseSize: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "response_size_bytes",
+			Help:      "HTTP response body size by route.",
+			Buckets:   sizeBuckets,
+		}, []string{"route"}),
+		middlewareFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "rejected_requests_total",
+			Help:      "Requests rejected by middleware stage: gzip, decrypt, hash or trusted_subnet.",
+		}, []string{"stage"}),
+		storageDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "storage",
+			Name:      "operation_duration_seconds",
+			Help:      "Storage operation latency by backend and operation.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"backend", "operation"}),
+		storageErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "storage",
+			Name:      "operation_errors_total",
+			Help:      "Failed storage operations by backend and operation.",
+		}, []string{"backend", "operation"}),
+		ingestedMetrics: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "ingest",
+			Name:      "metrics_total",
+			Help:      "Metrics accepted into storage by metric type.",
+		}, []string{"type"}),
+		snapshotDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "file",
+			Name:      "snapshot_duration_seconds",
+			Help:      "Duration of writing the metrics snapshot file.",
+			Buckets:   prometheus.DefBuckets,
+		}),
+		snapshotFailures: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "file",
+			Name:      "snapshot_failures_total",
+			Help:      "Failed attempts to write the metrics snapshot file.",
+		}),
+	}
+
+	t.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		t.requestDuration,
+		t.requestSize,
+		t.responseSize,
+		t.middlewareFailures,
+		t.storageDuration,
+		t.storageErrors,
+		t.ingestedMetrics,
+		t.snapshotDuration,
+		t.snapshotFailures,
+	)
+
+	return t
+}
+
+// Handler возвращает HTTP-обработчик, отдающий метрики сервера в формате Prometheus.
+func (t *Telemetry) Handler() http.Handler {
+	return promhttp.HandlerFor(t.registry, promhttp.HandlerOpts{})
+}
+
+// Register регистрирует дополнительный сборщик метрик, например статистику пула соединений.
+func (t *Telemetry) Register(collector prometheus.Collector) error {
+	return t.registry.Register(collector)
+}
+
+// ObserveRequest учитывает обработанный HTTP-запрос. Отрицательный размер означает, что он неизвестен.
+func (t *Telemetry) ObserveRequest(route, method string, status int, duration time.Duration, requestSize, responseSize int64) {
+	t.requestDuration.WithLabelValues(route, method, strconv.Itoa(status)).Observe(duration.Seconds())
+	if requestSize >= 0 {
+		t.requestSize.WithLabelValues(route).Observe(float64(requestSize))
+	}
+	if responseSize >= 0 {
+		t.responseSize.WithLabelValues(route).Observe(float64(responseSize))
+	}
+}
+
+// IncRejected учитывает запрос, отклонённый на стадии stage (см. Stage*).
+func (t *Telemetry) IncRejected(stage string) {
+	t.middlewareFailures.WithLabelValues(stage).Inc()
+}
+
+// ObserveStorage учитывает операцию operation хранилища backend.
+func (t *Telemetry) ObserveStorage(backend, operation string, duration time.Duration, err error) {
+	t.storageDuration.WithLabelValues(backend, operation).Observe(duration.Seconds())
+	if err != nil {
+		t.storageErrors.WithLabelValues(backend, operation).Inc()
+	}
+}
+
+// AddIngested учитывает count принятых метрик типа metricType.
+func (t *Telemetry) AddIngested(metricType string, count int) {
+	t.ingestedMetrics.WithLabelValues(metricType).Add(float64(count))
+}
+
+// ObserveSnapshot учитывает запись снимка метрик в файл.
+func (t *Telemetry) ObserveSnapshot(duration time.Duration, err error) {
+	t.snapshotDuration.Observe(duration.Seconds())
+	if err != nil {
+		t.snapshotFailures.Inc()
+	}
+}